@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type AuthController interface {
+	CreateApiKey(writer http.ResponseWriter, request *http.Request)
+	GetApiKeys(writer http.ResponseWriter, request *http.Request)
+	RevokeApiKey(writer http.ResponseWriter, request *http.Request)
+}
+
+type Controller struct {
+	authService AuthService
+
+	logger logger.LoggerService
+}
+
+func NewController(authService AuthService, logger logger.LoggerService) *Controller {
+	controller := new(Controller)
+	controller.authService = authService
+	controller.logger = logger
+
+	return controller
+}
+
+func (controller *Controller) CreateApiKey(writer http.ResponseWriter, request *http.Request) {
+	input := new(models.CreateApiKeyInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		controller.logger.Error(err, "Failed to decode JSON.")
+		lib.WriteError(writer, lib.NewValidationError("Invalid JSON body.", err.Error()))
+		return
+	}
+	principal := PrincipalFromContext(request.Context())
+	apiKey, err := controller.authService.CreateApiKey(input, principal.Subject)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusCreated, map[string]interface{}{
+		"apiKey": apiKey,
+	})
+}
+
+func (controller *Controller) GetApiKeys(writer http.ResponseWriter, request *http.Request) {
+	apiKeys, err := controller.authService.GetApiKeys()
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"apiKeys": apiKeys,
+	})
+}
+
+func (controller *Controller) RevokeApiKey(writer http.ResponseWriter, request *http.Request) {
+	id := chi.URLParam(request, "id")
+	err := controller.authService.RevokeApiKey(id)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"id":      id,
+		"revoked": true,
+	})
+}
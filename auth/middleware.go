@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/nw.lee/idioms-backend/lib"
+)
+
+type Middleware struct {
+	authService AuthService
+}
+
+func NewMiddleware(authService AuthService) *Middleware {
+	middleware := new(Middleware)
+	middleware.authService = authService
+
+	return middleware
+}
+
+func (middleware *Middleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		token := requestToken(request)
+		if len(token) == 0 {
+			lib.WriteError(writer, lib.NewUnauthorizedError("Authentication is required."))
+			return
+		}
+		principal, err := middleware.authService.Authenticate(token)
+		if err != nil {
+			lib.WriteError(writer, err)
+			return
+		}
+		next.ServeHTTP(writer, request.WithContext(WithPrincipal(request.Context(), principal)))
+	})
+}
+
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			principal := PrincipalFromContext(request.Context())
+			if principal == nil {
+				lib.WriteError(writer, lib.NewUnauthorizedError("Authentication is required."))
+				return
+			}
+			if !principal.HasScope(scope) {
+				lib.WriteError(writer, lib.NewForbiddenError("Missing required scope.", map[string]string{"scope": scope}))
+				return
+			}
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+func requestToken(request *http.Request) string {
+	authorization := request.Header.Get("authorization")
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return strings.TrimSpace(request.Header.Get("x-api-key"))
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/logger"
+)
+
+func TestMiddlewareWithToken(t *testing.T) {
+	service := NewService(nil, logger.NewService(log.Default()), "secret", "useidioms.com")
+	middleware := NewMiddleware(service)
+	handler := middleware.Authenticate(RequireScope(ScopeContentWrite)(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusNoContent)
+	})))
+
+	writer, _ := service.IssueToken("writer", []string{ScopeContentWrite}, time.Minute)
+	reader, _ := service.IssueToken("reader", []string{ScopeContentRead}, time.Minute)
+	expired, _ := service.IssueToken("writer", []string{ScopeContentWrite}, -time.Minute)
+	forged, _ := NewService(nil, logger.NewService(log.Default()), "other", "useidioms.com").IssueToken("writer", []string{ScopeContentWrite}, time.Minute)
+
+	cases := map[string]int{
+		"":                  http.StatusUnauthorized,
+		"Bearer " + writer:  http.StatusNoContent,
+		"bearer " + writer:  http.StatusNoContent,
+		"Bearer " + reader:  http.StatusForbidden,
+		"Bearer " + expired: http.StatusUnauthorized,
+		"Bearer " + forged:  http.StatusUnauthorized,
+		"Bearer invalid":    http.StatusUnauthorized,
+	}
+	for authorization, status := range cases {
+		request := httptest.NewRequest(http.MethodPost, "/idioms/inputs", nil)
+		if len(authorization) > 0 {
+			request.Header.Set("authorization", authorization)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if recorder.Code != status {
+			t.Errorf("Expected %d with %q, received %d", status, authorization, recorder.Code)
+		}
+	}
+}
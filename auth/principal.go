@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"context"
+)
+
+const (
+	ScopeContentRead     = "content:read"
+	ScopeContentWrite    = "content:write"
+	ScopeThumbnailsWrite = "thumbnails:write"
+	ScopeJobsAdmin       = "jobs:admin"
+	ScopeKeysAdmin       = "keys:admin"
+)
+
+var Scopes = []string{ScopeContentRead, ScopeContentWrite, ScopeThumbnailsWrite, ScopeJobsAdmin, ScopeKeysAdmin}
+
+const (
+	PrincipalApiKey = "api_key"
+	PrincipalToken  = "token"
+)
+
+type Principal struct {
+	Subject string   `json:"subject"`
+	Kind    string   `json:"kind"`
+	Scopes  []string `json:"scopes"`
+}
+
+type principalKey struct{}
+
+func (principal *Principal) HasScope(scope string) bool {
+	for _, granted := range principal.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+func IsScope(scope string) bool {
+	for _, known := range Scopes {
+		if known == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type AuthService interface {
+	CreateApiKey(input *models.CreateApiKeyInput, createdBy string) (*models.CreatedApiKey, error)
+	GetApiKeys() ([]models.ApiKey, error)
+	RevokeApiKey(id string) error
+	Authenticate(token string) (*Principal, error)
+	IssueToken(subject string, scopes []string, duration time.Duration) (string, error)
+}
+
+type Service struct {
+	db        *sqlx.DB
+	logger    logger.LoggerService
+	jwtSecret []byte
+	jwtIssuer string
+}
+
+type TokenClaims struct {
+	Scopes []string `json:"scopes"`
+	jwt.RegisteredClaims
+}
+
+const apiKeyPrefix = "idk"
+
+var apiKeyColumns = []string{"id", "name", "prefix", "key_hash", "scopes", "created_by", "created_at", "last_used_at", "revoked_at"}
+
+func NewService(db *sqlx.DB, logger logger.LoggerService, jwtSecret string, jwtIssuer string) *Service {
+	service := new(Service)
+	service.db = db
+	service.logger = logger
+	service.jwtSecret = []byte(jwtSecret)
+	service.jwtIssuer = jwtIssuer
+
+	return service
+}
+
+func (service *Service) CreateApiKey(input *models.CreateApiKeyInput, createdBy string) (*models.CreatedApiKey, error) {
+	name := strings.TrimSpace(input.Name)
+	if len(name) == 0 {
+		return nil, lib.NewValidationError("Name is required.", nil)
+	}
+	if len(input.Scopes) == 0 {
+		return nil, lib.NewValidationError("At least one scope is required.", map[string][]string{"scopes": Scopes})
+	}
+	for _, scope := range input.Scopes {
+		if !IsScope(scope) {
+			return nil, lib.NewValidationError(fmt.Sprintf("Unknown scope %s.", scope), map[string][]string{"scopes": Scopes})
+		}
+	}
+
+	prefix, err := randomHex(6)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%s_%s_%s", apiKeyPrefix, prefix, secret)
+	apiKey := models.ApiKey{
+		ID:      uuid.NewString(),
+		Name:    name,
+		Prefix:  prefix,
+		KeyHash: hashKey(key),
+		Scopes:  models.TextArray(input.Scopes),
+	}
+
+	query, args, err := sq.Insert("api_keys").
+		Columns("id", "name", "prefix", "key_hash", "scopes", "created_by").
+		Values(apiKey.ID, apiKey.Name, apiKey.Prefix, apiKey.KeyHash, apiKey.Scopes, createdBy).
+		Suffix(fmt.Sprintf("returning %s", strings.Join(apiKeyColumns, ", "))).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		service.logger.Error(err, "Failed to create a query to insert api key.")
+		return nil, err
+	}
+	created := models.ApiKey{}
+	err = service.db.Get(&created, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to insert api key.", name)
+		return nil, err
+	}
+
+	return &models.CreatedApiKey{ApiKey: created, Key: key}, nil
+}
+
+func (service *Service) GetApiKeys() ([]models.ApiKey, error) {
+	apiKeys := []models.ApiKey{}
+	query, args, err := sq.Select(apiKeyColumns...).From("api_keys").OrderBy("created_at desc").PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		service.logger.Error(err, "Failed to create a query.")
+		return nil, err
+	}
+	err = service.db.Select(&apiKeys, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to query api keys.")
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+func (service *Service) RevokeApiKey(id string) error {
+	_, err := uuid.Parse(id)
+	if err != nil {
+		return lib.NewValidationError("Invalid api key id.", map[string]string{"id": id})
+	}
+	query, args, err := sq.Update("api_keys").
+		Set("revoked_at", sq.Expr("now() at time zone 'utc'")).
+		Where("id = ?", id).
+		Where("revoked_at is null").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		service.logger.Error(err, "Failed to create a query with id.", id)
+		return err
+	}
+	result, err := service.db.Exec(query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to revoke api key with id.", id)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return lib.NewNotFoundError("Api key not found.", map[string]string{"id": id})
+	}
+	return nil
+}
+
+func (service *Service) Authenticate(token string) (*Principal, error) {
+	if strings.HasPrefix(token, apiKeyPrefix+"_") {
+		return service.authenticateApiKey(token)
+	}
+	return service.authenticateToken(token)
+}
+
+func (service *Service) IssueToken(subject string, scopes []string, duration time.Duration) (string, error) {
+	if len(service.jwtSecret) == 0 {
+		return "", errors.New("jwt secret is not configured")
+	}
+	now := time.Now()
+	claims := TokenClaims{
+		Scopes: scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   subject,
+			Issuer:    service.jwtIssuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(service.jwtSecret)
+}
+
+func (service *Service) authenticateApiKey(key string) (*Principal, error) {
+	tokens := strings.Split(key, "_")
+	if len(tokens) != 3 {
+		return nil, lib.NewUnauthorizedError("Invalid api key.")
+	}
+	apiKeys := []models.ApiKey{}
+	query, args, _ := sq.Select(apiKeyColumns...).From("api_keys").Where("prefix = ?", tokens[1]).Where("revoked_at is null").Limit(1).PlaceholderFormat(sq.Dollar).ToSql()
+	err := service.db.Select(&apiKeys, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to query api key with prefix.", tokens[1])
+		return nil, err
+	}
+	if len(apiKeys) == 0 || subtle.ConstantTimeCompare([]byte(apiKeys[0].KeyHash), []byte(hashKey(key))) != 1 {
+		return nil, lib.NewUnauthorizedError("Invalid api key.")
+	}
+	apiKey := apiKeys[0]
+
+	updateQuery, updateArgs, _ := sq.Update("api_keys").Set("last_used_at", sq.Expr("now() at time zone 'utc'")).Where("id = ?", apiKey.ID).PlaceholderFormat(sq.Dollar).ToSql()
+	_, err = service.db.Exec(updateQuery, updateArgs...)
+	if err != nil {
+		service.logger.Warn("Failed to update last used time of api key.", apiKey.ID, err)
+	}
+
+	return &Principal{
+		Subject: fmt.Sprintf("api_key:%s", apiKey.Name),
+		Kind:    PrincipalApiKey,
+		Scopes:  apiKey.Scopes,
+	}, nil
+}
+
+func (service *Service) authenticateToken(token string) (*Principal, error) {
+	if len(service.jwtSecret) == 0 {
+		return nil, lib.NewUnauthorizedError("Invalid token.")
+	}
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if len(service.jwtIssuer) > 0 {
+		options = append(options, jwt.WithIssuer(service.jwtIssuer))
+	}
+	claims := new(TokenClaims)
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return service.jwtSecret, nil
+	}, options...)
+	if err != nil {
+		service.logger.Warn("Failed to verify token.", err)
+		return nil, lib.NewUnauthorizedError("Invalid token.")
+	}
+
+	return &Principal{
+		Subject: claims.Subject,
+		Kind:    PrincipalToken,
+		Scopes:  claims.Scopes,
+	}, nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
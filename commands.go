@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/auth"
+	"github.com/nw.lee/idioms-backend/engine"
+	"github.com/nw.lee/idioms-backend/exports"
+	"github.com/nw.lee/idioms-backend/idioms"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/orphans"
+)
+
+// cli runs the subcommands given as `app <command> [flags]` instead of the server.
+type cli struct {
+	migrator      engine.MigrationService
+	orphanService orphans.OrphanService
+	idiomService  idioms.IdiomService
+	exportService exports.ExportService
+	authService   auth.AuthService
+}
+
+// exit runs the command and exits with 1 when it fails.
+func (cli *cli) exit(ctx context.Context, args []string) {
+	err := cli.run(ctx, args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func (cli *cli) run(ctx context.Context, args []string) error {
+	switch args[0] {
+	case "migrate":
+		{
+			return cli.migrate(ctx, args[1:])
+		}
+	case "reconcile":
+		{
+			return cli.reconcile(ctx, args[1:])
+		}
+	case "import":
+		{
+			return cli.importInputs(ctx, args[1:])
+		}
+	case "export":
+		{
+			return cli.export(ctx, args[1:])
+		}
+	case "apikey":
+		{
+			return cli.createApiKey(args[1:])
+		}
+	case "token":
+		{
+			return cli.issueToken(args[1:])
+		}
+	default:
+		{
+			return fmt.Errorf("unknown command %q, expected one of: migrate, reconcile, import, export, apikey, token", args[0])
+		}
+	}
+}
+
+func (cli *cli) migrate(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert with down")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	var migrations []engine.Migration
+	switch flags.Arg(0) {
+	case "up":
+		{
+			migrations, err = cli.migrator.Up(ctx)
+		}
+	case "down":
+		{
+			migrations, err = cli.migrator.Down(ctx, *steps)
+		}
+	case "status":
+		{
+			migrations, err = cli.migrator.Status(ctx)
+		}
+	default:
+		{
+			return fmt.Errorf("usage: migrate [-steps n] up|down|status")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(migrations)
+}
+
+func (cli *cli) reconcile(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", true, "report orphans without deleting them")
+	grace := flags.Duration("grace", orphans.DefaultGrace, "keep orphans modified within this duration")
+	prefix := flags.String("prefix", "", "only reconcile keys with this prefix")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	report, err := cli.orphanService.Reconcile(ctx, &orphans.Option{
+		DryRun: *dryRun,
+		Grace:  *grace,
+		Prefix: *prefix,
+	})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func (cli *cli) importInputs(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", "", "csv or ndjson, by default from the file extension")
+	dryRun := flags.Bool("dry-run", false, "report the rows without inserting them")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("usage: import [-format csv|ndjson] [-dry-run] <file|->")
+	}
+	path := flags.Arg(0)
+	if len(*format) == 0 {
+		*format = idioms.FormatByExtension(path)
+	}
+	file := os.Stdin
+	if path != "-" {
+		file, err = os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+	reader, err := idioms.NewInputReader(*format, file)
+	if err != nil {
+		return err
+	}
+	report, err := cli.idiomService.ImportInputs(reader, &idioms.ImportOption{DryRun: *dryRun}, &ctx)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func (cli *cli) export(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", models.ExportNDJSON, "ndjson, csv or apkg")
+	output := flags.String("o", "-", "write to this file instead of stdout")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	file := os.Stdout
+	if *output != "-" {
+		file, err = os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+	}
+	count, err := cli.exportService.Export(ctx, *format, file)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d idioms.\n", count)
+	return nil
+}
+
+// createApiKey creates an API key without authenticating, so a new deployment can get its first key.
+func (cli *cli) createApiKey(args []string) error {
+	flags := flag.NewFlagSet("apikey", flag.ContinueOnError)
+	name := flags.String("name", "", "name of the key")
+	scopes := flags.String("scopes", auth.ScopeKeysAdmin, "comma separated scopes of the key")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	created, err := cli.authService.CreateApiKey(&models.CreateApiKeyInput{
+		Name:   *name,
+		Scopes: splitScopes(*scopes),
+	}, "cli")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(created)
+}
+
+func (cli *cli) issueToken(args []string) error {
+	flags := flag.NewFlagSet("token", flag.ContinueOnError)
+	subject := flags.String("subject", "cli", "subject of the token, recorded as the author of revisions")
+	scopes := flags.String("scopes", auth.ScopeKeysAdmin, "comma separated scopes of the token")
+	duration := flags.Duration("ttl", time.Hour, "how long the token is valid")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	for _, scope := range splitScopes(*scopes) {
+		if !auth.IsScope(scope) {
+			return fmt.Errorf("unknown scope %s, expected some of: %s", scope, strings.Join(auth.Scopes, ", "))
+		}
+	}
+	token, err := cli.authService.IssueToken(*subject, splitScopes(*scopes), *duration)
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
+
+func splitScopes(scopes string) []string {
+	split := []string{}
+	for _, scope := range strings.Split(scopes, ",") {
+		if scope = strings.TrimSpace(scope); len(scope) > 0 {
+			split = append(split, scope)
+		}
+	}
+	return split
+}
@@ -0,0 +1,7 @@
+-- 0000_init adopts the tables of databases created before the migrations, so reverting it
+-- would drop data it never created. The migrator never reverts it, and this fails if run by hand.
+do $$
+begin
+  raise exception '0000_init cannot be reverted';
+end
+$$;
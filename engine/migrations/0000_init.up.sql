@@ -0,0 +1,31 @@
+create table if not exists idioms (
+  id text primary key,
+  num_id bigserial not null,
+  idiom text not null,
+  meaning_brief text not null default '',
+  meaning_full text not null default '',
+  description text,
+  thumbnail text,
+  thumbnails jsonb,
+  thumbnail_prompt text,
+  created_at timestamp not null default (now() at time zone 'utc'),
+  published_at timestamp
+);
+
+create unique index if not exists idioms_num_id on idioms (num_id);
+create index if not exists idioms_created_at on idioms (created_at desc);
+
+create table if not exists idiom_examples (
+  idiom_id text not null,
+  expression text not null
+);
+
+create index if not exists idiom_examples_idiom_id on idiom_examples (idiom_id);
+
+-- Inputs wait here until the generation job turns them into idioms.
+create table if not exists idiom_inputs (
+  id text primary key,
+  idiom text not null,
+  meaning text not null default '',
+  created_at timestamp not null default (now() at time zone 'utc')
+);
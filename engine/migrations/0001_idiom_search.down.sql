@@ -0,0 +1,11 @@
+drop index if exists idioms_idiom_trgm_idx;
+drop index if exists idioms_search_document_idx;
+
+drop trigger if exists idiom_examples_search_document on idiom_examples;
+drop function if exists idiom_examples_search_document_trigger();
+drop trigger if exists idioms_search_document on idioms;
+drop function if exists idioms_search_document_trigger();
+drop function if exists idioms_build_search_document(text, text, text, text, text);
+drop function if exists idioms_examples_text(text);
+
+alter table idioms drop column if exists search_document;
@@ -0,0 +1,55 @@
+create extension if not exists pg_trgm;
+
+alter table idioms add column if not exists search_document tsvector;
+
+create or replace function idioms_examples_text(target_id text) returns text as $$
+  select coalesce(string_agg(expression, ' '), '') from idiom_examples where idiom_id = target_id
+$$ language sql stable;
+
+create or replace function idioms_build_search_document(idiom text, meaning_brief text, meaning_full text, description text, examples text) returns tsvector as $$
+  select
+    setweight(to_tsvector('english', coalesce(idiom, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(meaning_brief, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(meaning_full, '')), 'C') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'C') ||
+    setweight(to_tsvector('english', coalesce(examples, '')), 'D')
+$$ language sql immutable;
+
+create or replace function idioms_search_document_trigger() returns trigger as $$
+begin
+  new.search_document := idioms_build_search_document(new.idiom, new.meaning_brief, new.meaning_full, new.description, idioms_examples_text(new.id));
+  return new;
+end
+$$ language plpgsql;
+
+drop trigger if exists idioms_search_document on idioms;
+create trigger idioms_search_document
+  before insert or update of idiom, meaning_brief, meaning_full, description on idioms
+  for each row execute function idioms_search_document_trigger();
+
+create or replace function idiom_examples_search_document_trigger() returns trigger as $$
+begin
+  if tg_op in ('UPDATE', 'DELETE') then
+    update idioms
+    set search_document = idioms_build_search_document(idiom, meaning_brief, meaning_full, description, idioms_examples_text(id))
+    where id = old.idiom_id;
+  end if;
+  if tg_op in ('INSERT', 'UPDATE') then
+    update idioms
+    set search_document = idioms_build_search_document(idiom, meaning_brief, meaning_full, description, idioms_examples_text(id))
+    where id = new.idiom_id;
+  end if;
+  return null;
+end
+$$ language plpgsql;
+
+drop trigger if exists idiom_examples_search_document on idiom_examples;
+create trigger idiom_examples_search_document
+  after insert or update or delete on idiom_examples
+  for each row execute function idiom_examples_search_document_trigger();
+
+update idioms
+set search_document = idioms_build_search_document(idiom, meaning_brief, meaning_full, description, idioms_examples_text(id));
+
+create index if not exists idioms_search_document_idx on idioms using gin (search_document);
+create index if not exists idioms_idiom_trgm_idx on idioms using gin (idiom gin_trgm_ops);
@@ -0,0 +1,11 @@
+create table if not exists api_keys (
+  id uuid primary key,
+  name text not null,
+  prefix text not null unique,
+  key_hash text not null,
+  scopes jsonb not null default '[]'::jsonb,
+  created_by text,
+  created_at timestamp not null default (now() at time zone 'utc'),
+  last_used_at timestamp,
+  revoked_at timestamp
+);
@@ -0,0 +1,25 @@
+create table if not exists jobs (
+  id bigserial primary key,
+  kind text not null,
+  payload jsonb not null default '{}'::jsonb,
+  status text not null default 'pending' check (status in ('pending', 'running', 'succeeded', 'failed', 'dead')),
+  dedupe_key text,
+  attempts integer not null default 0,
+  max_attempts integer not null default 5,
+  run_at timestamp not null default (now() at time zone 'utc'),
+  locked_at timestamp,
+  locked_by text,
+  last_error text,
+  created_at timestamp not null default (now() at time zone 'utc'),
+  updated_at timestamp not null default (now() at time zone 'utc'),
+  finished_at timestamp
+);
+
+create index if not exists jobs_ready_idx on jobs (kind, run_at) where status in ('pending', 'running', 'failed');
+create index if not exists jobs_status_idx on jobs (status, updated_at desc);
+create unique index if not exists jobs_dedupe_key_idx on jobs (dedupe_key) where status in ('pending', 'running', 'failed');
+
+insert into jobs (kind, payload, dedupe_key)
+select 'idiom.generate', jsonb_build_object('inputId', id), 'idiom.generate:' || id
+from idiom_inputs
+on conflict (dedupe_key) where status in ('pending', 'running', 'failed') do nothing;
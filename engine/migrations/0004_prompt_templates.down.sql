@@ -0,0 +1,3 @@
+alter table idioms drop column if exists prompt_version;
+
+drop table if exists prompt_templates;
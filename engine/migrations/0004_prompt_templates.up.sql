@@ -0,0 +1,11 @@
+create table if not exists prompt_templates (
+  name text not null,
+  version integer not null check (version > 0),
+  body text not null,
+  active boolean not null default true,
+  created_by text,
+  created_at timestamp not null default (now() at time zone 'utc'),
+  primary key (name, version)
+);
+
+alter table idioms add column if not exists prompt_version text;
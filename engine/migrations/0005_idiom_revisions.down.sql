@@ -0,0 +1 @@
+drop table if exists idiom_revisions;
@@ -0,0 +1,33 @@
+create table if not exists idiom_revisions (
+  id bigserial primary key,
+  idiom_id text not null,
+  idiom text not null,
+  meaning_brief text,
+  meaning_full text,
+  description text,
+  examples jsonb not null default '[]'::jsonb,
+  thumbnail text,
+  thumbnails jsonb,
+  prompt_version text,
+  source text not null check (source in ('ai', 'admin')),
+  author text,
+  restored_from bigint,
+  created_at timestamp not null default (now() at time zone 'utc')
+);
+
+create index if not exists idiom_revisions_idiom_id on idiom_revisions (idiom_id, id desc);
+
+insert into idiom_revisions (idiom_id, idiom, meaning_brief, meaning_full, description, examples, thumbnail, thumbnails, prompt_version, source)
+select
+  idioms.id,
+  idioms.idiom,
+  idioms.meaning_brief,
+  idioms.meaning_full,
+  idioms.description,
+  coalesce((select jsonb_agg(expression) from idiom_examples where idiom_id = idioms.id), '[]'::jsonb),
+  idioms.thumbnail,
+  idioms.thumbnails,
+  idioms.prompt_version,
+  'ai'
+from idioms
+where not exists (select 1 from idiom_revisions where idiom_revisions.idiom_id = idioms.id);
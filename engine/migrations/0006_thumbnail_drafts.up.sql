@@ -0,0 +1,16 @@
+create table if not exists thumbnail_drafts (
+  id bigserial primary key,
+  idiom_id text not null,
+  prompt text not null,
+  model text not null,
+  storage_key text not null unique,
+  content_type text not null,
+  created_by text,
+  created_at timestamp not null default (now() at time zone 'utc'),
+  expires_at timestamp,
+  promoted_at timestamp,
+  promoted_by text
+);
+
+create index if not exists thumbnail_drafts_idiom_id on thumbnail_drafts (idiom_id, id desc);
+create index if not exists thumbnail_drafts_expires_at on thumbnail_drafts (expires_at) where promoted_at is null;
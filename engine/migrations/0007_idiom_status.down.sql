@@ -0,0 +1,5 @@
+drop index if exists idioms_publish_at;
+drop index if exists idioms_status_published_at;
+alter table idioms drop constraint if exists idioms_status_check;
+alter table idioms drop column if exists publish_at;
+alter table idioms drop column if exists status;
@@ -0,0 +1,14 @@
+alter table idioms add column if not exists status text not null default 'draft';
+alter table idioms add column if not exists publish_at timestamp;
+
+do $$
+begin
+  if not exists (select 1 from pg_constraint where conname = 'idioms_status_check') then
+    alter table idioms add constraint idioms_status_check check (status in ('draft', 'in_review', 'published', 'archived'));
+    -- Idioms with a thumbnail were public before there was a status.
+    update idioms set status = 'published', published_at = coalesce(published_at, created_at) where thumbnail is not null;
+  end if;
+end $$;
+
+create index if not exists idioms_status_published_at on idioms (status, published_at desc);
+create index if not exists idioms_publish_at on idioms (publish_at) where status = 'in_review' and publish_at is not null;
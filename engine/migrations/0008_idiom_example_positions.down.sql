@@ -0,0 +1,4 @@
+drop index if exists idiom_examples_idiom_position;
+drop index if exists idiom_examples_id;
+alter table idiom_examples drop column if exists position;
+alter table idiom_examples drop column if exists id;
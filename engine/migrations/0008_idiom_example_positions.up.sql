@@ -0,0 +1,17 @@
+alter table idiom_examples add column if not exists id bigserial;
+alter table idiom_examples add column if not exists position integer;
+
+-- Existing examples keep the order they were inserted in.
+update idiom_examples
+set position = ordered.position
+from (
+  select ctid, row_number() over (partition by idiom_id order by ctid) - 1 as position
+  from idiom_examples
+) as ordered
+where idiom_examples.ctid = ordered.ctid and idiom_examples.position is null;
+
+alter table idiom_examples alter column position set not null;
+alter table idiom_examples alter column position set default 0;
+
+create unique index if not exists idiom_examples_id on idiom_examples (id);
+create index if not exists idiom_examples_idiom_position on idiom_examples (idiom_id, position, id);
@@ -0,0 +1,4 @@
+drop table if exists idiom_tags;
+drop table if exists idiom_categories;
+drop table if exists tags;
+drop table if exists categories;
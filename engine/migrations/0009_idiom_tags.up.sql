@@ -0,0 +1,31 @@
+create table if not exists categories (
+  id text primary key,
+  name text not null,
+  description text,
+  created_at timestamp not null default (now() at time zone 'utc')
+);
+
+create table if not exists tags (
+  id text primary key,
+  name text not null,
+  created_at timestamp not null default (now() at time zone 'utc')
+);
+
+create table if not exists idiom_categories (
+  idiom_id text not null,
+  category_id text not null references categories (id) on delete cascade,
+  created_at timestamp not null default (now() at time zone 'utc'),
+  primary key (idiom_id, category_id)
+);
+
+-- source is ai for the tags suggested during generation and admin otherwise.
+create table if not exists idiom_tags (
+  idiom_id text not null,
+  tag_id text not null references tags (id) on delete cascade,
+  source text not null default 'admin',
+  created_at timestamp not null default (now() at time zone 'utc'),
+  primary key (idiom_id, tag_id)
+);
+
+create index if not exists idiom_categories_category_id on idiom_categories (category_id, idiom_id);
+create index if not exists idiom_tags_tag_id on idiom_tags (tag_id, idiom_id);
@@ -0,0 +1,13 @@
+drop index if exists idioms_embedding_stale;
+drop trigger if exists idiom_examples_content_hash on idiom_examples;
+drop function if exists idiom_examples_content_hash_trigger();
+drop trigger if exists idioms_content_hash on idioms;
+drop function if exists idioms_content_hash_trigger();
+drop function if exists idioms_embedding_source(text, text, text, text);
+drop function if exists idioms_embedding_examples(text);
+drop index if exists idioms_embedding;
+alter table idioms drop column if exists content_hash;
+alter table idioms drop column if exists embedded_at;
+alter table idioms drop column if exists embedding_hash;
+alter table idioms drop column if exists embedding_model;
+alter table idioms drop column if exists embedding;
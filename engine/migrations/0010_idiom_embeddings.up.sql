@@ -0,0 +1,60 @@
+create extension if not exists vector;
+
+-- embedding_hash is the md5 of the text which was embedded, and content_hash the md5 of the text
+-- to embed now, kept current by triggers like search_document. The worker embeds an idiom again when
+-- they differ, which compares two columns instead of hashing every idiom.
+alter table idioms add column if not exists embedding vector(1536);
+alter table idioms add column if not exists embedding_model text;
+alter table idioms add column if not exists embedding_hash text;
+alter table idioms add column if not exists embedded_at timestamp;
+alter table idioms add column if not exists content_hash text;
+
+create index if not exists idioms_embedding on idioms using hnsw (embedding vector_cosine_ops);
+
+create or replace function idioms_embedding_examples(target_id text) returns text as $$
+  select string_agg(expression, E'\n' order by position, id) from idiom_examples where idiom_id = target_id
+$$ language sql stable;
+
+create or replace function idioms_embedding_source(idiom text, meaning_brief text, meaning_full text, examples text) returns text as $$
+  select concat_ws(E'\n', idiom, meaning_brief, meaning_full, examples)
+$$ language sql immutable;
+
+create or replace function idioms_content_hash_trigger() returns trigger as $$
+begin
+  new.content_hash := md5(idioms_embedding_source(new.idiom, new.meaning_brief, new.meaning_full, idioms_embedding_examples(new.id)));
+  return new;
+end
+$$ language plpgsql;
+
+drop trigger if exists idioms_content_hash on idioms;
+create trigger idioms_content_hash
+  before insert or update of idiom, meaning_brief, meaning_full on idioms
+  for each row execute function idioms_content_hash_trigger();
+
+create or replace function idiom_examples_content_hash_trigger() returns trigger as $$
+begin
+  if tg_op in ('UPDATE', 'DELETE') then
+    update idioms
+    set content_hash = md5(idioms_embedding_source(idiom, meaning_brief, meaning_full, idioms_embedding_examples(id)))
+    where id = old.idiom_id;
+  end if;
+  if tg_op in ('INSERT', 'UPDATE') then
+    update idioms
+    set content_hash = md5(idioms_embedding_source(idiom, meaning_brief, meaning_full, idioms_embedding_examples(id)))
+    where id = new.idiom_id;
+  end if;
+  return null;
+end
+$$ language plpgsql;
+
+drop trigger if exists idiom_examples_content_hash on idiom_examples;
+create trigger idiom_examples_content_hash
+  after insert or update or delete on idiom_examples
+  for each row execute function idiom_examples_content_hash_trigger();
+
+update idioms
+set content_hash = md5(idioms_embedding_source(idiom, meaning_brief, meaning_full, idioms_embedding_examples(id)));
+
+-- idioms_embedding_stale finds the published idioms whose embedding is missing or stale without
+-- reading the idioms which are up to date.
+create index if not exists idioms_embedding_stale on idioms (id) where status = 'published' and embedding_hash is distinct from content_hash;
@@ -0,0 +1,311 @@
+package engine
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/logger"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockKey is the advisory lock every instance takes before migrating, so
+// instances started together apply each migration once.
+const migrationLockKey int64 = 0x1d10_5000
+
+const schemaMigrationsQuery = `
+create table if not exists schema_migrations (
+  version bigint primary key,
+  name text not null,
+  applied_at timestamp not null default (now() at time zone 'utc')
+)`
+
+// baseVersion is the migration which adopts the tables that existed before the migrations.
+// It is never reverted, so Down stops above it.
+const baseVersion int64 = 0
+
+var migrationName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version   int64            `json:"version"`
+	Name      string           `json:"name"`
+	Applied   bool             `json:"applied"`
+	AppliedAt pgtype.Timestamp `json:"appliedAt"`
+
+	up   string
+	down string
+}
+
+type MigrationService interface {
+	Up(ctx context.Context) ([]Migration, error)
+	Down(ctx context.Context, steps int) ([]Migration, error)
+	Status(ctx context.Context) ([]Migration, error)
+}
+
+type Migrator struct {
+	db     *sqlx.DB
+	logger logger.LoggerService
+
+	migrations []Migration
+}
+
+// NewMigrator loads the migrations embedded under migrations/.
+func NewMigrator(db *sqlx.DB, logger logger.LoggerService) (*Migrator, error) {
+	migrator := new(Migrator)
+	migrator.db = db
+	migrator.logger = logger
+
+	migrations, err := parseMigrations(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrator.migrations = migrations
+
+	return migrator, nil
+}
+
+// parseMigrations pairs the NNNN_name.up.sql and NNNN_name.down.sql files of dir and sorts them by version.
+func parseMigrations(files fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		matches := migrationName.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s", entry.Name())
+		}
+		body, err := fs.ReadFile(files, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			migration.up = string(body)
+		} else {
+			migration.down = string(body)
+		}
+	}
+
+	migrations := []Migration{}
+	for _, migration := range byVersion {
+		if len(migration.up) == 0 || len(migration.down) == 0 {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Up applies every pending migration in order, each in its own transaction.
+func (migrator *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	conn, err := migrator.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer migrator.unlock(conn)
+
+	applied, err := migrator.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	for version := range applied {
+		if migrator.find(version) == nil {
+			migrator.logger.Warn("The database has a migration this build does not know.", version)
+		}
+	}
+
+	done := []Migration{}
+	for _, migration := range migrator.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err = migrator.apply(ctx, conn, migration.up, "insert into schema_migrations (version, name) values ($1, $2)", migration.Version, migration.Name)
+		if err != nil {
+			migrator.logger.Error(err, "Failed to apply the migration.", migration.Version, migration.Name)
+			return done, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		migrator.logger.Info("Applied the migration.", migration.Version, migration.Name)
+		migration.Applied = true
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the latest steps applied migrations, newest first.
+func (migrator *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be at least 1, received %d", steps)
+	}
+	conn, err := migrator.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer migrator.unlock(conn)
+
+	applied, err := migrator.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := revertedVersions(applied, steps)
+	if err != nil {
+		return nil, err
+	}
+
+	done := []Migration{}
+	for _, version := range versions {
+		migration := migrator.find(version)
+		if migration == nil {
+			return done, fmt.Errorf("migration %d is applied but not part of this build", version)
+		}
+		err = migrator.apply(ctx, conn, migration.down, "delete from schema_migrations where version = $1", migration.Version)
+		if err != nil {
+			migrator.logger.Error(err, "Failed to revert the migration.", migration.Version, migration.Name)
+			return done, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		migrator.logger.Info("Reverted the migration.", migration.Version, migration.Name)
+		done = append(done, *migration)
+	}
+	return done, nil
+}
+
+// revertedVersions returns the latest steps applied versions, newest first, and refuses to
+// revert the base migration.
+func revertedVersions(applied map[int64]pgtype.Timestamp, steps int) ([]int64, error) {
+	versions := []int64{}
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] > versions[j]
+	})
+	if steps < len(versions) {
+		versions = versions[:steps]
+	}
+	for _, version := range versions {
+		if version <= baseVersion {
+			return nil, fmt.Errorf("migration %d cannot be reverted, at most %d steps can", version, len(versions)-1)
+		}
+	}
+	return versions, nil
+}
+
+func (migrator *Migrator) Status(ctx context.Context) ([]Migration, error) {
+	conn, err := migrator.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer migrator.unlock(conn)
+
+	applied, err := migrator.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	migrations := []Migration{}
+	for _, migration := range migrator.migrations {
+		migration.AppliedAt, migration.Applied = applied[migration.Version]
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// lock holds the advisory lock on a dedicated connection, since it belongs to the session that took it.
+func (migrator *Migrator) lock(ctx context.Context) (*sqlx.Conn, error) {
+	conn, err := migrator.db.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.ExecContext(ctx, "select pg_advisory_lock($1)", migrationLockKey)
+	if err != nil {
+		conn.Close()
+		migrator.logger.Error(err, "Failed to take the migration lock.")
+		return nil, err
+	}
+	_, err = conn.ExecContext(ctx, schemaMigrationsQuery)
+	if err != nil {
+		migrator.unlock(conn)
+		migrator.logger.Error(err, "Failed to create schema_migrations.")
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (migrator *Migrator) unlock(conn *sqlx.Conn) {
+	_, err := conn.ExecContext(context.Background(), "select pg_advisory_unlock($1)", migrationLockKey)
+	if err != nil {
+		migrator.logger.Error(err, "Failed to release the migration lock.")
+	}
+	conn.Close()
+}
+
+func (migrator *Migrator) applied(ctx context.Context, conn *sqlx.Conn) (map[int64]pgtype.Timestamp, error) {
+	rows := []struct {
+		Version   int64            `db:"version"`
+		AppliedAt pgtype.Timestamp `db:"applied_at"`
+	}{}
+	err := conn.SelectContext(ctx, &rows, "select version, applied_at from schema_migrations")
+	if err != nil {
+		migrator.logger.Error(err, "Failed to query schema_migrations.")
+		return nil, err
+	}
+	applied := map[int64]pgtype.Timestamp{}
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+// apply runs body and records it with query in one transaction, so a failed migration leaves no trace.
+func (migrator *Migrator) apply(ctx context.Context, conn *sqlx.Conn, body string, query string, args ...interface{}) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, body)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (migrator *Migrator) find(version int64) *Migration {
+	for index := range migrator.migrations {
+		if migrator.migrations[index].Version == version {
+			return &migrator.migrations[index]
+		}
+	}
+	return nil
+}
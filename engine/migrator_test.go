@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParseMigrations(t *testing.T) {
+	files := fstest.MapFS{
+		"migrations/0002_b.up.sql":   {Data: []byte("create table b ();")},
+		"migrations/0002_b.down.sql": {Data: []byte("drop table b;")},
+		"migrations/0001_a.up.sql":   {Data: []byte("create table a ();")},
+		"migrations/0001_a.down.sql": {Data: []byte("drop table a;")},
+	}
+	migrations, err := parseMigrations(files, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[1].Name != "b" {
+		t.Fatalf("Unexpected migrations %v", migrations)
+	}
+	if migrations[0].up != "create table a ();" || migrations[0].down != "drop table a;" {
+		t.Errorf("Unexpected bodies %q and %q", migrations[0].up, migrations[0].down)
+	}
+
+	invalid := map[string]fstest.MapFS{
+		"missing down": {
+			"migrations/0001_a.up.sql": {Data: []byte("select 1;")},
+		},
+		"file name": {
+			"migrations/0001-a.up.sql":   {Data: []byte("select 1;")},
+			"migrations/0001-a.down.sql": {Data: []byte("select 1;")},
+		},
+		"different names": {
+			"migrations/0001_a.up.sql":   {Data: []byte("select 1;")},
+			"migrations/0001_b.down.sql": {Data: []byte("select 1;")},
+		},
+	}
+	for name, files := range invalid {
+		if _, err := parseMigrations(files, "migrations"); err == nil {
+			t.Errorf("Expected an error for the %s", name)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := parseMigrations(migrationFiles, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, migration := range migrations {
+		if migration.Version != int64(index) {
+			t.Errorf("Expected version %d, received %d_%s", index, migration.Version, migration.Name)
+		}
+	}
+	if migrations[0].Name != "init" || !strings.Contains(migrations[0].up, "thumbnail_prompt") {
+		t.Errorf("Expected the first migration to create the base tables")
+	}
+}
+
+func TestRevertedVersions(t *testing.T) {
+	applied := map[int64]pgtype.Timestamp{0: {}, 1: {}, 2: {}}
+	versions, err := revertedVersions(applied, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []int64{2, 1}) {
+		t.Errorf("Expected to revert 2 and 1, received %v", versions)
+	}
+	for _, steps := range []int{3, 10} {
+		if _, err := revertedVersions(applied, steps); err == nil {
+			t.Errorf("Expected %d steps to be refused for reverting the base migration", steps)
+		}
+	}
+}
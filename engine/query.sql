@@ -0,0 +1,354 @@
+-- name: GetIdiom :one
+select id, idiom, meaning_brief, meaning_full, created_at, published_at, thumbnail, thumbnails, description, num_id, prompt_version, status, publish_at
+from idioms
+where id = @id and (not @published::boolean or status = 'published');
+
+-- name: GetIdiomStatus :one
+select status from idioms where id = $1;
+
+-- name: IdiomExists :one
+select exists (select 1 from idioms where id = $1);
+
+-- name: ListIdiomExamples :many
+select id, idiom_id, expression, position
+from idiom_examples
+where idiom_id = $1
+order by position, id;
+
+-- name: GetIdiomExample :one
+select id, idiom_id, expression, position
+from idiom_examples
+where idiom_id = $1 and id = $2;
+
+-- name: CountIdiomExamples :one
+select count(*)::int from idiom_examples where idiom_id = $1;
+
+-- name: CreateIdiomExample :one
+insert into idiom_examples (idiom_id, expression, position)
+values ($1, $2, $3)
+returning id, idiom_id, expression, position;
+
+-- name: CreateIdiomExamples :exec
+-- CreateIdiomExamples inserts the expressions as the examples of the idiom, in their order from position 0.
+insert into idiom_examples (idiom_id, expression, position)
+select @idiom_id::text, examples.expression, examples.position - 1
+from unnest(@expressions::text[]) with ordinality as examples(expression, position);
+
+-- name: UpdateIdiomExample :one
+-- UpdateIdiomExample changes only the fields which are not null.
+update idiom_examples
+set expression = coalesce(sqlc.narg('expression')::text, expression), position = coalesce(sqlc.narg('position')::int, position)
+where id = @id
+returning id, idiom_id, expression, position;
+
+-- name: ShiftIdiomExamples :exec
+-- ShiftIdiomExamples moves the examples of the idiom from from_position up to, and not including,
+-- to_position by the shift.
+update idiom_examples
+set position = position + @shift::int
+where idiom_id = @idiom_id and position >= @from_position::int and position < @to_position::int;
+
+-- name: ReorderIdiomExamples :exec
+-- ReorderIdiomExamples moves each example of ids to its index in ids.
+update idiom_examples
+set position = ordered.position - 1
+from unnest(@ids::bigint[]) with ordinality as ordered(id, position)
+where idiom_examples.id = ordered.id and idiom_examples.idiom_id = @idiom_id;
+
+-- name: DeleteIdiomExample :exec
+delete from idiom_examples where id = $1;
+
+-- name: DeleteIdiomExamples :exec
+delete from idiom_examples where idiom_id = $1;
+
+-- name: ListIdiomTags :many
+select tags.id, tags.name, tags.created_at
+from tags
+join idiom_tags on idiom_tags.tag_id = tags.id
+where idiom_tags.idiom_id = $1
+order by tags.name;
+
+-- name: ListIdiomCategories :many
+select categories.id, categories.name, categories.description, categories.created_at
+from categories
+join idiom_categories on idiom_categories.category_id = categories.id
+where idiom_categories.idiom_id = $1
+order by categories.name;
+
+-- name: ListIdioms :many
+-- ListIdioms returns a page in the given direction, after the (sort value, id) key of the cursor
+-- when there is one. An idiom needs every tag of tags to match, and an idiom which was never
+-- published sorts as published at infinity, as nulls sort in Postgres.
+select id, idiom, meaning_brief, meaning_full, created_at, published_at, thumbnail, thumbnails, description, num_id, prompt_version, status, publish_at
+from idioms
+where (not @published::boolean or status = 'published')
+  and (sqlc.narg('status')::text is null or status = sqlc.narg('status'))
+  and (cardinality(@tags::text[]) = 0 or id in (
+    select idiom_id from idiom_tags where tag_id = any(@tags::text[]) group by idiom_id having count(*) = cardinality(@tags::text[])
+  ))
+  and (sqlc.narg('category')::text is null or id in (select idiom_id from idiom_categories where category_id = sqlc.narg('category')))
+  and (sqlc.narg('cursor_id')::text is null or case
+    when @order_by::text = 'idiom' and @descending::boolean then (idiom, id) < (sqlc.narg('cursor_idiom')::text, sqlc.narg('cursor_id'))
+    when @order_by = 'idiom' then (idiom, id) > (sqlc.narg('cursor_idiom'), sqlc.narg('cursor_id'))
+    when @order_by = 'created_at' and @descending then (created_at, id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id'))
+    when @order_by = 'created_at' then (created_at, id) > (sqlc.narg('cursor_time'), sqlc.narg('cursor_id'))
+    when @descending then (coalesce(published_at, 'infinity'), id) < (sqlc.narg('cursor_time'), sqlc.narg('cursor_id'))
+    else (coalesce(published_at, 'infinity'), id) > (sqlc.narg('cursor_time'), sqlc.narg('cursor_id'))
+  end)
+order by
+  case when @order_by = 'idiom' and not @descending then idiom end asc,
+  case when @order_by = 'idiom' and @descending then idiom end desc,
+  case when @order_by = 'created_at' and not @descending then created_at end asc,
+  case when @order_by = 'created_at' and @descending then created_at end desc,
+  case when @order_by = 'published_at' and not @descending then coalesce(published_at, 'infinity') end asc,
+  case when @order_by = 'published_at' and @descending then coalesce(published_at, 'infinity') end desc,
+  case when not @descending then id end asc,
+  case when @descending then id end desc
+limit @count::int;
+
+-- name: SearchIdioms :many
+-- SearchIdioms matches the keyword against the search document, the trigram similarity and a
+-- substring of the idiom, and highlights the matches of the page only. It pages like ListIdioms,
+-- and by (rank, id) as well.
+with search as (
+  select to_tsquery('english', @query::text) as query, @keyword::text as keyword
+), matches as (
+  select
+    idioms.id, idioms.idiom, idioms.meaning_brief, idioms.meaning_full, idioms.created_at, idioms.published_at, idioms.thumbnail, idioms.thumbnails, idioms.description, idioms.num_id, idioms.prompt_version, idioms.status, idioms.publish_at,
+    round((ts_rank_cd(idioms.search_document, search.query, 32) + word_similarity(search.keyword, idioms.idiom))::numeric, 6)::float8 as rank,
+    search.query as search_query
+  from idioms
+  cross join search
+  where (idioms.search_document @@ search.query or search.keyword <% idioms.idiom or idioms.idiom ilike @pattern::text)
+    and (not @published::boolean or idioms.status = 'published')
+    and (cardinality(@tags::text[]) = 0 or idioms.id in (
+      select idiom_id from idiom_tags where tag_id = any(@tags::text[]) group by idiom_id having count(*) = cardinality(@tags::text[])
+    ))
+    and (sqlc.narg('category')::text is null or idioms.id in (select idiom_id from idiom_categories where category_id = sqlc.narg('category')))
+), page as (
+  select * from matches
+  where (sqlc.narg('cursor_id')::text is null or case
+      when @order_by::text = 'rank' and @descending::boolean then (rank, id) < (sqlc.narg('cursor_rank')::float8, sqlc.narg('cursor_id'))
+      when @order_by = 'rank' then (rank, id) > (sqlc.narg('cursor_rank'), sqlc.narg('cursor_id'))
+      when @order_by = 'idiom' and @descending then (idiom, id) < (sqlc.narg('cursor_idiom')::text, sqlc.narg('cursor_id'))
+      when @order_by = 'idiom' then (idiom, id) > (sqlc.narg('cursor_idiom'), sqlc.narg('cursor_id'))
+      when @order_by = 'created_at' and @descending then (created_at, id) < (sqlc.narg('cursor_time')::timestamp, sqlc.narg('cursor_id'))
+      when @order_by = 'created_at' then (created_at, id) > (sqlc.narg('cursor_time'), sqlc.narg('cursor_id'))
+      when @descending then (coalesce(published_at, 'infinity'), id) < (sqlc.narg('cursor_time'), sqlc.narg('cursor_id'))
+      else (coalesce(published_at, 'infinity'), id) > (sqlc.narg('cursor_time'), sqlc.narg('cursor_id'))
+    end)
+  order by
+    case when @order_by = 'rank' and not @descending then rank end asc,
+    case when @order_by = 'rank' and @descending then rank end desc,
+    case when @order_by = 'idiom' and not @descending then idiom end asc,
+    case when @order_by = 'idiom' and @descending then idiom end desc,
+    case when @order_by = 'created_at' and not @descending then created_at end asc,
+    case when @order_by = 'created_at' and @descending then created_at end desc,
+    case when @order_by = 'published_at' and not @descending then coalesce(published_at, 'infinity') end asc,
+    case when @order_by = 'published_at' and @descending then coalesce(published_at, 'infinity') end desc,
+    case when not @descending then id end asc,
+    case when @descending then id end desc
+  limit @count::int
+)
+select
+  page.id, page.idiom, page.meaning_brief, page.meaning_full, page.created_at, page.published_at, page.thumbnail, page.thumbnails, page.description, page.num_id, page.prompt_version, page.status, page.publish_at,
+  page.rank,
+  ts_headline('english', page.idiom, page.search_query, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>')::text as highlight_idiom,
+  ts_headline('english', page.meaning_brief, page.search_query, 'HighlightAll=true, StartSel=<mark>, StopSel=</mark>')::text as highlight_meaning_brief,
+  ts_headline('english', page.meaning_full, page.search_query, 'MaxFragments=2, MaxWords=24, MinWords=8, FragmentDelimiter=" … ", StartSel=<mark>, StopSel=</mark>')::text as highlight_meaning_full,
+  ts_headline('english', coalesce(page.description, ''), page.search_query, 'MaxFragments=2, MaxWords=24, MinWords=8, FragmentDelimiter=" … ", StartSel=<mark>, StopSel=</mark>')::text as highlight_description,
+  ts_headline('english', idioms_examples_text(page.id), page.search_query, 'MaxFragments=2, MaxWords=24, MinWords=8, FragmentDelimiter=" … ", StartSel=<mark>, StopSel=</mark>')::text as highlight_examples
+from page
+order by
+  case when @order_by::text = 'rank' and not @descending::boolean then page.rank end asc,
+  case when @order_by = 'rank' and @descending then page.rank end desc,
+  case when @order_by = 'idiom' and not @descending then page.idiom end asc,
+  case when @order_by = 'idiom' and @descending then page.idiom end desc,
+  case when @order_by = 'created_at' and not @descending then page.created_at end asc,
+  case when @order_by = 'created_at' and @descending then page.created_at end desc,
+  case when @order_by = 'published_at' and not @descending then coalesce(page.published_at, 'infinity') end asc,
+  case when @order_by = 'published_at' and @descending then coalesce(page.published_at, 'infinity') end desc,
+  case when not @descending then page.id end asc,
+  case when @descending then page.id end desc;
+
+-- name: ListRelatedIdioms :many
+-- ListRelatedIdioms returns the four idioms published before and after the idiom.
+with target as (
+  select published_at from idioms where idioms.id = $1
+)
+select related.id, related.idiom, related.meaning_brief, related.meaning_full, related.created_at, related.published_at, related.thumbnail, related.thumbnails, related.description, related.num_id, related.prompt_version, related.status, related.publish_at
+from (
+  (
+    select idioms.id, idioms.idiom, idioms.meaning_brief, idioms.meaning_full, idioms.created_at, idioms.published_at, idioms.thumbnail, idioms.thumbnails, idioms.description, idioms.num_id, idioms.prompt_version, idioms.status, idioms.publish_at
+    from idioms, target
+    where idioms.status = 'published' and idioms.published_at > target.published_at
+    order by idioms.published_at asc
+    limit 4
+  )
+  union all
+  (
+    select idioms.id, idioms.idiom, idioms.meaning_brief, idioms.meaning_full, idioms.created_at, idioms.published_at, idioms.thumbnail, idioms.thumbnails, idioms.description, idioms.num_id, idioms.prompt_version, idioms.status, idioms.publish_at
+    from idioms, target
+    where idioms.status = 'published' and idioms.published_at < target.published_at
+    order by idioms.published_at desc
+    limit 4
+  )
+) as related
+order by related.published_at desc;
+
+-- name: ListSimilarIdioms :many
+-- ListSimilarIdioms returns the published idioms nearest to the idiom by the cosine distance of
+-- embeddings of the same model, which are none until the idiom is embedded.
+select id, idiom, meaning_brief, meaning_full, created_at, published_at, thumbnail, thumbnails, description, num_id, prompt_version, status, publish_at
+from idioms
+where idioms.status = 'published' and idioms.id <> @id and idioms.embedding_model = (select target.embedding_model from idioms as target where target.id = @id)
+order by idioms.embedding <=> (select target.embedding from idioms as target where target.id = @id)
+limit @count::int;
+
+-- name: ListIdiomsToEmbed :many
+-- ListIdiomsToEmbed returns the text of the published idioms which have no embedding of the model,
+-- or whose meanings or examples changed after they were embedded.
+select id, idioms_embedding_source(idiom, meaning_brief, meaning_full, idioms_embedding_examples(id))::text as source, content_hash
+from idioms
+where status = 'published' and (embedding_hash is distinct from content_hash or embedding_model is distinct from @model::text)
+order by id
+limit @count::int;
+
+-- name: UpdateIdiomEmbedding :exec
+-- The embedding is passed in the text format of pgvector, e.g. [0.1,0.2,0.3].
+update idioms
+set embedding = cast(@embedding::text as vector), embedding_model = @model, embedding_hash = @hash, embedded_at = now() at time zone 'utc'
+where id = @id;
+
+-- name: ListMainPageIdioms :many
+select id, idiom, meaning_brief, meaning_full, created_at, published_at, thumbnail, thumbnails, description, num_id, prompt_version, status, publish_at
+from idioms
+where status = 'published'
+order by published_at desc
+limit 24;
+
+-- name: CreateIdiom :exec
+insert into idioms (id, idiom, meaning_brief, meaning_full, description, thumbnail_prompt, prompt_version, status)
+values ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: PatchIdiom :execrows
+-- PatchIdiom changes only the fields which are not null.
+update idioms
+set
+  idiom = coalesce(sqlc.narg('idiom')::text, idiom),
+  meaning_brief = coalesce(sqlc.narg('meaning_brief')::text, meaning_brief),
+  meaning_full = coalesce(sqlc.narg('meaning_full')::text, meaning_full),
+  description = coalesce(sqlc.narg('description')::text, description),
+  thumbnail_prompt = coalesce(sqlc.narg('thumbnail_prompt')::text, thumbnail_prompt)
+where id = @id;
+
+-- name: UpdateIdiomMeanings :execrows
+-- The prompt version is kept when it is null, as for the meanings written by an admin.
+update idioms
+set meaning_brief = @meaning_brief, meaning_full = @meaning_full, prompt_version = coalesce(sqlc.narg('prompt_version')::text, prompt_version)
+where id = @id;
+
+-- name: UpdateIdiomDescription :execrows
+update idioms set description = @description, prompt_version = @prompt_version where id = @id;
+
+-- name: UpdateIdiomStatus :one
+-- UpdateIdiomStatus returns no rows when the status is not current anymore. An idiom published
+-- again keeps its first publication date and its place in listings.
+update idioms
+set
+  status = @status::text,
+  publish_at = null,
+  published_at = case when @status = 'published' then coalesce(published_at, now() at time zone 'utc') else published_at end
+where id = @id and status = @current::text
+returning id, idiom, meaning_brief, meaning_full, created_at, published_at, thumbnail, thumbnails, description, num_id, prompt_version, status, publish_at;
+
+-- name: ScheduleIdiom :one
+-- ScheduleIdiom keeps the idiom in its status until PublishScheduledIdioms publishes it at publish_at.
+update idioms
+set publish_at = @publish_at
+where id = @id and status = @current::text
+returning id, idiom, meaning_brief, meaning_full, created_at, published_at, thumbnail, thumbnails, description, num_id, prompt_version, status, publish_at;
+
+-- name: PublishScheduledIdioms :many
+update idioms
+set status = 'published', published_at = coalesce(published_at, publish_at), publish_at = null
+where status = 'in_review' and publish_at <= now() at time zone 'utc'
+returning id;
+
+-- name: SetIdiomThumbnail :execrows
+update idioms set thumbnail = @thumbnail, thumbnails = @thumbnails where id = @id;
+
+-- name: LockIdiom :one
+-- LockIdiom holds the idiom until the end of the transaction, for the edits of its examples.
+select id from idioms where id = $1 for update;
+
+-- name: DeleteIdiom :execrows
+-- DeleteIdiom deletes an idiom with its examples, drafts and topics. Its revisions are kept.
+with examples as (
+  delete from idiom_examples where idiom_examples.idiom_id = @id
+), drafts as (
+  delete from thumbnail_drafts where thumbnail_drafts.idiom_id = @id
+), tags as (
+  delete from idiom_tags where idiom_tags.idiom_id = @id
+), categories as (
+  delete from idiom_categories where idiom_categories.idiom_id = @id
+)
+delete from idioms where idioms.id = @id;
+
+-- name: GetThumbnailPrompt :one
+select thumbnail_prompt from idioms where id = $1;
+
+-- name: UpdateThumbnailPrompt :execrows
+update idioms set thumbnail_prompt = @thumbnail_prompt where id = @id;
+
+-- name: CreateThumbnailDraft :one
+insert into thumbnail_drafts (idiom_id, prompt, model, storage_key, content_type, created_by, expires_at)
+values ($1, $2, $3, $4, $5, $6, $7)
+returning id, idiom_id, prompt, model, storage_key, content_type, created_by, created_at, expires_at, promoted_at, promoted_by;
+
+-- name: GetThumbnailDraft :one
+select id, idiom_id, prompt, model, storage_key, content_type, created_by, created_at, expires_at, promoted_at, promoted_by
+from thumbnail_drafts
+where idiom_id = $1 and id = $2;
+
+-- name: ListThumbnailDrafts :many
+select id, idiom_id, prompt, model, storage_key, content_type, created_by, created_at, expires_at, promoted_at, promoted_by
+from thumbnail_drafts
+where idiom_id = $1
+order by id desc;
+
+-- name: ListExpiredThumbnailDrafts :many
+select id, idiom_id, prompt, model, storage_key, content_type, created_by, created_at, expires_at, promoted_at, promoted_by
+from thumbnail_drafts
+where promoted_at is null and expires_at <= now() at time zone 'utc'
+order by expires_at
+limit $1;
+
+-- name: PromoteThumbnailDraft :one
+-- A promoted draft is kept as the history of the thumbnail and never expires.
+update thumbnail_drafts
+set promoted_at = now() at time zone 'utc', promoted_by = $2, expires_at = null
+where id = $1
+returning id, idiom_id, prompt, model, storage_key, content_type, created_by, created_at, expires_at, promoted_at, promoted_by;
+
+-- name: DeleteThumbnailDrafts :exec
+delete from thumbnail_drafts where id = any(@ids::bigint[]);
+
+-- name: GetIdiomInput :one
+select id, idiom, meaning, created_at from idiom_inputs where id = $1;
+
+-- name: ListIdiomIDs :many
+select id from idioms where id = any(@ids::text[]);
+
+-- name: ListIdiomInputIDs :many
+select id from idiom_inputs where id = any(@ids::text[]);
+
+-- name: CreateIdiomInputs :many
+-- CreateIdiomInputs inserts the inputs which are not in idiom_inputs yet and returns their ids.
+insert into idiom_inputs (id, idiom, meaning)
+select unnest(@ids::text[]), unnest(@idioms::text[]), unnest(@meanings::text[])
+on conflict (id) do nothing
+returning id;
+
+-- name: DeleteIdiomInput :exec
+delete from idiom_inputs where id = $1;
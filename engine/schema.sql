@@ -0,0 +1,193 @@
+-- The schema the migrations in engine/migrations produce, kept in one file for sqlc.
+-- Change it together with a new migration. The triggers keeping search_document and content_hash current only live in the migrations.
+
+create extension if not exists pg_trgm;
+create extension if not exists vector;
+
+create table idioms (
+  id text primary key,
+  num_id bigserial not null,
+  idiom text not null,
+  meaning_brief text not null default '',
+  meaning_full text not null default '',
+  description text,
+  thumbnail text,
+  thumbnails jsonb,
+  thumbnail_prompt text,
+  created_at timestamp not null default (now() at time zone 'utc'),
+  published_at timestamp,
+  search_document tsvector,
+  prompt_version text,
+  status text not null default 'draft',
+  publish_at timestamp,
+  embedding vector(1536),
+  embedding_model text,
+  embedding_hash text,
+  embedded_at timestamp,
+  content_hash text,
+  constraint idioms_status_check check (status in ('draft', 'in_review', 'published', 'archived'))
+);
+
+create unique index idioms_num_id on idioms (num_id);
+create index idioms_created_at on idioms (created_at desc);
+create index idioms_search_document_idx on idioms using gin (search_document);
+create index idioms_idiom_trgm_idx on idioms using gin (idiom gin_trgm_ops);
+create index idioms_status_published_at on idioms (status, published_at desc);
+create index idioms_publish_at on idioms (publish_at) where status = 'in_review' and publish_at is not null;
+create index idioms_embedding on idioms using hnsw (embedding vector_cosine_ops);
+create index idioms_embedding_stale on idioms (id) where status = 'published' and embedding_hash is distinct from content_hash;
+
+create table idiom_examples (
+  idiom_id text not null,
+  expression text not null,
+  id bigserial not null,
+  position integer not null default 0
+);
+
+create index idiom_examples_idiom_id on idiom_examples (idiom_id);
+create unique index idiom_examples_id on idiom_examples (id);
+create index idiom_examples_idiom_position on idiom_examples (idiom_id, position, id);
+
+create table idiom_inputs (
+  id text primary key,
+  idiom text not null,
+  meaning text not null default '',
+  created_at timestamp not null default (now() at time zone 'utc')
+);
+
+create function idioms_examples_text(target_id text) returns text as $$
+  select coalesce(string_agg(expression, ' '), '') from idiom_examples where idiom_id = target_id
+$$ language sql stable;
+
+create function idioms_build_search_document(idiom text, meaning_brief text, meaning_full text, description text, examples text) returns tsvector as $$
+  select
+    setweight(to_tsvector('english', coalesce(idiom, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(meaning_brief, '')), 'B') ||
+    setweight(to_tsvector('english', coalesce(meaning_full, '')), 'C') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'C') ||
+    setweight(to_tsvector('english', coalesce(examples, '')), 'D')
+$$ language sql immutable;
+
+create function idioms_embedding_examples(target_id text) returns text as $$
+  select string_agg(expression, E'\n' order by position, id) from idiom_examples where idiom_id = target_id
+$$ language sql stable;
+
+create function idioms_embedding_source(idiom text, meaning_brief text, meaning_full text, examples text) returns text as $$
+  select concat_ws(E'\n', idiom, meaning_brief, meaning_full, examples)
+$$ language sql immutable;
+
+create table api_keys (
+  id uuid primary key,
+  name text not null,
+  prefix text not null unique,
+  key_hash text not null,
+  scopes jsonb not null default '[]'::jsonb,
+  created_by text,
+  created_at timestamp not null default (now() at time zone 'utc'),
+  last_used_at timestamp,
+  revoked_at timestamp
+);
+
+create table jobs (
+  id bigserial primary key,
+  kind text not null,
+  payload jsonb not null default '{}'::jsonb,
+  status text not null default 'pending' check (status in ('pending', 'running', 'succeeded', 'failed', 'dead')),
+  dedupe_key text,
+  attempts integer not null default 0,
+  max_attempts integer not null default 5,
+  run_at timestamp not null default (now() at time zone 'utc'),
+  locked_at timestamp,
+  locked_by text,
+  last_error text,
+  created_at timestamp not null default (now() at time zone 'utc'),
+  updated_at timestamp not null default (now() at time zone 'utc'),
+  finished_at timestamp
+);
+
+create index jobs_ready_idx on jobs (kind, run_at) where status in ('pending', 'running', 'failed');
+create index jobs_status_idx on jobs (status, updated_at desc);
+create unique index jobs_dedupe_key_idx on jobs (dedupe_key) where status in ('pending', 'running', 'failed');
+
+create table prompt_templates (
+  name text not null,
+  version integer not null check (version > 0),
+  body text not null,
+  active boolean not null default true,
+  created_by text,
+  created_at timestamp not null default (now() at time zone 'utc'),
+  primary key (name, version)
+);
+
+create table idiom_revisions (
+  id bigserial primary key,
+  idiom_id text not null,
+  idiom text not null,
+  meaning_brief text,
+  meaning_full text,
+  description text,
+  examples jsonb not null default '[]'::jsonb,
+  thumbnail text,
+  thumbnails jsonb,
+  prompt_version text,
+  source text not null check (source in ('ai', 'admin')),
+  author text,
+  restored_from bigint,
+  created_at timestamp not null default (now() at time zone 'utc')
+);
+
+create index idiom_revisions_idiom_id on idiom_revisions (idiom_id, id desc);
+
+create table thumbnail_drafts (
+  id bigserial primary key,
+  idiom_id text not null,
+  prompt text not null,
+  model text not null,
+  storage_key text not null unique,
+  content_type text not null,
+  created_by text,
+  created_at timestamp not null default (now() at time zone 'utc'),
+  expires_at timestamp,
+  promoted_at timestamp,
+  promoted_by text
+);
+
+create index thumbnail_drafts_idiom_id on thumbnail_drafts (idiom_id, id desc);
+create index thumbnail_drafts_expires_at on thumbnail_drafts (expires_at) where promoted_at is null;
+
+create table categories (
+  id text primary key,
+  name text not null,
+  description text,
+  created_at timestamp not null default (now() at time zone 'utc')
+);
+
+create table tags (
+  id text primary key,
+  name text not null,
+  created_at timestamp not null default (now() at time zone 'utc')
+);
+
+create table idiom_categories (
+  idiom_id text not null,
+  category_id text not null references categories (id) on delete cascade,
+  created_at timestamp not null default (now() at time zone 'utc'),
+  primary key (idiom_id, category_id)
+);
+
+create table idiom_tags (
+  idiom_id text not null,
+  tag_id text not null references tags (id) on delete cascade,
+  source text not null default 'admin',
+  created_at timestamp not null default (now() at time zone 'utc'),
+  primary key (idiom_id, tag_id)
+);
+
+create index idiom_categories_category_id on idiom_categories (category_id, idiom_id);
+create index idiom_tags_tag_id on idiom_tags (tag_id, idiom_id);
+
+create table schema_migrations (
+  version bigint primary key,
+  name text not null,
+  applied_at timestamp not null default (now() at time zone 'utc')
+);
@@ -0,0 +1,283 @@
+package exports
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/storage"
+
+	_ "modernc.org/sqlite"
+)
+
+// The model and the deck keep their ids, and every note a guid derived from the
+// idiom id, so that importing a newer export updates the cards instead of copying them.
+const (
+	ankiModelID = int64(1717000000001)
+	ankiDeckID  = int64(1717000000002)
+	ankiDeck    = "Idioms"
+)
+
+const ankiSchema = `
+create table col (id integer primary key, crt integer not null, mod integer not null, scm integer not null, ver integer not null, dty integer not null, usn integer not null, ls integer not null, conf text not null, models text not null, decks text not null, dconf text not null, tags text not null);
+create table notes (id integer primary key, guid text not null, mid integer not null, mod integer not null, usn integer not null, tags text not null, flds text not null, sfld integer not null, csum integer not null, flags integer not null, data text not null);
+create table cards (id integer primary key, nid integer not null, did integer not null, ord integer not null, mod integer not null, usn integer not null, type integer not null, queue integer not null, due integer not null, ivl integer not null, factor integer not null, reps integer not null, lapses integer not null, left integer not null, odue integer not null, odid integer not null, flags integer not null, data text not null);
+create table revlog (id integer primary key, cid integer not null, usn integer not null, ease integer not null, ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null, type integer not null);
+create table graves (usn integer not null, oid integer not null, type integer not null);
+create index ix_notes_usn on notes (usn);
+create index ix_cards_usn on cards (usn);
+create index ix_revlog_usn on revlog (usn);
+create index ix_cards_nid on cards (nid);
+create index ix_cards_sched on cards (did, queue, due);
+create index ix_revlog_cid on revlog (cid);
+create index ix_notes_csum on notes (csum);
+`
+
+const ankiCSS = `.card { font-family: arial; font-size: 20px; text-align: center; color: black; background-color: white; }
+.meaning { margin: 12px 0; }
+.examples { text-align: left; }
+img { max-width: 100%; }`
+
+// ankiWriter builds an Anki deck with a card per idiom. The notes are kept in a
+// SQLite collection in a temporary directory, while the images are streamed into
+// the archive as they are written.
+type ankiWriter struct {
+	ctx     context.Context
+	storage storage.StorageService
+	logger  logger.LoggerService
+
+	archive *zip.Writer
+	dir     string
+	db      *sql.DB
+	tx      *sql.Tx
+	media   map[string]string
+	now     time.Time
+	count   int64
+}
+
+func newAnkiWriter(ctx context.Context, writer io.Writer, storage storage.StorageService, logger logger.LoggerService) (*ankiWriter, error) {
+	ankiWriter := new(ankiWriter)
+	ankiWriter.ctx = ctx
+	ankiWriter.storage = storage
+	ankiWriter.logger = logger
+	ankiWriter.media = map[string]string{}
+	ankiWriter.now = time.Now()
+
+	dir, err := os.MkdirTemp("", "idioms-anki-")
+	if err != nil {
+		return nil, err
+	}
+	ankiWriter.dir = dir
+	ankiWriter.db, err = sql.Open("sqlite", filepath.Join(dir, "collection.anki2"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	err = ankiWriter.createCollection()
+	if err != nil {
+		ankiWriter.db.Close()
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	ankiWriter.tx, err = ankiWriter.db.BeginTx(ctx, nil)
+	if err != nil {
+		ankiWriter.db.Close()
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	ankiWriter.archive = zip.NewWriter(writer)
+	return ankiWriter, nil
+}
+
+func (writer *ankiWriter) createCollection() error {
+	_, err := writer.db.ExecContext(writer.ctx, ankiSchema)
+	if err != nil {
+		return err
+	}
+	now := writer.now.Unix()
+	noteTypes, _ := json.Marshal(map[string]interface{}{
+		strconv.FormatInt(ankiModelID, 10): map[string]interface{}{
+			"id":    ankiModelID,
+			"name":  "Idiom",
+			"type":  0,
+			"mod":   now,
+			"usn":   -1,
+			"sortf": 0,
+			"did":   ankiDeckID,
+			"tmpls": []map[string]interface{}{{
+				"name":  "Card 1",
+				"ord":   0,
+				"qfmt":  "{{Front}}",
+				"afmt":  "{{FrontSide}}<hr id=answer>{{Back}}",
+				"did":   nil,
+				"bqfmt": "",
+				"bafmt": "",
+			}},
+			"flds": []map[string]interface{}{
+				{"name": "Front", "ord": 0, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []string{}},
+				{"name": "Back", "ord": 1, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []string{}},
+			},
+			"css":       ankiCSS,
+			"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+			"latexPost": "\\end{document}",
+			"req":       []interface{}{[]interface{}{0, "all", []int{0}}},
+			"tags":      []string{},
+			"vers":      []string{},
+		},
+	})
+	decks, _ := json.Marshal(map[string]interface{}{
+		"1":                               ankiDeckJSON(1, "Default", now),
+		strconv.FormatInt(ankiDeckID, 10): ankiDeckJSON(ankiDeckID, ankiDeck, now),
+	})
+	dconf, _ := json.Marshal(map[string]interface{}{
+		"1": map[string]interface{}{
+			"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0, "replayq": true, "dyn": false,
+			"new":   map[string]interface{}{"bury": true, "delays": []int{1, 10}, "initialFactor": 2500, "ints": []int{1, 4, 7}, "order": 1, "perDay": 20, "separate": true},
+			"lapse": map[string]interface{}{"delays": []int{10}, "leechAction": 0, "leechFails": 8, "minInt": 1, "mult": 0},
+			"rev":   map[string]interface{}{"bury": true, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500, "minSpace": 1, "perDay": 100},
+		},
+	})
+	conf, _ := json.Marshal(map[string]interface{}{
+		"nextPos": 1, "estTimes": true, "activeDecks": []int64{ankiDeckID}, "sortType": "noteFld", "timeLim": 0,
+		"sortBackwards": false, "addToCur": true, "curDeck": ankiDeckID, "newBury": true, "newSpread": 0,
+		"dueCounts": true, "curModel": strconv.FormatInt(ankiModelID, 10), "collapseTime": 1200,
+	})
+	_, err = writer.db.ExecContext(writer.ctx,
+		"insert into col values (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')",
+		now, writer.now.UnixMilli(), writer.now.UnixMilli(), string(conf), string(noteTypes), string(decks), string(dconf))
+	return err
+}
+
+func ankiDeckJSON(id int64, name string, mod int64) map[string]interface{} {
+	return map[string]interface{}{
+		"id": id, "name": name, "desc": "", "mod": mod, "usn": -1, "collapsed": false, "browserCollapsed": false,
+		"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		"dyn": 0, "conf": 1, "extendNew": 10, "extendRev": 50,
+	}
+}
+
+func (writer *ankiWriter) Write(idiom *models.ExportedIdiom) error {
+	image := writer.addImage(idiom)
+	back := strings.Builder{}
+	if len(image) > 0 {
+		back.WriteString(fmt.Sprintf(`<img src="%s"><br>`, html.EscapeString(image)))
+	}
+	back.WriteString(fmt.Sprintf(`<div class="meaning"><b>%s</b></div>`, html.EscapeString(idiom.MeaningBrief)))
+	back.WriteString(fmt.Sprintf(`<div class="meaning">%s</div>`, html.EscapeString(idiom.MeaningFull)))
+	if len(idiom.Examples) > 0 {
+		back.WriteString(`<ul class="examples">`)
+		for _, example := range idiom.Examples {
+			back.WriteString(fmt.Sprintf("<li>%s</li>", html.EscapeString(example)))
+		}
+		back.WriteString("</ul>")
+	}
+
+	id := writer.now.UnixMilli() + writer.count
+	writer.count++
+	fields := html.EscapeString(idiom.Idiom) + "\x1f" + back.String()
+	_, err := writer.tx.ExecContext(writer.ctx,
+		"insert into notes values (?, ?, ?, ?, -1, '', ?, ?, ?, 0, '')",
+		id, ankiGuid(idiom.ID), ankiModelID, writer.now.Unix(), fields, idiom.Idiom, ankiChecksum(idiom.Idiom))
+	if err != nil {
+		return err
+	}
+	_, err = writer.tx.ExecContext(writer.ctx,
+		"insert into cards values (?, ?, ?, 0, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')",
+		id, id, ankiDeckID, writer.now.Unix(), writer.count)
+	return err
+}
+
+// addImage copies the narrowest variant of the thumbnail into the archive and
+// returns its name. An image which can not be read is left out of the card.
+func (writer *ankiWriter) addImage(idiom *models.ExportedIdiom) string {
+	key := idiom.Thumbnail.String
+	if len(idiom.Thumbnails) > 0 {
+		key = idiom.Thumbnails[0]
+	}
+	if len(key) == 0 {
+		return ""
+	}
+	body, _, err := writer.storage.GetObject(writer.ctx, key)
+	if err != nil {
+		writer.logger.Warn("Failed to read the thumbnail for the deck.", key, err.Error())
+		return ""
+	}
+	defer body.Close()
+
+	index := strconv.Itoa(len(writer.media))
+	entry, err := writer.archive.Create(index)
+	if err != nil {
+		return ""
+	}
+	_, err = io.Copy(entry, body)
+	if err != nil {
+		writer.logger.Warn("Failed to copy the thumbnail into the deck.", key, err.Error())
+		return ""
+	}
+	name := idiom.ID + path.Ext(key)
+	writer.media[index] = name
+	return name
+}
+
+func (writer *ankiWriter) Close() error {
+	defer os.RemoveAll(writer.dir)
+	err := writer.tx.Commit()
+	if err != nil {
+		writer.db.Close()
+		return err
+	}
+	err = writer.db.Close()
+	if err != nil {
+		return err
+	}
+
+	collection, err := os.Open(filepath.Join(writer.dir, "collection.anki2"))
+	if err != nil {
+		return err
+	}
+	defer collection.Close()
+	entry, err := writer.archive.Create("collection.anki2")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, collection)
+	if err != nil {
+		return err
+	}
+	entry, err = writer.archive.Create("media")
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(entry).Encode(writer.media)
+	if err != nil {
+		return err
+	}
+	return writer.archive.Close()
+}
+
+func ankiGuid(id string) string {
+	hash := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(hash[:8])
+}
+
+// ankiChecksum is the number of the first 8 hex digits of the SHA-1 of the sort field.
+func ankiChecksum(field string) int64 {
+	hash := sha1.Sum([]byte(field))
+	return int64(binary.BigEndian.Uint32(hash[:4]))
+}
@@ -0,0 +1,66 @@
+package exports
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type ExportController interface {
+	ExportIdioms(writer http.ResponseWriter, request *http.Request)
+}
+
+type Controller struct {
+	exportService ExportService
+
+	logger logger.LoggerService
+}
+
+func NewController(exportService ExportService, logger logger.LoggerService) *Controller {
+	controller := new(Controller)
+	controller.exportService = exportService
+	controller.logger = logger
+
+	return controller
+}
+
+// ExportIdioms streams the published idioms as an attachment, NDJSON unless the format parameter is given.
+func (controller *Controller) ExportIdioms(writer http.ResponseWriter, request *http.Request) {
+	format := request.URL.Query().Get("format")
+	if len(format) == 0 {
+		format = models.ExportNDJSON
+	}
+	if !IsFormat(format) {
+		lib.WriteError(writer, lib.NewValidationError("The format must be ndjson, csv or apkg.", map[string]string{"format": format}))
+		return
+	}
+	writer.Header().Set("content-type", ContentType(format))
+	writer.Header().Set("content-disposition", fmt.Sprintf(`attachment; filename="idioms-%s.%s"`, time.Now().UTC().Format("2006-01-02"), format))
+
+	body := &startedWriter{writer: writer}
+	count, err := controller.exportService.Export(request.Context(), format, body)
+	if err != nil {
+		// The status is sent with the first byte, after which the export can only be cut short.
+		if !body.started {
+			writer.Header().Del("content-disposition")
+			lib.WriteError(writer, err)
+			return
+		}
+		controller.logger.Error(err, "Failed to finish the export.", format, count)
+	}
+}
+
+type startedWriter struct {
+	writer  io.Writer
+	started bool
+}
+
+func (writer *startedWriter) Write(bytes []byte) (int, error) {
+	writer.started = writer.started || len(bytes) > 0
+	return writer.writer.Write(bytes)
+}
@@ -0,0 +1,104 @@
+package exports
+
+import (
+	"context"
+	"io"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/storage"
+)
+
+const exportQuery = `
+select idioms.id, idioms.idiom, idioms.meaning_brief, idioms.meaning_full, idioms.description,
+	idioms.thumbnail, idioms.thumbnails, idioms.published_at,
+	coalesce((select jsonb_agg(expression order by position, id) from idiom_examples where idiom_id = idioms.id), '[]'::jsonb) as examples
+from idioms
+where idioms.status = $1
+order by idioms.published_at, idioms.id
+`
+
+type ExportService interface {
+	Export(ctx context.Context, format string, writer io.Writer) (int, error)
+}
+
+type Service struct {
+	db      *sqlx.DB
+	logger  logger.LoggerService
+	storage storage.StorageService
+}
+
+func NewService(db *sqlx.DB, logger logger.LoggerService, storage storage.StorageService) *Service {
+	service := new(Service)
+	service.db = db
+	service.logger = logger
+	service.storage = storage
+
+	return service
+}
+
+// Export streams every published idiom into the writer and returns how many were written.
+// Once the first idiom is written the output is incomplete on error.
+func (service *Service) Export(ctx context.Context, format string, writer io.Writer) (int, error) {
+	if !IsFormat(format) {
+		return 0, lib.NewValidationError("The format must be ndjson, csv or apkg.", map[string]string{"format": format})
+	}
+	rows, err := service.db.QueryxContext(ctx, exportQuery, models.IdiomPublished)
+	if err != nil {
+		service.logger.Error(err, "Failed to query idioms to export.")
+		return 0, err
+	}
+	defer rows.Close()
+
+	idiomWriter, err := service.newWriter(ctx, format, writer)
+	if err != nil {
+		service.logger.Error(err, "Failed to create the export.", format)
+		return 0, err
+	}
+	count := 0
+	for rows.Next() {
+		idiom := new(models.ExportedIdiom)
+		err = rows.StructScan(idiom)
+		if err != nil {
+			service.logger.Error(err, "Failed to scan the idiom to export.")
+			idiomWriter.Close()
+			return count, err
+		}
+		if idiom.Thumbnail.Valid && len(idiom.Thumbnail.String) > 0 {
+			idiom.ThumbnailURL = service.storage.PublicURL(idiom.Thumbnail.String)
+		}
+		err = idiomWriter.Write(idiom)
+		if err != nil {
+			service.logger.Error(err, "Failed to write the idiom to export.", idiom.ID)
+			idiomWriter.Close()
+			return count, err
+		}
+		count++
+	}
+	err = rows.Err()
+	if err != nil {
+		service.logger.Error(err, "Failed to read idioms to export.")
+		idiomWriter.Close()
+		return count, err
+	}
+	return count, idiomWriter.Close()
+}
+
+func (service *Service) newWriter(ctx context.Context, format string, writer io.Writer) (IdiomWriter, error) {
+	switch format {
+	case models.ExportCSV:
+		{
+			return newCSVWriter(writer), nil
+		}
+	case models.ExportAnki:
+		{
+			return newAnkiWriter(ctx, writer, service.storage, service.logger)
+		}
+	default:
+		{
+			return newNDJSONWriter(writer), nil
+		}
+	}
+}
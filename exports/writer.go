@@ -0,0 +1,99 @@
+package exports
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+var contentTypes = map[string]string{
+	models.ExportNDJSON: "application/x-ndjson",
+	models.ExportCSV:    "text/csv; charset=utf-8",
+	models.ExportAnki:   "application/octet-stream",
+}
+
+var csvHeader = []string{"id", "idiom", "meaning_brief", "meaning_full", "description", "examples", "thumbnail_url", "published_at"}
+
+// IdiomWriter writes idioms one at a time. Close flushes what is buffered but
+// does not close the underlying writer.
+type IdiomWriter interface {
+	Write(idiom *models.ExportedIdiom) error
+	Close() error
+}
+
+func IsFormat(format string) bool {
+	_, ok := contentTypes[format]
+	return ok
+}
+
+func ContentType(format string) string {
+	return contentTypes[format]
+}
+
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONWriter(writer io.Writer) *ndjsonWriter {
+	ndjsonWriter := new(ndjsonWriter)
+	ndjsonWriter.encoder = json.NewEncoder(writer)
+	ndjsonWriter.encoder.SetEscapeHTML(false)
+	return ndjsonWriter
+}
+
+func (writer *ndjsonWriter) Write(idiom *models.ExportedIdiom) error {
+	return writer.encoder.Encode(idiom)
+}
+
+func (writer *ndjsonWriter) Close() error {
+	return nil
+}
+
+// csvWriter writes a header and a row per idiom, with the examples on separate lines of one cell.
+type csvWriter struct {
+	writer *csv.Writer
+	header bool
+}
+
+func newCSVWriter(writer io.Writer) *csvWriter {
+	csvWriter := new(csvWriter)
+	csvWriter.writer = csv.NewWriter(writer)
+	return csvWriter
+}
+
+func (writer *csvWriter) Write(idiom *models.ExportedIdiom) error {
+	if !writer.header {
+		writer.header = true
+		err := writer.writer.Write(csvHeader)
+		if err != nil {
+			return err
+		}
+	}
+	publishedAt := ""
+	if idiom.PublishedAt.Valid {
+		publishedAt = idiom.PublishedAt.Time.Format(time.RFC3339)
+	}
+	return writer.writer.Write([]string{
+		idiom.ID,
+		idiom.Idiom,
+		idiom.MeaningBrief,
+		idiom.MeaningFull,
+		idiom.Description.String,
+		strings.Join(idiom.Examples, "\n"),
+		idiom.ThumbnailURL,
+		publishedAt,
+	})
+}
+
+func (writer *csvWriter) Close() error {
+	if !writer.header {
+		writer.header = true
+		writer.writer.Write(csvHeader)
+	}
+	writer.writer.Flush()
+	return writer.writer.Error()
+}
@@ -0,0 +1,155 @@
+package exports
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/storage"
+)
+
+func exportedIdioms() []models.ExportedIdiom {
+	return []models.ExportedIdiom{
+		{
+			ID:           "break-the-ice",
+			Idiom:        "Break the ice",
+			MeaningBrief: "To start a conversation",
+			MeaningFull:  "To make people <more> comfortable",
+			Description:  pgtype.Text{String: "At a party", Valid: true},
+			Examples:     models.TextArray{"She broke the ice.", "Who will break the ice?"},
+			Thumbnail:    pgtype.Text{String: "2024/1/1/break-the-ice-640w.webp", Valid: true},
+			Thumbnails:   models.TextArray{"2024/1/1/break-the-ice-320w.webp", "2024/1/1/break-the-ice-640w.webp"},
+			ThumbnailURL: "http://localhost:8081/storage/2024/1/1/break-the-ice-640w.webp",
+		},
+		{
+			ID:           "piece-of-cake",
+			Idiom:        "Piece of cake",
+			MeaningBrief: "Something easy",
+			MeaningFull:  "Something very easy to do",
+			Examples:     models.TextArray{},
+		},
+	}
+}
+
+func TestNDJSONWriter(t *testing.T) {
+	buffer := bytes.Buffer{}
+	writer := newNDJSONWriter(&buffer)
+	for _, idiom := range exportedIdioms() {
+		if err := writer.Write(&idiom); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, received %d", len(lines))
+	}
+	decoded := map[string]interface{}{}
+	json.Unmarshal([]byte(lines[0]), &decoded)
+	if decoded["meaningFull"] != "To make people <more> comfortable" || decoded["thumbnailUrl"] == "" || decoded["thumbnail"] != nil {
+		t.Errorf("Unexpected line %s", lines[0])
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	buffer := bytes.Buffer{}
+	writer := newCSVWriter(&buffer)
+	for _, idiom := range exportedIdioms() {
+		if err := writer.Write(&idiom); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writer.Close()
+	records, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0][0] != "id" {
+		t.Fatalf("Expected a header and 2 rows, received %v", records)
+	}
+	if records[1][5] != "She broke the ice.\nWho will break the ice?" {
+		t.Errorf("Expected the examples on separate lines, received %q", records[1][5])
+	}
+
+	buffer.Reset()
+	newCSVWriter(&buffer).Close()
+	if buffer.String() != strings.Join(csvHeader, ",")+"\n" {
+		t.Errorf("Expected only the header, received %q", buffer.String())
+	}
+}
+
+func TestAnkiWriter(t *testing.T) {
+	ctx := context.Background()
+	files, err := storage.NewLocalService(t.TempDir(), "", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files.PutObject(ctx, "2024/1/1/break-the-ice-320w.webp", strings.NewReader("image"), nil)
+
+	buffer := bytes.Buffer{}
+	writer, err := newAnkiWriter(ctx, &buffer, files, logger.NewService(log.New(io.Discard, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, idiom := range exportedIdioms() {
+		if err := writer.Write(&idiom); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(writer.dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the temporary directory to be removed")
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string][]byte{}
+	for _, file := range archive.File {
+		reader, _ := file.Open()
+		entries[file.Name], _ = io.ReadAll(reader)
+		reader.Close()
+	}
+	if string(entries["0"]) != "image" {
+		t.Errorf("Expected the image as media 0, received %q", entries["0"])
+	}
+	media := map[string]string{}
+	json.Unmarshal(entries["media"], &media)
+	if len(media) != 1 || media["0"] != "break-the-ice.webp" {
+		t.Errorf("Unexpected media %v", media)
+	}
+
+	path := filepath.Join(t.TempDir(), "collection.anki2")
+	os.WriteFile(path, entries["collection.anki2"], 0o600)
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var notes, cards int
+	db.QueryRow("select count(*) from notes").Scan(&notes)
+	db.QueryRow("select count(*) from cards").Scan(&cards)
+	if notes != 2 || cards != 2 {
+		t.Errorf("Expected 2 notes and cards, received %d and %d", notes, cards)
+	}
+	var fields string
+	db.QueryRow("select flds from notes where guid = ?", ankiGuid("break-the-ice")).Scan(&fields)
+	if !strings.HasPrefix(fields, "Break the ice\x1f<img src=\"break-the-ice.webp\">") || !strings.Contains(fields, "&lt;more&gt;") {
+		t.Errorf("Unexpected fields %q", fields)
+	}
+}
@@ -0,0 +1,224 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalidURL       = errors.New("invalid url")
+	ErrBlockedAddress   = errors.New("address is not allowed")
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrTooLarge         = errors.New("response body is too large")
+	ErrUnsupportedType  = errors.New("unsupported content type")
+)
+
+var DefaultAllowedTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+const (
+	DefaultTimeout      = time.Second * 30
+	DefaultMaxBytes     = int64(20 << 20)
+	DefaultMaxRedirects = 3
+
+	dialTimeout = time.Second * 10
+	sniffLength = 512
+)
+
+// Reserved ranges which net/netip does not classify on its own.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+var allowedSchemes = map[string]bool{"http": true, "https": true}
+
+type Option struct {
+	Timeout      time.Duration
+	MaxBytes     int64
+	MaxRedirects int
+	AllowedTypes []string
+	// AllowedHosts may resolve to private addresses, e.g. the fake LLM server on 127.0.0.1.
+	AllowedHosts []string
+}
+
+type Response struct {
+	URL         string
+	ContentType string
+	Body        []byte
+}
+
+type StatusError struct {
+	StatusCode int
+}
+
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", err.StatusCode)
+}
+
+type Fetcher struct {
+	client       *http.Client
+	maxBytes     int64
+	allowedTypes []string
+}
+
+func NewFetcher(option *Option) *Fetcher {
+	fetcher := new(Fetcher)
+	fetcher.maxBytes = option.MaxBytes
+	if fetcher.maxBytes <= 0 {
+		fetcher.maxBytes = DefaultMaxBytes
+	}
+	fetcher.allowedTypes = option.AllowedTypes
+	if len(fetcher.allowedTypes) == 0 {
+		fetcher.allowedTypes = DefaultAllowedTypes
+	}
+	timeout := option.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	maxRedirects := option.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = DefaultMaxRedirects
+	}
+	allowedHosts := map[string]bool{}
+	for _, host := range option.AllowedHosts {
+		allowedHosts[strings.ToLower(host)] = true
+	}
+
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	guardedDialer := &net.Dialer{Timeout: dialTimeout, Control: controlAddress}
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			if allowedHosts[strings.ToLower(host)] {
+				return dialer.DialContext(ctx, network, address)
+			}
+			return guardedDialer.DialContext(ctx, network, address)
+		},
+		TLSHandshakeTimeout:   dialTimeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       time.Minute,
+	}
+	fetcher.client = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return ErrTooManyRedirects
+			}
+			if !allowedSchemes[request.URL.Scheme] {
+				return ErrInvalidURL
+			}
+			return nil
+		},
+	}
+
+	return fetcher
+}
+
+// Fetch downloads a URL whose sniffed content type is in the allow-list. The
+// declared content type is ignored. Connections to private, loopback, link-local
+// and other reserved addresses are refused after DNS resolution, including on redirects.
+func (fetcher *Fetcher) Fetch(ctx context.Context, rawURL string) (*Response, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || !allowedSchemes[parsed.Scheme] || len(parsed.Hostname()) == 0 {
+		return nil, ErrInvalidURL
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+	request.Header.Set("accept", strings.Join(fetcher.allowedTypes, ", "))
+
+	response, err := fetcher.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: response.StatusCode}
+	}
+	if response.ContentLength > fetcher.maxBytes {
+		return nil, ErrTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, fetcher.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > fetcher.maxBytes {
+		return nil, ErrTooLarge
+	}
+	sniffed := body
+	if len(sniffed) > sniffLength {
+		sniffed = sniffed[:sniffLength]
+	}
+	contentType := http.DetectContentType(sniffed)
+	if !fetcher.isAllowedType(contentType) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+	}
+	return &Response{
+		URL:         response.Request.URL.String(),
+		ContentType: contentType,
+		Body:        body,
+	}, nil
+}
+
+func (fetcher *Fetcher) isAllowedType(contentType string) bool {
+	for _, allowed := range fetcher.allowedTypes {
+		if contentType == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func controlAddress(network string, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if IsBlocked(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+	}
+	return nil
+}
+
+func IsBlocked(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
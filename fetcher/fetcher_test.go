@@ -0,0 +1,94 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+)
+
+func TestIsBlocked(t *testing.T) {
+	for address, blocked := range map[string]bool{
+		"127.0.0.1":        true,
+		"10.1.2.3":         true,
+		"172.16.0.1":       true,
+		"192.168.1.1":      true,
+		"169.254.169.254":  true,
+		"100.64.0.1":       true,
+		"0.0.0.0":          true,
+		"::1":              true,
+		"fd00:ec2::254":    true,
+		"::ffff:127.0.0.1": true,
+		"8.8.8.8":          false,
+		"2606:4700::1111":  false,
+	} {
+		if IsBlocked(netip.MustParseAddr(address)) != blocked {
+			t.Errorf("expected %s blocked to be %v", address, blocked)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	body := new(bytes.Buffer)
+	png.Encode(body, newImage())
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/image":
+			writer.Header().Set("content-type", "text")
+			writer.Write(body.Bytes())
+		case "/text":
+			writer.Write([]byte("<html></html>"))
+		case "/redirect":
+			http.Redirect(writer, request, "/redirect", http.StatusFound)
+		default:
+			http.NotFound(writer, request)
+		}
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+	ctx := context.Background()
+
+	_, err := NewFetcher(&Option{}).Fetch(ctx, server.URL+"/image")
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("expected a blocked address, got %v", err)
+	}
+
+	fetcher := NewFetcher(&Option{AllowedHosts: []string{serverURL.Hostname()}, MaxBytes: int64(body.Len())})
+	response, err := fetcher.Fetch(ctx, server.URL+"/image")
+	if err != nil || response.ContentType != "image/png" || !bytes.Equal(response.Body, body.Bytes()) {
+		t.Errorf("expected the png, got %v", err)
+	}
+	_, err = fetcher.Fetch(ctx, server.URL+"/text")
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("expected an unsupported type, got %v", err)
+	}
+	_, err = fetcher.Fetch(ctx, server.URL+"/redirect")
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("expected too many redirects, got %v", err)
+	}
+	var statusError *StatusError
+	_, err = fetcher.Fetch(ctx, server.URL+"/missing")
+	if !errors.As(err, &statusError) || statusError.StatusCode != http.StatusNotFound {
+		t.Errorf("expected a 404, got %v", err)
+	}
+	_, err = fetcher.Fetch(ctx, "file:///etc/passwd")
+	if !errors.Is(err, ErrInvalidURL) {
+		t.Errorf("expected an invalid url, got %v", err)
+	}
+
+	small := NewFetcher(&Option{AllowedHosts: []string{serverURL.Hostname()}, MaxBytes: 16})
+	_, err = small.Fetch(ctx, server.URL+"/image")
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected too large, got %v", err)
+	}
+}
+
+func newImage() image.Image {
+	return image.NewRGBA(image.Rect(0, 0, 8, 8))
+}
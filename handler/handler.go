@@ -7,23 +7,37 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/nw.lee/idioms-backend/auth"
+	"github.com/nw.lee/idioms-backend/exports"
 	"github.com/nw.lee/idioms-backend/idioms"
+	"github.com/nw.lee/idioms-backend/jobs"
 	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/orphans"
+	"github.com/nw.lee/idioms-backend/prompts"
+	"github.com/nw.lee/idioms-backend/revisions"
+	"github.com/nw.lee/idioms-backend/storage"
+	"github.com/nw.lee/idioms-backend/tags"
 )
 
 type Handler struct {
-	idiomController idioms.IdiomController
-	router          *chi.Mux
-	logger          logger.LoggerService
-
-	isAdmin bool
+	idiomController    idioms.IdiomController
+	authController     auth.AuthController
+	authMiddleware     *auth.Middleware
+	jobController      jobs.JobController
+	promptController   prompts.PromptController
+	revisionController revisions.RevisionController
+	orphanController   orphans.OrphanController
+	exportController   exports.ExportController
+	tagController      tags.TagController
+	router             *chi.Mux
+	logger             logger.LoggerService
+	storage            storage.StorageService
 }
 
-func NewHandler(isAdmin bool) *Handler {
+func NewHandler() *Handler {
 	handler := new(Handler)
 	handler.router = chi.NewRouter()
 	handler.logger = logger.NewService(log.Default())
-	handler.isAdmin = isAdmin
 
 	return handler
 }
@@ -33,11 +47,52 @@ func (handler *Handler) AddIdiomController(controller idioms.IdiomController) *H
 	return handler
 }
 
+func (handler *Handler) AddAuth(controller auth.AuthController, middleware *auth.Middleware) *Handler {
+	handler.authController = controller
+	handler.authMiddleware = middleware
+	return handler
+}
+
+func (handler *Handler) AddJobController(controller jobs.JobController) *Handler {
+	handler.jobController = controller
+	return handler
+}
+
+func (handler *Handler) AddPromptController(controller prompts.PromptController) *Handler {
+	handler.promptController = controller
+	return handler
+}
+
+func (handler *Handler) AddRevisionController(controller revisions.RevisionController) *Handler {
+	handler.revisionController = controller
+	return handler
+}
+
+func (handler *Handler) AddOrphanController(controller orphans.OrphanController) *Handler {
+	handler.orphanController = controller
+	return handler
+}
+
+func (handler *Handler) AddExportController(controller exports.ExportController) *Handler {
+	handler.exportController = controller
+	return handler
+}
+
+func (handler *Handler) AddTagController(controller tags.TagController) *Handler {
+	handler.tagController = controller
+	return handler
+}
+
+func (handler *Handler) AddStorage(storage storage.StorageService) *Handler {
+	handler.storage = storage
+	return handler
+}
+
 func (handler *Handler) Run() {
 	// handler.router.Use(middleware.Logger)
 	handler.router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://useidioms.com", "https://api.useidioms.com", "http://useidioms.com", "http://api.useidioms.com", "http://localhost:8082"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "HEAD"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "HEAD"},
 		AllowedHeaders:   []string{"*"},
 		AllowCredentials: true,
 		MaxAge:           600,
@@ -49,24 +104,81 @@ func (handler *Handler) Run() {
 			next.ServeHTTP(res, req)
 		})
 	})
-	handler.router.Get("/idioms/admin", handler.idiomController.GetIdioms)
 	handler.router.Get("/idioms/main", handler.idiomController.GetMainPageIdioms)
 	handler.router.Get("/idioms", handler.idiomController.GetIdiomsWithThumbnail)
 	handler.router.Get("/idioms/{id}", handler.idiomController.GetIdiomById)
 	handler.router.Get("/idioms/{id}/related", handler.idiomController.GetRelatedIdioms)
 	handler.router.Get("/idioms/search", handler.idiomController.SearchIdioms)
+	handler.router.Get("/tags", handler.tagController.GetTags)
+	handler.router.Get("/categories", handler.tagController.GetCategories)
 
-	if handler.isAdmin {
-		handler.router.Post("/idioms/inputs", handler.idiomController.CreateIdiomInputs)
-		handler.router.Post("/idioms/thumbnail/draft", handler.idiomController.CreateThumbnail)
-		handler.router.Post("/idioms/thumbnail/file", handler.idiomController.UploadThumbnail)
-		handler.router.Post("/idioms/thumbnail/url", handler.idiomController.CreateThumbnailByURL)
-		handler.router.Post("/idioms/{id}/thumbnail", handler.idiomController.UpdateThumbnailPrompt)
-		handler.router.Put("/idioms/{id}/description", handler.idiomController.CreateDescription)
-		handler.router.Post("/idioms/{id}/examples", handler.idiomController.CreateExamples)
-		handler.router.Put("/idioms/{id}/examples", handler.idiomController.UpdateExamples)
-
+	if fileServer, ok := handler.storage.(http.Handler); ok {
+		handler.router.Mount("/storage", http.StripPrefix("/storage", fileServer))
 	}
+
+	handler.router.Group(func(router chi.Router) {
+		router.Use(handler.authMiddleware.Authenticate)
+
+		readRouter := router.With(auth.RequireScope(auth.ScopeContentRead))
+		readRouter.Get("/idioms/admin", handler.idiomController.GetIdioms)
+		readRouter.Get("/idioms/export", handler.exportController.ExportIdioms)
+		readRouter.Get("/tags/admin", handler.tagController.GetAllTags)
+		readRouter.Get("/categories/admin", handler.tagController.GetAllCategories)
+		readRouter.Get("/prompts", handler.promptController.GetTemplates)
+		readRouter.Post("/prompts/preview", handler.promptController.PreviewPrompt)
+		readRouter.Get("/idioms/{id}/examples/items", handler.idiomController.GetExamples)
+		readRouter.Get("/idioms/{id}/revisions", handler.revisionController.GetRevisions)
+		readRouter.Get("/idioms/{id}/revisions/diff", handler.revisionController.DiffRevisions)
+		readRouter.Get("/idioms/{id}/revisions/{revisionId}", handler.revisionController.GetRevision)
+
+		contentRouter := router.With(auth.RequireScope(auth.ScopeContentWrite))
+		contentRouter.Post("/idioms/inputs", handler.idiomController.CreateIdiomInputs)
+		contentRouter.Post("/idioms/inputs/import", handler.idiomController.ImportIdiomInputs)
+		contentRouter.Post("/idioms", handler.idiomController.CreateIdiom)
+		contentRouter.Patch("/idioms/{id}", handler.idiomController.PatchIdiom)
+		contentRouter.Delete("/idioms/{id}", handler.idiomController.DeleteIdiom)
+		contentRouter.Post("/idioms/{id}/examples/items", handler.idiomController.CreateExample)
+		contentRouter.Put("/idioms/{id}/examples/order", handler.idiomController.ReorderExamples)
+		contentRouter.Patch("/idioms/{id}/examples/items/{exampleId}", handler.idiomController.PatchExample)
+		contentRouter.Delete("/idioms/{id}/examples/items/{exampleId}", handler.idiomController.DeleteExample)
+		contentRouter.Post("/idioms/{id}/thumbnail", handler.idiomController.UpdateThumbnailPrompt)
+		contentRouter.Put("/idioms/{id}/description", handler.idiomController.CreateDescription)
+		contentRouter.Post("/idioms/{id}/examples", handler.idiomController.CreateExamples)
+		contentRouter.Put("/idioms/{id}/examples", handler.idiomController.UpdateExamples)
+		contentRouter.Put("/idioms/{id}/status", handler.idiomController.UpdateStatus)
+		contentRouter.Put("/idioms/{id}/tags", handler.tagController.SetIdiomTags)
+		contentRouter.Put("/idioms/{id}/categories", handler.tagController.SetIdiomCategories)
+		contentRouter.Post("/tags", handler.tagController.CreateTag)
+		contentRouter.Patch("/tags/{id}", handler.tagController.RenameTag)
+		contentRouter.Delete("/tags/{id}", handler.tagController.DeleteTag)
+		contentRouter.Post("/categories", handler.tagController.CreateCategory)
+		contentRouter.Patch("/categories/{id}", handler.tagController.UpdateCategory)
+		contentRouter.Delete("/categories/{id}", handler.tagController.DeleteCategory)
+		contentRouter.Post("/idioms/{id}/revisions/{revisionId}/restore", handler.revisionController.RestoreRevision)
+		contentRouter.Post("/prompts", handler.promptController.CreateTemplate)
+
+		thumbnailRouter := router.With(auth.RequireScope(auth.ScopeThumbnailsWrite))
+		thumbnailRouter.Post("/idioms/thumbnail/draft", handler.idiomController.CreateThumbnail)
+		thumbnailRouter.Post("/idioms/thumbnail/file", handler.idiomController.UploadThumbnail)
+		thumbnailRouter.Post("/idioms/thumbnail/url", handler.idiomController.CreateThumbnailByURL)
+		thumbnailRouter.Post("/idioms/{id}/thumbnail/uploads", handler.idiomController.CreateThumbnailUpload)
+		thumbnailRouter.Post("/idioms/{id}/thumbnail/uploads/confirm", handler.idiomController.ConfirmThumbnailUpload)
+		thumbnailRouter.Get("/idioms/{id}/thumbnail/drafts", handler.idiomController.GetThumbnailDrafts)
+		thumbnailRouter.Post("/idioms/{id}/thumbnail/drafts", handler.idiomController.CreateThumbnail)
+		thumbnailRouter.Delete("/idioms/{id}/thumbnail/drafts", handler.idiomController.DiscardThumbnailDrafts)
+		thumbnailRouter.Post("/idioms/{id}/thumbnail/drafts/{draftId}/promote", handler.idiomController.PromoteThumbnailDraft)
+		thumbnailRouter.Delete("/idioms/{id}/thumbnail/drafts/{draftId}", handler.idiomController.DiscardThumbnailDraft)
+		thumbnailRouter.Post("/thumbnails/reconcile", handler.orphanController.Reconcile)
+
+		jobRouter := router.With(auth.RequireScope(auth.ScopeJobsAdmin))
+		jobRouter.Get("/jobs", handler.jobController.GetJobs)
+		jobRouter.Post("/jobs/{id}/retry", handler.jobController.RetryJob)
+
+		keyRouter := router.With(auth.RequireScope(auth.ScopeKeysAdmin))
+		keyRouter.Get("/auth/keys", handler.authController.GetApiKeys)
+		keyRouter.Post("/auth/keys", handler.authController.CreateApiKey)
+		keyRouter.Delete("/auth/keys/{id}", handler.authController.RevokeApiKey)
+	})
 }
 
 func (handler *Handler) ListenAndServe(address string) {
@@ -0,0 +1,126 @@
+package idioms
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/repository"
+)
+
+// CreateIdiom inserts an idiom written by an admin as a draft, with its examples in order.
+func (service *Service) CreateIdiom(input *models.CreateIdiomInput, ctx *context.Context) (*models.Idiom, error) {
+	if problems := input.Validate(); len(problems) > 0 {
+		return nil, invalidInput("Invalid idiom.", problems)
+	}
+	idiomId := lib.ToIdiomID(strings.TrimSpace(input.Idiom))
+	if len(strings.Trim(idiomId, "-")) == 0 {
+		return nil, invalidInput("Invalid idiom.", []string{"idiom must contain a letter or a digit"})
+	}
+	tx, err := service.db.BeginTxx(*ctx, nil)
+	if err != nil {
+		service.logger.Error(err, "Failed to instantiate new transaction.")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	idioms := service.repository.WithTx(tx)
+	err = idioms.CreateIdiom(*ctx, &repository.NewIdiom{
+		ID:              idiomId,
+		Idiom:           strings.TrimSpace(input.Idiom),
+		MeaningBrief:    input.MeaningBrief,
+		MeaningFull:     input.MeaningFull,
+		Description:     repository.ToNullableText(input.Description),
+		ThumbnailPrompt: repository.ToNullableText(input.ThumbnailPrompt),
+		Status:          models.IdiomDraft,
+		Examples:        input.Examples,
+	})
+	if lib.IsUniqueViolation(err) {
+		return nil, lib.NewConflictError("An idiom with the same id already exists.", map[string]string{"id": idiomId})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to insert idiom.", idiomId)
+		return nil, err
+	}
+	err = service.revisions.Record(*ctx, tx, idiomId, models.RevisionSourceAdmin)
+	if err != nil {
+		return nil, err
+	}
+	idiom, err := service.findIdiom(*ctx, idioms, idiomId)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	idiom.Examples = append(idiom.Examples, input.Examples...)
+	return idiom, nil
+}
+
+func (service *Service) PatchIdiom(idiomId string, input *models.PatchIdiomInput, ctx *context.Context) (*models.Idiom, error) {
+	if problems := input.Validate(); len(problems) > 0 {
+		return nil, invalidInput("Invalid idiom.", problems)
+	}
+	patch := *input
+	if patch.Idiom != nil {
+		trimmed := strings.TrimSpace(*patch.Idiom)
+		patch.Idiom = &trimmed
+	}
+	tx, err := service.db.BeginTxx(*ctx, nil)
+	if err != nil {
+		service.logger.Error(err, "Failed to instantiate new transaction.")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	idioms := service.repository.WithTx(tx)
+	err = idioms.PatchIdiom(*ctx, idiomId, &patch)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, lib.NewNotFoundError("Idiom not found.", map[string]string{"id": idiomId})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to update the idiom.", idiomId)
+		return nil, err
+	}
+	err = service.revisions.Record(*ctx, tx, idiomId, models.RevisionSourceAdmin)
+	if err != nil {
+		return nil, err
+	}
+	idiom, err := service.findIdiom(*ctx, idioms, idiomId)
+	if err != nil {
+		return nil, err
+	}
+	return idiom, tx.Commit()
+}
+
+// DeleteIdiom deletes an idiom with its examples, drafts and topics. Its revisions are kept,
+// and its images are left to storage reconciliation.
+func (service *Service) DeleteIdiom(idiomId string, ctx *context.Context) error {
+	err := service.repository.DeleteIdiom(*ctx, idiomId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return lib.NewNotFoundError("Idiom not found.", map[string]string{"id": idiomId})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to delete the idiom.", idiomId)
+	}
+	return err
+}
+
+func (service *Service) findIdiom(ctx context.Context, idioms repository.IdiomRepository, idiomId string) (*models.Idiom, error) {
+	idiom, err := idioms.GetIdiom(ctx, idiomId, false)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, lib.NewNotFoundError("Idiom not found.", map[string]string{"id": idiomId})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to query the idiom with id.", idiomId)
+		return nil, err
+	}
+	return idiom, nil
+}
+
+func invalidInput(message string, problems []string) error {
+	return lib.NewValidationError(message, map[string]interface{}{"problems": problems})
+}
@@ -3,7 +3,7 @@ package idioms
 import (
 	"encoding/base64"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"net/http"
 	"path"
 	"strconv"
@@ -19,6 +19,7 @@ import (
 type Controller struct {
 	idiomService     IdiomService
 	thumbnailService thumbnail.ThumbnailService
+	paginator        *Paginator
 
 	logger logger.LoggerService
 }
@@ -33,102 +34,72 @@ type IdiomController interface {
 	UploadThumbnail(writer http.ResponseWriter, request *http.Request)
 	CreateThumbnail(writer http.ResponseWriter, request *http.Request)
 	CreateThumbnailByURL(writer http.ResponseWriter, request *http.Request)
+	CreateThumbnailUpload(writer http.ResponseWriter, request *http.Request)
+	ConfirmThumbnailUpload(writer http.ResponseWriter, request *http.Request)
+	GetThumbnailDrafts(writer http.ResponseWriter, request *http.Request)
+	PromoteThumbnailDraft(writer http.ResponseWriter, request *http.Request)
+	DiscardThumbnailDraft(writer http.ResponseWriter, request *http.Request)
+	DiscardThumbnailDrafts(writer http.ResponseWriter, request *http.Request)
 	UpdateThumbnailPrompt(writer http.ResponseWriter, request *http.Request)
 	CreateIdiomInputs(writer http.ResponseWriter, request *http.Request)
+	ImportIdiomInputs(writer http.ResponseWriter, request *http.Request)
 	CreateDescription(writer http.ResponseWriter, request *http.Request)
 	CreateExamples(writer http.ResponseWriter, request *http.Request)
 	UpdateExamples(writer http.ResponseWriter, request *http.Request)
+	UpdateStatus(writer http.ResponseWriter, request *http.Request)
+	CreateIdiom(writer http.ResponseWriter, request *http.Request)
+	PatchIdiom(writer http.ResponseWriter, request *http.Request)
+	DeleteIdiom(writer http.ResponseWriter, request *http.Request)
+	GetExamples(writer http.ResponseWriter, request *http.Request)
+	CreateExample(writer http.ResponseWriter, request *http.Request)
+	PatchExample(writer http.ResponseWriter, request *http.Request)
+	DeleteExample(writer http.ResponseWriter, request *http.Request)
+	ReorderExamples(writer http.ResponseWriter, request *http.Request)
 }
 
-func NewController(idiomService IdiomService, thumbnailService thumbnail.ThumbnailService, logger logger.LoggerService) *Controller {
+func NewController(idiomService IdiomService, thumbnailService thumbnail.ThumbnailService, paginator *Paginator, logger logger.LoggerService) *Controller {
 	controller := new(Controller)
 	controller.idiomService = idiomService
 	controller.thumbnailService = thumbnailService
+	controller.paginator = paginator
 	controller.logger = logger
 
 	return controller
 }
 
-func (contoller *Controller) EncodeToken(idioms []models.Idiom, filter *QueryFilter) (*models.CursorToken, error) {
-	if len(idioms) < 1 {
-		return &models.CursorToken{
-			Previous: "",
-			Next:     "",
-		}, nil
-	}
-	fromIdiom := idioms[0]
-	toIdiom := idioms[len(idioms)-1]
-
-	prevCursor := new(Cursor)
-	nextCursor := new(Cursor)
-	if filter.OrderBy == "idiom" {
-		prevCursor.Idiom = &(fromIdiom.Idiom)
-		nextCursor.Idiom = &(toIdiom.Idiom)
-	} else {
-		prevCursor.CreatedAt = &(fromIdiom.CreatedAt)
-		nextCursor.CreatedAt = &(toIdiom.CreatedAt)
-	}
-	nextCursor.IsNext = true
-	prevCursor.IsNext = false
-
-	prevToken, prevError := json.Marshal(prevCursor)
-	nextToken, nextError := json.Marshal(nextCursor)
-	if prevError != nil || nextError != nil {
-		contoller.logger.Error(errors.New("failed to create cursors"), "2 Errors", prevError, nextError)
-		return nil, errors.New("failed to create cursors")
-	}
-	encodedPrevToken := base64.StdEncoding.EncodeToString([]byte(prevToken))
-	encodedNextToken := base64.StdEncoding.EncodeToString([]byte(nextToken))
-	return &models.CursorToken{
-		Previous: encodedPrevToken,
-		Next:     encodedNextToken,
-	}, nil
-}
-
-func (controller *Controller) DecodeToken(request *http.Request) *Cursor {
-	var encodedToken string
-	params := request.URL.Query()
-	encodedNextToken := params.Get("nextToken")
-	encodedPrevToken := params.Get("prevToken")
-	cursor := new(Cursor)
-
-	if len(encodedPrevToken) == 0 && len(encodedNextToken) == 0 {
-		return nil
-	}
-
-	if len(encodedPrevToken) > 0 {
-		encodedToken = encodedPrevToken
-	} else {
-		encodedToken = encodedNextToken
-	}
-	token, err := base64.StdEncoding.DecodeString(encodedToken)
-	if err != nil {
-		controller.logger.Error(err, "failed to decode tokens.", encodedToken)
-		return nil
-	}
-	err = json.Unmarshal(token, cursor)
-	if err != nil {
-		controller.logger.Error(err, "failed to decode JSON.", encodedToken)
-		return nil
-	}
-	return cursor
-}
-
-func (controller *Controller) GetFilter(request *http.Request) (*QueryFilter, error) {
+// GetFilter reads the filter and the cursor of a page. orderBy without a value or "relevance" falls back to defaultOrderBy,
+// and an unknown orderBy is rejected rather than read as another ordering.
+func (controller *Controller) GetFilter(request *http.Request, defaultOrderBy string) (*QueryFilter, error) {
 	params := request.URL.Query()
 	filter := new(QueryFilter)
 	orderBy := (params.Get("orderBy"))
 	orderDirection := strings.ToLower(params.Get(("orderDirection")))
 	count, intErr := strconv.Atoi(params.Get(("count")))
 	if intErr != nil {
-		count = 20
+		count = defaultCount
+	}
+	if count < 1 || count > maxCount {
+		return nil, lib.NewValidationError(fmt.Sprintf("count must be between 1 and %d.", maxCount), map[string]int{"count": count})
 	}
 	filter.Count = count
-	cursor := controller.DecodeToken(request)
-	operator := "<"
-	innerOrderDirection := "desc"
+	seen := map[string]bool{}
+	for _, tag := range strings.Split(params.Get("tags"), ",") {
+		if id := lib.ToTagID(tag); len(id) > 0 && !seen[id] {
+			seen[id] = true
+			filter.Tags = append(filter.Tags, id)
+		}
+	}
+	if len(filter.Tags) > models.MaxTagsPerIdiom {
+		return nil, lib.NewValidationError(fmt.Sprintf("At most %d tags can be filtered.", models.MaxTagsPerIdiom), nil)
+	}
+	filter.Category = lib.ToTagID(params.Get("category"))
 
 	switch orderBy {
+	case "publishedAt":
+		{
+			filter.OrderBy = "published_at"
+			break
+		}
 	case "createdAt":
 		{
 			filter.OrderBy = "created_at"
@@ -139,9 +110,22 @@ func (controller *Controller) GetFilter(request *http.Request) (*QueryFilter, er
 			filter.OrderBy = "idiom"
 			break
 		}
+	case "rank":
+		{
+			if defaultOrderBy != "rank" {
+				return nil, lib.NewValidationError("orderBy rank is only available when searching.", map[string]string{"orderBy": orderBy})
+			}
+			filter.OrderBy = "rank"
+			break
+		}
+	case "", "relevance":
+		{
+			filter.OrderBy = defaultOrderBy
+			break
+		}
 	default:
 		{
-			filter.OrderBy = "published_at"
+			return nil, lib.NewValidationError("orderBy must be publishedAt, createdAt, idiom or relevance.", map[string]string{"orderBy": orderBy})
 		}
 	}
 
@@ -149,211 +133,138 @@ func (controller *Controller) GetFilter(request *http.Request) (*QueryFilter, er
 		orderDirection = "desc"
 	}
 	filter.OrderDirection = orderDirection
-	if cursor != nil {
-		filter.idiom = cursor.Idiom
-		filter.createdAt = cursor.CreatedAt
-		if filter.OrderDirection == "desc" && cursor.IsNext {
-			innerOrderDirection = "desc"
-			operator = "<"
-		}
-		if filter.OrderDirection == "desc" && !cursor.IsNext {
-			innerOrderDirection = "asc"
-			operator = ">"
-		}
-		if filter.OrderDirection == "asc" && cursor.IsNext {
-			innerOrderDirection = "asc"
-			operator = ">"
-		}
-		if filter.OrderDirection == "asc" && !cursor.IsNext {
-			innerOrderDirection = "desc"
-			operator = "<"
+
+	token := params.Get("prevToken")
+	if len(token) == 0 {
+		token = params.Get("nextToken")
+	}
+	if len(token) > 0 {
+		cursor, err := controller.paginator.Decode(token, filter.OrderBy)
+		if err != nil {
+			controller.logger.Warn("Failed to decode the cursor.", token)
+			return nil, err
 		}
+		filter.cursor = cursor
 	}
-	filter.innerOrderDirection = innerOrderDirection
-	filter.operator = operator
-
 	return filter, nil
 }
 
 func (controller *Controller) GetIdiomById(writer http.ResponseWriter, request *http.Request) {
-	writer.Header().Add("content-type", "application/json")
 	id := chi.URLParam(request, "id")
-	idiom, err := controller.idiomService.GetIdiomById(id)
-	body := map[string]interface{}{
-		"idiom": nil,
-	}
-
+	idiom, err := controller.idiomService.GetIdiomById(id, true)
 	if err != nil {
-		str, _ := json.Marshal(body)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	body["idiom"] = idiom
-	str, _ := json.Marshal(body)
-	writer.Write(str)
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"idiom": idiom,
+	})
 }
 
 func (controller *Controller) GetRelatedIdioms(writer http.ResponseWriter, request *http.Request) {
-	writer.Header().Add("content-type", "application/json")
 	idiomId := chi.URLParam(request, "id")
-	body := map[string]interface{}{
-		"idioms": nil,
-	}
 	idioms, err := controller.idiomService.GetRelatedIdioms(idiomId)
 	if err != nil {
-		str, _ := json.Marshal(body)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	body["idioms"] = idioms
-	str, _ := json.Marshal(body)
-	writer.Write(str)
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"idioms": idioms,
+	})
 }
 
 func (controller *Controller) GetIdioms(writer http.ResponseWriter, request *http.Request) {
-	writer.Header().Add("content-type", "application/json")
-	filter, err := controller.GetFilter(request)
-	body := new(models.IdiomResponse)
+	filter, err := controller.GetFilter(request, "published_at")
 	if err != nil {
-		str, _ := json.Marshal(body)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	idioms, err := controller.idiomService.GetIdioms(filter, false)
-	if err != nil {
-		str, _ := json.Marshal(body)
-		writer.Write(str)
+	filter.Status = request.URL.Query().Get("status")
+	if len(filter.Status) > 0 && !isStatus(filter.Status) {
+		lib.WriteError(writer, lib.NewValidationError("Unknown status.", map[string]string{"status": filter.Status}))
 		return
 	}
-
-	cursorToken, err := controller.EncodeToken(idioms, filter)
+	page, err := controller.idiomService.GetIdioms(filter, false)
 	if err != nil {
-		controller.logger.Warn("failed to create cursor tokens.", err)
-		str, _ := json.Marshal(body)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	body.Cursor.Previous = cursorToken.Previous
-	body.Cursor.Next = cursorToken.Next
-	body.Idioms = idioms
-	str, _ := json.Marshal(body)
-	writer.Write(str)
+	controller.writeIdioms(writer, page)
 }
 
 func (controller *Controller) SearchIdioms(writer http.ResponseWriter, request *http.Request) {
-	writer.Header().Add("content-type", "application/json")
-	filter, err := controller.GetFilter(request)
-	searchQuery := request.URL.Query()
-	filter.Keyword = searchQuery.Get("keyword")
-	body := new(models.IdiomResponse)
+	filter, err := controller.GetFilter(request, "rank")
 	if err != nil {
-		str, _ := json.Marshal(body)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	idioms, err := controller.idiomService.SearchIdioms(filter, true)
-	if err != nil {
-		str, _ := json.Marshal(body)
-		writer.Write(str)
+	filter.Keyword = request.URL.Query().Get("keyword")
+	if len(strings.TrimSpace(filter.Keyword)) == 0 {
+		lib.WriteError(writer, lib.NewValidationError("Keyword is required.", nil))
 		return
 	}
-
-	cursorToken, err := controller.EncodeToken(idioms, filter)
+	page, err := controller.idiomService.SearchIdioms(filter, true)
 	if err != nil {
-		controller.logger.Warn("failed to create cursor tokens.", err)
-		str, _ := json.Marshal(body)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	body.Cursor.Previous = cursorToken.Previous
-	body.Cursor.Next = cursorToken.Next
-	body.Idioms = idioms
-	str, _ := json.Marshal(body)
-	writer.Write(str)
+	controller.writeIdioms(writer, page)
 }
 
 func (controller *Controller) GetIdiomsWithThumbnail(writer http.ResponseWriter, request *http.Request) {
-	writer.Header().Add("content-type", "application/json")
-	filter, err := controller.GetFilter(request)
-	body := new(models.IdiomResponse)
+	filter, err := controller.GetFilter(request, "published_at")
 	if err != nil {
-		str, _ := json.Marshal(body)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	idioms, err := controller.idiomService.GetIdioms(filter, true)
+	page, err := controller.idiomService.GetIdioms(filter, true)
 	if err != nil {
-		str, _ := json.Marshal(body)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
+	controller.writeIdioms(writer, page)
+}
 
-	cursorToken, err := controller.EncodeToken(idioms, filter)
+func (controller *Controller) writeIdioms(writer http.ResponseWriter, page *Page) {
+	body := new(models.IdiomResponse)
+	cursorToken, err := controller.paginator.Tokens(page)
 	if err != nil {
 		controller.logger.Warn("failed to create cursor tokens.", err)
-		str, _ := json.Marshal(body)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	body.Cursor.Previous = cursorToken.Previous
-	body.Cursor.Next = cursorToken.Next
-	body.Idioms = idioms
-	str, _ := json.Marshal(body)
-	writer.Write(str)
+	body.Cursor = *cursorToken
+	body.Idioms = page.Idioms
+	lib.WriteJSON(writer, http.StatusOK, body)
 }
 
 func (controller *Controller) GetMainPageIdioms(writer http.ResponseWriter, request *http.Request) {
-	body := map[string]interface{}{
-		"idioms": nil,
-	}
-	writer.Header().Add("content-type", "application/json")
-
 	idioms, err := controller.idiomService.GetMainPageIdioms()
 	if err != nil {
-		str, _ := json.Marshal(body)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-
-	body["idioms"] = idioms
-	str, _ := json.Marshal(body)
-	writer.Write(str)
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"idioms": idioms,
+	})
 }
 
 func (controller *Controller) UploadThumbnail(writer http.ResponseWriter, request *http.Request) {
-	message := map[string]interface{}{
-		"idiomId":   nil,
-		"thumbnail": nil,
-	}
-
 	formSize := 32 << 20
 	formBufferSize := 4 << 20
-	_ = formBufferSize
-	// request.Body = http.MaxBytesReader(writer, request.Body, int64(formSize)+int64(formBufferSize))
-	err := request.ParseMultipartForm(int64(formSize))
-
-	writer.Header().Add("content-type", "application/json")
-
+	request.Body = http.MaxBytesReader(writer, request.Body, int64(formSize)+int64(formBufferSize))
+	err := request.ParseMultipartForm(int64(formBufferSize))
 	if err != nil {
 		controller.logger.Error(err, "Failed to parse form.")
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, lib.NewValidationError("Failed to parse form.", err.Error()))
 		return
 	}
 
 	idiomId := request.FormValue("idiomId")
-
-	message = map[string]interface{}{
-		"idiomId":   idiomId,
-		"thumbnail": nil,
-	}
-
 	formFile, handler, err := request.FormFile("thumbnail")
 	if err != nil {
 		controller.logger.Error(err, "Failed to get file from form", idiomId)
-		message["idiomId"] = idiomId
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, lib.NewValidationError("Thumbnail file is required.", map[string]string{"idiomId": idiomId}))
 		return
 	}
 	file := &lib.File{
@@ -361,184 +272,447 @@ func (controller *Controller) UploadThumbnail(writer http.ResponseWriter, reques
 		Extension: path.Ext(handler.Filename),
 	}
 
-	thumbnail, err := controller.thumbnailService.UploadThumbnail(idiomId, file)
+	reqContext := request.Context()
+	thumbnail, err := controller.thumbnailService.UploadThumbnail(idiomId, file, &reqContext)
 	if err != nil {
-		message["idiomId"] = idiomId
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"idiomId":   idiomId,
+		"thumbnail": thumbnail,
+	})
+}
 
-	message["thumbnail"] = thumbnail
-	str, _ := json.Marshal(message)
-	writer.Write(str)
+func (controller *Controller) CreateThumbnailUpload(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	input := new(models.CreateThumbnailUploadInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, invalidJSON(err))
+		return
+	}
+	reqContext := request.Context()
+	upload, err := controller.thumbnailService.CreateUploadURL(idiomId, input, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"upload": upload,
+	})
 }
 
-func (controller *Controller) CreateDescription(writer http.ResponseWriter, request *http.Request) {
-	message := map[string]interface{}{
-		"id":          nil,
-		"description": nil,
+func (controller *Controller) ConfirmThumbnailUpload(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	input := new(models.ConfirmThumbnailUploadInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, invalidJSON(err))
+		return
 	}
+	reqContext := request.Context()
+	thumbnail, err := controller.thumbnailService.ConfirmUpload(idiomId, input.Key, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"idiomId":   idiomId,
+		"thumbnail": thumbnail,
+	})
+}
+
+func (controller *Controller) CreateDescription(writer http.ResponseWriter, request *http.Request) {
 	idiomId := chi.URLParam(request, "id")
-	description, err := controller.idiomService.CreateDescription(idiomId)
+	reqContext := request.Context()
+	description, err := controller.idiomService.CreateDescription(idiomId, &reqContext)
 	if err != nil {
-		message["idiomId"] = idiomId
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	message["id"] = idiomId
-	message["description"] = description.Description
-	str, _ := json.Marshal(message)
-	writer.Write(str)
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"id":          idiomId,
+		"description": description.Description,
+	})
 }
 
 func (controller *Controller) CreateThumbnailByURL(writer http.ResponseWriter, request *http.Request) {
-	message := map[string]interface{}{
-		"idiomId":   nil,
-		"thumbnail": nil,
-	}
 	idiomId := request.FormValue("idiomId")
 	imageUrl := request.FormValue("imageUrl")
-	message["idiomId"] = idiomId
-	decodedUrl, _ := base64.StdEncoding.DecodeString(imageUrl)
+	decodedUrl, err := base64.StdEncoding.DecodeString(imageUrl)
+	if err != nil || len(decodedUrl) == 0 {
+		lib.WriteError(writer, lib.NewValidationError("imageUrl must be a base64 encoded URL.", map[string]string{"idiomId": idiomId}))
+		return
+	}
 
-	thumbnail, err := controller.thumbnailService.CreateThumbnailByURL(idiomId, string(decodedUrl))
+	reqContext := request.Context()
+	thumbnail, err := controller.thumbnailService.CreateThumbnailByURL(idiomId, string(decodedUrl), &reqContext)
 	if err != nil {
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	message["thumbnail"] = thumbnail
-	str, _ := json.Marshal(message)
-	writer.Write(str)
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"idiomId":   idiomId,
+		"thumbnail": thumbnail,
+	})
 }
 
 func (controller *Controller) UpdateThumbnailPrompt(writer http.ResponseWriter, request *http.Request) {
-	message := map[string]interface{}{
-		"idiomId":         nil,
-		"thumbnailPrompt": nil,
-	}
 	id := chi.URLParam(request, "id")
 	body := new(models.IdiomThumbnailBody)
 	err := json.NewDecoder(request.Body).Decode(body)
 	if err != nil {
 		controller.logger.Error(err, "Failed to decode JSON.", id)
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, invalidJSON(err))
 		return
 	}
 	_, err = controller.idiomService.UpdateThumbnailPrompt(id, body.ThumbnailPrompt)
 	if err != nil {
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	message["thumbnailPrompt"] = body.ThumbnailPrompt
-	str, _ := json.Marshal(message)
-	writer.Write(str)
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"idiomId":         id,
+		"thumbnailPrompt": body.ThumbnailPrompt,
+	})
 }
 
 func (controller *Controller) CreateIdiomInputs(writer http.ResponseWriter, request *http.Request) {
-	message := map[string]interface{}{
-		"rows": nil,
-	}
 	inputs := []models.IdiomInput{}
 	err := json.NewDecoder(request.Body).Decode(&inputs)
 	if err != nil {
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, invalidJSON(err))
 		return
 	}
 
-	rows, err := controller.idiomService.CreateIdiomInputs(inputs)
+	reqContext := request.Context()
+	rows, err := controller.idiomService.CreateIdiomInputs(inputs, &reqContext)
 	if err != nil {
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	message["rows"] = &rows
-	str, _ := json.Marshal(message)
-	writer.Write(str)
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"rows": rows,
+	})
 }
 
-func (controller *Controller) CreateThumbnail(writer http.ResponseWriter, request *http.Request) {
-	message := map[string]interface{}{
-		"image": nil,
+// ImportIdiomInputs streams a CSV or NDJSON body, chosen by the format parameter or the content type.
+func (controller *Controller) ImportIdiomInputs(writer http.ResponseWriter, request *http.Request) {
+	importSize := 64 << 20
+	format := request.URL.Query().Get("format")
+	if len(format) == 0 {
+		format = FormatByContentType(request.Header.Get("content-type"))
+	}
+	reader, err := NewInputReader(format, http.MaxBytesReader(writer, request.Body, int64(importSize)))
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
 	}
+	reqContext := request.Context()
+	report, err := controller.idiomService.ImportInputs(reader, &ImportOption{
+		DryRun: request.URL.Query().Get("dryRun") == "true",
+	}, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"report": report,
+	})
+}
+
+func (controller *Controller) CreateThumbnail(writer http.ResponseWriter, request *http.Request) {
 	input := new(models.IdiomImageInput)
 	err := json.NewDecoder(request.Body).Decode(&input)
 	if err != nil {
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, invalidJSON(err))
 		return
 	}
-	image, err := controller.thumbnailService.CreateThumbnail(input.Prompt)
+	idiomId := chi.URLParam(request, "id")
+	if len(idiomId) == 0 {
+		idiomId = input.Idiom
+	}
+	reqContext := request.Context()
+	draft, err := controller.thumbnailService.CreateThumbnail(idiomId, input.Prompt, &reqContext)
 	if err != nil {
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	message["image"] = *image
-	str, _ := json.Marshal(message)
-	writer.Write(str)
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"image": draft.StorageKey,
+		"draft": draft,
+	})
 }
 
-func (controller *Controller) CreateExamples(writer http.ResponseWriter, request *http.Request) {
-	message := map[string]interface{}{
-		"idiom": nil,
+func (controller *Controller) GetThumbnailDrafts(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	reqContext := request.Context()
+	drafts, err := controller.thumbnailService.GetDrafts(idiomId, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"drafts": drafts,
+	})
+}
+
+func (controller *Controller) PromoteThumbnailDraft(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	draftId, err := strconv.ParseInt(chi.URLParam(request, "draftId"), 10, 64)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid draft id.", nil))
+		return
+	}
+	reqContext := request.Context()
+	draft, err := controller.thumbnailService.PromoteDraft(idiomId, draftId, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"draft": draft,
+	})
+}
+
+func (controller *Controller) DiscardThumbnailDraft(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	draftId, err := strconv.ParseInt(chi.URLParam(request, "draftId"), 10, 64)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid draft id.", nil))
+		return
+	}
+	reqContext := request.Context()
+	err = controller.thumbnailService.DiscardDraft(idiomId, draftId, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"id": draftId,
+	})
+}
+
+func (controller *Controller) DiscardThumbnailDrafts(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	reqContext := request.Context()
+	discarded, err := controller.thumbnailService.DiscardDrafts(idiomId, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
 	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"idiomId":   idiomId,
+		"discarded": discarded,
+	})
+}
+
+func (controller *Controller) CreateExamples(writer http.ResponseWriter, request *http.Request) {
 	input := new(models.CreateExamplesInput)
 	err := json.NewDecoder(request.Body).Decode(&input)
 	if err != nil {
 		controller.logger.Error(err, "Failed to decode JSON.", input)
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, invalidJSON(err))
 		return
 	}
 	reqContext := request.Context()
 	newIdiom, err := controller.idiomService.CreateExamples(input, &reqContext)
 	if err != nil {
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, err)
 		return
 	}
-	message["idiom"] = newIdiom
-	str, _ := json.Marshal(message)
-	writer.Write(str)
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"idiom": newIdiom,
+	})
 }
 
 func (controller *Controller) UpdateExamples(writer http.ResponseWriter, request *http.Request) {
-	message := map[string]interface{}{
-		"status":  "ok",
-		"message": nil,
-	}
 	form := new(models.UpdateExamplesInput)
-
 	err := json.NewDecoder(request.Body).Decode(form)
 	if err != nil {
 		controller.logger.Error(err, "Failed to decode JSON.")
-		message["status"] = "failed"
-		message["message"] = "Failed to decode JSON."
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, invalidJSON(err))
 		return
 	}
 
 	reqContext := request.Context()
 	_, err = controller.idiomService.UpdateExamples(form, &reqContext)
-
 	if err != nil {
 		controller.logger.Error(err, "Failed to update the idiom.")
-		message["status"] = "failed"
-		message["message"] = "Failed to update the idiom."
-		str, _ := json.Marshal(message)
-		writer.Write(str)
+		lib.WriteError(writer, err)
+		return
+	}
+
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"status":  "ok",
+		"message": nil,
+		"form":    form,
+	})
+}
+
+func (controller *Controller) UpdateStatus(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	input := new(models.UpdateIdiomStatusInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, invalidJSON(err))
+		return
+	}
+	reqContext := request.Context()
+	idiom, err := controller.idiomService.UpdateStatus(idiomId, input, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"idiom": idiom,
+	})
+}
+
+func (controller *Controller) CreateIdiom(writer http.ResponseWriter, request *http.Request) {
+	input := new(models.CreateIdiomInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, invalidJSON(err))
+		return
+	}
+	reqContext := request.Context()
+	idiom, err := controller.idiomService.CreateIdiom(input, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusCreated, map[string]interface{}{
+		"idiom": idiom,
+	})
+}
+
+func (controller *Controller) PatchIdiom(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	input := new(models.PatchIdiomInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, invalidJSON(err))
+		return
+	}
+	reqContext := request.Context()
+	idiom, err := controller.idiomService.PatchIdiom(idiomId, input, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
 		return
 	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"idiom": idiom,
+	})
+}
+
+func (controller *Controller) DeleteIdiom(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	reqContext := request.Context()
+	err := controller.idiomService.DeleteIdiom(idiomId, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"id": idiomId,
+	})
+}
+
+func (controller *Controller) GetExamples(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	reqContext := request.Context()
+	examples, err := controller.idiomService.GetExamples(idiomId, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"examples": examples,
+	})
+}
+
+func (controller *Controller) CreateExample(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	input := new(models.CreateExampleInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, invalidJSON(err))
+		return
+	}
+	reqContext := request.Context()
+	example, err := controller.idiomService.CreateExample(idiomId, input, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusCreated, map[string]interface{}{
+		"example": example,
+	})
+}
+
+func (controller *Controller) PatchExample(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	exampleId, err := strconv.ParseInt(chi.URLParam(request, "exampleId"), 10, 64)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid example id.", nil))
+		return
+	}
+	input := new(models.PatchExampleInput)
+	err = json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, invalidJSON(err))
+		return
+	}
+	reqContext := request.Context()
+	example, err := controller.idiomService.PatchExample(idiomId, exampleId, input, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"example": example,
+	})
+}
+
+func (controller *Controller) DeleteExample(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	exampleId, err := strconv.ParseInt(chi.URLParam(request, "exampleId"), 10, 64)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid example id.", nil))
+		return
+	}
+	reqContext := request.Context()
+	err = controller.idiomService.DeleteExample(idiomId, exampleId, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"id": exampleId,
+	})
+}
+
+func (controller *Controller) ReorderExamples(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	input := new(models.ReorderExamplesInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, invalidJSON(err))
+		return
+	}
+	reqContext := request.Context()
+	examples, err := controller.idiomService.ReorderExamples(idiomId, input, &reqContext)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"examples": examples,
+	})
+}
 
-	message["form"] = form
-	str, _ := json.Marshal(message)
-	writer.Write(str)
-	return
+func invalidJSON(err error) *lib.Error {
+	return lib.NewValidationError("Invalid JSON body.", err.Error())
 }
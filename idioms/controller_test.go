@@ -0,0 +1,83 @@
+package idioms
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+func TestGetIdiomsCount(t *testing.T) {
+	_, controller := newMemoryService(numberedIdioms(30))
+	for _, test := range []struct {
+		count  string
+		status int
+		length int
+	}{
+		{"", http.StatusOK, defaultCount},
+		{"abc", http.StatusOK, defaultCount},
+		{"1", http.StatusOK, 1},
+		{"100", http.StatusOK, 30},
+		{"0", http.StatusBadRequest, 0},
+		{"-1", http.StatusBadRequest, 0},
+		{"-2", http.StatusBadRequest, 0},
+		{"101", http.StatusBadRequest, 0},
+		{"1000000000", http.StatusBadRequest, 0},
+	} {
+		recorder := httptest.NewRecorder()
+		controller.GetIdioms(recorder, httptest.NewRequest("GET", "/idioms?count="+test.count, nil))
+		if recorder.Code != test.status {
+			t.Errorf("Expected %d for count %q, received %d", test.status, test.count, recorder.Code)
+			continue
+		}
+		if test.status != http.StatusOK {
+			continue
+		}
+		body := new(models.IdiomResponse)
+		if err := json.NewDecoder(recorder.Body).Decode(body); err != nil {
+			t.Fatal(err)
+		}
+		if len(body.Idioms) != test.length {
+			t.Errorf("Expected %d idioms for count %q, received %d", test.length, test.count, len(body.Idioms))
+		}
+	}
+}
+
+func TestGetFilterOrderBy(t *testing.T) {
+	_, controller := newMemoryService(nil)
+	for _, test := range []struct {
+		orderBy        string
+		defaultOrderBy string
+		expected       string
+	}{
+		{"", "published_at", "published_at"},
+		{"relevance", "rank", "rank"},
+		{"rank", "rank", "rank"},
+		{"publishedAt", "rank", "published_at"},
+		{"createdAt", "published_at", "created_at"},
+		{"idiom", "published_at", "idiom"},
+		{"rank", "published_at", ""},
+		{"publishedat", "published_at", ""},
+		{"published_at", "published_at", ""},
+	} {
+		filter, err := controller.GetFilter(httptest.NewRequest("GET", "/idioms?orderBy="+test.orderBy, nil), test.defaultOrderBy)
+		if len(test.expected) == 0 {
+			var libError *lib.Error
+			if !errors.As(err, &libError) || libError.Code != lib.ErrorValidation {
+				t.Errorf("Expected a validation error for orderBy %q, received %v", test.orderBy, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Expected orderBy %q to be accepted, received %v", test.orderBy, err)
+			continue
+		}
+		if filter.OrderBy != test.expected {
+			t.Errorf("Expected orderBy %q to sort by %s, received %s", test.orderBy, test.expected, filter.OrderBy)
+		}
+	}
+}
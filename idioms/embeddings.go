@@ -0,0 +1,54 @@
+package idioms
+
+import (
+	"context"
+
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+const (
+	embedBatchSize  = 32
+	embedBatchLimit = 20
+	similarCount    = 8
+)
+
+// EmbedIdioms is the job handler embedding the meanings and examples of the published idioms
+// which have no embedding of the current model or changed after they were embedded. A run
+// embeds at most embedBatchLimit batches, and the next run continues a large backfill.
+func (service *Service) EmbedIdioms(ctx context.Context, job *models.Job) error {
+	model := service.ai.EmbeddingModel()
+	embedded := 0
+	for batch := 0; batch < embedBatchLimit; batch++ {
+		sources, err := service.repository.ListIdiomsToEmbed(ctx, model, embedBatchSize)
+		if err != nil {
+			service.logger.Error(err, "Failed to query the idioms to embed.")
+			return err
+		}
+		if len(sources) == 0 {
+			break
+		}
+		input := []string{}
+		for _, source := range sources {
+			input = append(input, source.Source)
+		}
+		embeddings, err := service.ai.Embedding(ctx, input)
+		if err != nil {
+			return err
+		}
+		for index := range sources {
+			err = service.repository.UpdateIdiomEmbedding(ctx, &sources[index], model, embeddings[index])
+			if err != nil {
+				service.logger.Error(err, "Failed to store the embedding of the idiom", sources[index].ID)
+				return err
+			}
+		}
+		embedded += len(sources)
+		if len(sources) < embedBatchSize {
+			break
+		}
+	}
+	if embedded > 0 {
+		service.logger.Info("Embedded idioms.", embedded, model)
+	}
+	return nil
+}
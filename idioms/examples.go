@@ -0,0 +1,236 @@
+package idioms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/repository"
+)
+
+func (service *Service) GetExamples(idiomId string, ctx *context.Context) ([]models.IdiomExample, error) {
+	exists, err := service.repository.IdiomExists(*ctx, idiomId)
+	if err != nil {
+		service.logger.Error(err, "Failed to query the idiom with id.", idiomId)
+		return nil, err
+	}
+	if !exists {
+		return nil, lib.NewNotFoundError("Idiom not found.", map[string]string{"id": idiomId})
+	}
+	examples, err := service.repository.GetExamples(*ctx, idiomId)
+	if err != nil {
+		service.logger.Error(err, "Failed to query the examples of the idiom.", idiomId)
+		return nil, err
+	}
+	return examples, nil
+}
+
+// CreateExample inserts an example at position, or after the last example when it is nil.
+func (service *Service) CreateExample(idiomId string, input *models.CreateExampleInput, ctx *context.Context) (*models.IdiomExample, error) {
+	if problems := input.Validate(); len(problems) > 0 {
+		return nil, invalidInput("Invalid example.", problems)
+	}
+	return service.editExamples(*ctx, idiomId, func(idioms repository.IdiomRepository, count int) (*models.IdiomExample, error) {
+		position := count
+		if input.Position != nil && *input.Position < count {
+			position = *input.Position
+		}
+		err := service.shiftExamples(*ctx, idioms, idiomId, position, count, 1)
+		if err != nil {
+			return nil, err
+		}
+		example, err := idioms.CreateExample(*ctx, idiomId, input.Expression, position)
+		if err != nil {
+			service.logger.Error(err, "Failed to insert the example.", idiomId)
+			return nil, err
+		}
+		return example, nil
+	})
+}
+
+func (service *Service) PatchExample(idiomId string, exampleId int64, input *models.PatchExampleInput, ctx *context.Context) (*models.IdiomExample, error) {
+	if problems := input.Validate(); len(problems) > 0 {
+		return nil, invalidInput("Invalid example.", problems)
+	}
+	// A patch which only moves the example where it already is changes nothing, and records no revision.
+	if input.Expression == nil {
+		example, err := service.findExample(*ctx, service.repository, idiomId, exampleId)
+		if err != nil {
+			return nil, err
+		}
+		count, err := service.repository.CountExamples(*ctx, idiomId)
+		if err != nil {
+			service.logger.Error(err, "Failed to count the examples.", idiomId)
+			return nil, err
+		}
+		if clampPosition(*input.Position, count) == example.Position {
+			return example, nil
+		}
+	}
+	return service.editExamples(*ctx, idiomId, func(idioms repository.IdiomRepository, count int) (*models.IdiomExample, error) {
+		example, err := service.findExample(*ctx, idioms, idiomId, exampleId)
+		if err != nil {
+			return nil, err
+		}
+		var position *int
+		if input.Position != nil && clampPosition(*input.Position, count) != example.Position {
+			to := clampPosition(*input.Position, count)
+			if to < example.Position {
+				err = service.shiftExamples(*ctx, idioms, idiomId, to, example.Position, 1)
+			} else {
+				err = service.shiftExamples(*ctx, idioms, idiomId, example.Position+1, to+1, -1)
+			}
+			if err != nil {
+				return nil, err
+			}
+			position = &to
+		}
+		example, err = idioms.UpdateExample(*ctx, exampleId, input.Expression, position)
+		if err != nil {
+			service.logger.Error(err, "Failed to update the example.", idiomId, exampleId)
+			return nil, err
+		}
+		return example, nil
+	})
+}
+
+func (service *Service) DeleteExample(idiomId string, exampleId int64, ctx *context.Context) error {
+	_, err := service.editExamples(*ctx, idiomId, func(idioms repository.IdiomRepository, count int) (*models.IdiomExample, error) {
+		example, err := service.findExample(*ctx, idioms, idiomId, exampleId)
+		if err != nil {
+			return nil, err
+		}
+		err = idioms.DeleteExample(*ctx, exampleId)
+		if err != nil {
+			service.logger.Error(err, "Failed to delete the example.", idiomId, exampleId)
+			return nil, err
+		}
+		return example, service.shiftExamples(*ctx, idioms, idiomId, example.Position+1, count, -1)
+	})
+	return err
+}
+
+// ReorderExamples sets the order of the examples to the ids, which must list every
+// example of the idiom exactly once.
+func (service *Service) ReorderExamples(idiomId string, input *models.ReorderExamplesInput, ctx *context.Context) ([]models.IdiomExample, error) {
+	var examples []models.IdiomExample
+	_, err := service.editExamples(*ctx, idiomId, func(idioms repository.IdiomRepository, count int) (*models.IdiomExample, error) {
+		current, err := service.findExamples(*ctx, idioms, idiomId)
+		if err != nil {
+			return nil, err
+		}
+		problems := validateOrder(current, input.IDs)
+		if len(problems) > 0 {
+			return nil, invalidInput("Invalid order of examples.", problems)
+		}
+		err = idioms.ReorderExamples(*ctx, idiomId, input.IDs)
+		if err != nil {
+			service.logger.Error(err, "Failed to reorder the examples.", idiomId)
+			return nil, err
+		}
+		examples, err = service.findExamples(*ctx, idioms, idiomId)
+		return nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return examples, nil
+}
+
+// editExamples runs an edit of the examples in a transaction holding the lock of the
+// idiom, so concurrent edits keep the positions running from 0 without gaps, and
+// records a revision with the result.
+func (service *Service) editExamples(ctx context.Context, idiomId string, edit func(idioms repository.IdiomRepository, count int) (*models.IdiomExample, error)) (*models.IdiomExample, error) {
+	tx, err := service.db.BeginTxx(ctx, nil)
+	if err != nil {
+		service.logger.Error(err, "Failed to instantiate new transaction.")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	idioms := service.repository.WithTx(tx)
+	err = idioms.LockIdiom(ctx, idiomId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, lib.NewNotFoundError("Idiom not found.", map[string]string{"id": idiomId})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to lock the idiom.", idiomId)
+		return nil, err
+	}
+	count, err := idioms.CountExamples(ctx, idiomId)
+	if err != nil {
+		return nil, err
+	}
+
+	example, err := edit(idioms, count)
+	if err != nil {
+		return nil, err
+	}
+	err = service.revisions.Record(ctx, tx, idiomId, models.RevisionSourceAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return example, tx.Commit()
+}
+
+// clampPosition keeps a position requested for an existing example within the count examples.
+func clampPosition(position int, count int) int {
+	if position > count-1 {
+		return count - 1
+	}
+	return position
+}
+
+// shiftExamples moves the examples at the positions from up to, and not including, to by shift.
+func (service *Service) shiftExamples(ctx context.Context, idioms repository.IdiomRepository, idiomId string, from int, to int, shift int) error {
+	err := idioms.ShiftExamples(ctx, idiomId, from, to, shift)
+	if err != nil {
+		service.logger.Error(err, "Failed to move the examples.", idiomId)
+	}
+	return err
+}
+
+func (service *Service) findExamples(ctx context.Context, idioms repository.IdiomRepository, idiomId string) ([]models.IdiomExample, error) {
+	examples, err := idioms.GetExamples(ctx, idiomId)
+	if err != nil {
+		service.logger.Error(err, "Failed to query the examples.", idiomId)
+		return nil, err
+	}
+	return examples, nil
+}
+
+func (service *Service) findExample(ctx context.Context, idioms repository.IdiomRepository, idiomId string, exampleId int64) (*models.IdiomExample, error) {
+	example, err := idioms.GetExample(ctx, idiomId, exampleId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, lib.NewNotFoundError("Example not found.", map[string]interface{}{"idiomId": idiomId, "id": exampleId})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to query the example.", idiomId, exampleId)
+		return nil, err
+	}
+	return example, nil
+}
+
+func validateOrder(examples []models.IdiomExample, ids []int64) []string {
+	problems := []string{}
+	if len(ids) != len(examples) {
+		problems = append(problems, fmt.Sprintf("ids must list all %d examples, not %d", len(examples), len(ids)))
+	}
+	known := map[int64]bool{}
+	for _, example := range examples {
+		known[example.ID] = true
+	}
+	seen := map[int64]bool{}
+	for _, id := range ids {
+		if !known[id] {
+			problems = append(problems, fmt.Sprintf("example %d does not belong to the idiom", id))
+		}
+		if seen[id] {
+			problems = append(problems, fmt.Sprintf("example %d is listed more than once", id))
+		}
+		seen[id] = true
+	}
+	return problems
+}
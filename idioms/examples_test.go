@@ -0,0 +1,49 @@
+package idioms
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+func TestValidateOrder(t *testing.T) {
+	examples := []models.IdiomExample{{ID: 1}, {ID: 2}, {ID: 3}}
+	for _, test := range []struct {
+		ids      []int64
+		problems int
+	}{
+		{[]int64{3, 1, 2}, 0},
+		{[]int64{1, 2}, 1},
+		{[]int64{1, 2, 4}, 1},
+		{[]int64{1, 1, 2}, 1},
+		{[]int64{1, 1, 2, 5}, 3},
+	} {
+		problems := validateOrder(examples, test.ids)
+		if len(problems) != test.problems {
+			t.Errorf("Expected %d problems for %v, received %v", test.problems, test.ids, problems)
+		}
+	}
+}
+
+func TestPatchExampleUnchanged(t *testing.T) {
+	idioms := numberedIdioms(1)
+	idioms[0].Examples = []string{"First.", "Second."}
+	service, _ := newMemoryService(idioms)
+	ctx := context.Background()
+	examples, err := service.GetExamples(idioms[0].ID, &ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The service has no database, so a patch which began a transaction would panic.
+	for _, position := range []int{1, 5} {
+		example, err := service.PatchExample(idioms[0].ID, examples[1].ID, &models.PatchExampleInput{Position: &position}, &ctx)
+		if err != nil {
+			t.Fatalf("Expected moving the last example to %d to change nothing, received %v", position, err)
+		}
+		if example.ID != examples[1].ID || example.Position != 1 || example.Expression != "Second." {
+			t.Errorf("Expected the example as it is, received %+v", example)
+		}
+	}
+}
@@ -1,26 +1,56 @@
 package idioms
 
 import (
-	"github.com/jackc/pgx/v5/pgtype"
+	"strings"
+
+	"github.com/nw.lee/idioms-backend/repository"
 )
 
-type PageToken [2]string
+const (
+	defaultCount = 20
+	maxCount     = 100
+)
 
-type Cursor struct {
-	Idiom     *string           `json:"idiom"`
-	CreatedAt *pgtype.Timestamp `json:"createdAt"`
+type QueryFilter struct {
+	OrderBy        string   `json:"orderBy"`
+	OrderDirection string   `json:"orderDirection"`
+	Keyword        string   `json:"keyword"`
+	Count          int      `json:"count"`
+	Status         string   `json:"status"`
+	Tags           []string `json:"tags"`
+	Category       string   `json:"category"`
 
-	IsNext bool `json:"isNext"`
+	cursor *Cursor
 }
 
-type QueryFilter struct {
-	OrderBy        string `json:"orderBy"`
-	OrderDirection string `json:"orderDirection"`
-	Keyword        string `json:"keyword"`
-	Count          int    `json:"count"`
+// descending is the direction the page is read in, which is against the order of the
+// filter for the page before a cursor.
+func (filter *QueryFilter) descending() bool {
+	descending := filter.OrderDirection == "desc"
+	if filter.cursor != nil && !filter.cursor.IsNext {
+		return !descending
+	}
+	return descending
+}
 
-	operator            string
-	innerOrderDirection string
-	idiom               *string
-	createdAt           *pgtype.Timestamp
+// page is the repository page of the filter. It reads one idiom more than the count, to
+// tell whether another page follows.
+func (filter *QueryFilter) page(published bool) *repository.Page {
+	page := &repository.Page{
+		Published:  published,
+		Status:     filter.Status,
+		Tags:       filter.Tags,
+		Category:   filter.Category,
+		Keyword:    strings.TrimSpace(filter.Keyword),
+		OrderBy:    filter.OrderBy,
+		Descending: filter.descending(),
+		Count:      filter.Count + 1,
+	}
+	if filter.cursor != nil {
+		page.Idiom = filter.cursor.Idiom
+		page.Time = filter.cursor.Time
+		page.Rank = filter.cursor.Rank
+		page.ID = &filter.cursor.ID
+	}
+	return page
 }
@@ -0,0 +1,27 @@
+package idioms
+
+import (
+	"testing"
+)
+
+func TestPage(t *testing.T) {
+	filter := &QueryFilter{OrderBy: "idiom", OrderDirection: "asc", Count: 20, Tags: []string{"business", "money"}, Category: "work", Keyword: " cake "}
+	page := filter.page(true)
+	if !page.Published || page.Descending || page.OrderBy != "idiom" || page.Count != 21 || page.ID != nil {
+		t.Errorf("Unexpected page %+v", page)
+	}
+	if len(page.Tags) != 2 || page.Category != "work" || page.Keyword != "cake" {
+		t.Errorf("Expected the topics and the trimmed keyword, received %+v", page)
+	}
+
+	// A previous page reads backwards from the key of the cursor.
+	idiom := "piece of cake"
+	filter.cursor = &Cursor{OrderBy: "idiom", Idiom: &idiom, ID: "piece-of-cake"}
+	if page := filter.page(false); !page.Descending || page.Idiom != &idiom || *page.ID != "piece-of-cake" {
+		t.Errorf("Expected a descending page from the cursor, received %+v", page)
+	}
+	filter.cursor.IsNext = true
+	if page := filter.page(false); page.Descending {
+		t.Errorf("Expected the next page in the order of the filter, received %+v", page)
+	}
+}
@@ -0,0 +1,348 @@
+package idioms
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+const (
+	importBatchSize = 500
+	maxImportLine   = 1 << 20
+)
+
+type ImportOption struct {
+	DryRun bool
+}
+
+type ImportedRow struct {
+	Line     int
+	Input    models.IdiomInput
+	Problems []string
+}
+
+// InputReader reads an import one row at a time. A row which can not be parsed
+// is returned with its problems, and io.EOF ends the import.
+type InputReader interface {
+	Read() (*ImportedRow, error)
+}
+
+func NewInputReader(format string, reader io.Reader) (InputReader, error) {
+	switch format {
+	case models.ImportCSV:
+		{
+			return newCSVReader(reader)
+		}
+	case models.ImportNDJSON:
+		{
+			return newNDJSONReader(reader), nil
+		}
+	default:
+		{
+			return nil, lib.NewValidationError("The format must be csv or ndjson.", map[string]string{"format": format})
+		}
+	}
+}
+
+func FormatByContentType(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		{
+			return models.ImportCSV
+		}
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		{
+			return models.ImportNDJSON
+		}
+	default:
+		{
+			return ""
+		}
+	}
+}
+
+func FormatByExtension(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		{
+			return models.ImportCSV
+		}
+	case ".ndjson", ".jsonl":
+		{
+			return models.ImportNDJSON
+		}
+	default:
+		{
+			return ""
+		}
+	}
+}
+
+// csvReader reads a CSV with a header row naming the idiom and meaning columns.
+// Other columns are ignored.
+type csvReader struct {
+	reader  *csv.Reader
+	idiom   int
+	meaning int
+}
+
+func newCSVReader(reader io.Reader) (*csvReader, error) {
+	csvReader := new(csvReader)
+	csvReader.reader = csv.NewReader(reader)
+	csvReader.reader.FieldsPerRecord = -1
+	csvReader.reader.ReuseRecord = true
+	csvReader.idiom = -1
+	csvReader.meaning = -1
+
+	header, err := csvReader.reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, lib.NewValidationError("The file is empty.", nil)
+	}
+	if err != nil {
+		return nil, lib.NewValidationError("The header can not be parsed.", err.Error())
+	}
+	for index, name := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) {
+		case "idiom":
+			{
+				csvReader.idiom = index
+			}
+		case "meaning":
+			{
+				csvReader.meaning = index
+			}
+		}
+	}
+	if csvReader.idiom < 0 {
+		return nil, lib.NewValidationError("The header must have an idiom column.", header)
+	}
+	return csvReader, nil
+}
+
+func (reader *csvReader) Read() (*ImportedRow, error) {
+	record, err := reader.reader.Read()
+	if err != nil {
+		var parseError *csv.ParseError
+		if errors.As(err, &parseError) {
+			return &ImportedRow{Line: parseError.StartLine, Problems: []string{parseError.Err.Error()}}, nil
+		}
+		return nil, err
+	}
+	line, _ := reader.reader.FieldPos(0)
+	row := &ImportedRow{Line: line}
+	row.Input.Idiom = field(record, reader.idiom)
+	row.Input.Meaning = field(record, reader.meaning)
+	return row, nil
+}
+
+func field(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return record[index]
+}
+
+// ndjsonReader reads one JSON object with idiom and meaning per line. Blank lines are skipped.
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONReader(reader io.Reader) *ndjsonReader {
+	ndjsonReader := new(ndjsonReader)
+	ndjsonReader.scanner = bufio.NewScanner(reader)
+	ndjsonReader.scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	return ndjsonReader
+}
+
+func (reader *ndjsonReader) Read() (*ImportedRow, error) {
+	for reader.scanner.Scan() {
+		reader.line++
+		text := bytes.TrimSpace(reader.scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		row := &ImportedRow{Line: reader.line}
+		body := struct {
+			Idiom   string `json:"idiom"`
+			Meaning string `json:"meaning"`
+		}{}
+		err := json.Unmarshal(text, &body)
+		if err != nil {
+			row.Problems = []string{fmt.Sprintf("invalid JSON: %s", err.Error())}
+			return row, nil
+		}
+		row.Input.Idiom = body.Idiom
+		row.Input.Meaning = body.Meaning
+		return row, nil
+	}
+	err := reader.scanner.Err()
+	if errors.Is(err, bufio.ErrTooLong) {
+		return nil, lib.NewValidationError(fmt.Sprintf("Line %d is longer than %d bytes.", reader.line+1, maxImportLine), nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// ImportInputs validates and inserts idiom inputs as they are read, a batch at a time.
+// Each batch is committed on its own, so an import which fails halfway can be repeated
+// and the rows which were already imported are reported as duplicates.
+func (service *Service) ImportInputs(reader InputReader, option *ImportOption, ctx *context.Context) (*models.ImportReport, error) {
+	report := &models.ImportReport{DryRun: option.DryRun, Rows: []models.ImportRow{}}
+	seen := map[string]int{}
+	batch := []int{}
+	inputs := map[int]models.IdiomInput{}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var libError *lib.Error
+			if errors.As(err, &libError) {
+				return nil, err
+			}
+			service.logger.Warn("Failed to read the import.", err.Error())
+			return nil, lib.NewValidationError("The import can not be read.", err.Error())
+		}
+		input := models.IdiomInput{
+			Idiom:   strings.TrimSpace(row.Input.Idiom),
+			Meaning: strings.TrimSpace(row.Input.Meaning),
+		}
+		input.ID = lib.ToIdiomID(input.Idiom)
+		result := classifyRow(row, input, seen)
+		report.Rows = append(report.Rows, result)
+		if result.Status != models.ImportAccepted {
+			continue
+		}
+		batch = append(batch, len(report.Rows)-1)
+		inputs[len(report.Rows)-1] = input
+		if len(batch) < importBatchSize {
+			continue
+		}
+		err = service.importBatch(*ctx, report, batch, inputs, option.DryRun)
+		if err != nil {
+			return nil, err
+		}
+		batch = batch[:0]
+		inputs = map[int]models.IdiomInput{}
+	}
+	if len(batch) > 0 {
+		err := service.importBatch(*ctx, report, batch, inputs, option.DryRun)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(report.Rows) == 0 {
+		return nil, lib.NewValidationError("At least one input is required.", nil)
+	}
+	report.Count()
+	return report, nil
+}
+
+// classifyRow validates a row and finds duplicates within the import. The rows which
+// pass are accepted until they are compared with the database.
+func classifyRow(row *ImportedRow, input models.IdiomInput, seen map[string]int) models.ImportRow {
+	result := models.ImportRow{Line: row.Line, ID: input.ID, Idiom: input.Idiom, Status: models.ImportInvalid}
+	if len(row.Problems) > 0 {
+		result.Problems = row.Problems
+		return result
+	}
+	result.Problems = input.Validate()
+	if len(result.Problems) == 0 && len(strings.Trim(input.ID, "-")) == 0 {
+		result.Problems = []string{"idiom must contain a letter or a digit"}
+	}
+	if len(result.Problems) > 0 {
+		return result
+	}
+	if line, ok := seen[input.ID]; ok {
+		result.Status = models.ImportDuplicate
+		result.Problems = []string{fmt.Sprintf("same id as line %d", line)}
+		return result
+	}
+	seen[input.ID] = row.Line
+	result.Status = models.ImportAccepted
+	return result
+}
+
+func (service *Service) importBatch(ctx context.Context, report *models.ImportReport, batch []int, inputs map[int]models.IdiomInput, dryRun bool) error {
+	ids := []string{}
+	for _, index := range batch {
+		ids = append(ids, inputs[index].ID)
+	}
+	existing := map[string]string{}
+	for _, table := range []struct {
+		name string
+		list func(ctx context.Context, ids []string) ([]string, error)
+	}{
+		{"idioms", service.repository.ListExistingIdioms},
+		{"idiom_inputs", service.repository.ListExistingIdiomInputs},
+	} {
+		found, err := table.list(ctx, ids)
+		if err != nil {
+			service.logger.Error(err, "Failed to query existing ids.", table.name)
+			return err
+		}
+		for _, id := range found {
+			if _, ok := existing[id]; !ok {
+				existing[id] = fmt.Sprintf("already in %s", table.name)
+			}
+		}
+	}
+	pending := []models.IdiomInput{}
+	for _, index := range batch {
+		if problem, ok := existing[inputs[index].ID]; ok {
+			report.Rows[index].Status = models.ImportDuplicate
+			report.Rows[index].Problems = []string{problem}
+			continue
+		}
+		pending = append(pending, inputs[index])
+	}
+	if dryRun || len(pending) == 0 {
+		return nil
+	}
+
+	tx, err := service.db.BeginTxx(ctx, nil)
+	if err != nil {
+		service.logger.Error(err, "Failed to instantiate new transaction.")
+		return err
+	}
+	defer tx.Rollback()
+
+	inserted, err := service.insertInputs(ctx, tx, pending)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		service.logger.Error(err, "Failed to commit idiom inputs")
+		return err
+	}
+	accepted := map[string]bool{}
+	for _, id := range inserted {
+		accepted[id] = true
+	}
+	// Inputs which were inserted by someone else since the lookup.
+	for _, index := range batch {
+		row := &report.Rows[index]
+		if row.Status == models.ImportAccepted && !accepted[row.ID] {
+			row.Status = models.ImportDuplicate
+			row.Problems = []string{"already in idiom_inputs"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package idioms
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+func readAll(t *testing.T, reader InputReader) []models.ImportRow {
+	seen := map[string]int{}
+	rows := []models.ImportRow{}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := models.IdiomInput{Idiom: strings.TrimSpace(row.Input.Idiom), Meaning: row.Input.Meaning}
+		input.ID = lib.ToIdiomID(input.Idiom)
+		rows = append(rows, classifyRow(row, input, seen))
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	body := "\ufeffMeaning,Idiom,source\n" +
+		"To start a conversation,Break the ice,book\n" +
+		"\"Something easy\",\"Piece of cake\"\n" +
+		",\n" +
+		"Again,BREAK  the ice\n" +
+		"\"unterminated,Spill the beans\n"
+	reader, err := NewInputReader(models.ImportCSV, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readAll(t, reader)
+	expected := []struct {
+		line   int
+		id     string
+		status string
+	}{
+		{2, "break-the-ice", models.ImportAccepted},
+		{3, "piece-of-cake", models.ImportAccepted},
+		{4, "", models.ImportInvalid},
+		{5, "break-the-ice", models.ImportDuplicate},
+		{6, "", models.ImportInvalid},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("Expected %d rows, received %v", len(expected), rows)
+	}
+	for index, row := range expected {
+		if rows[index].Line != row.line || rows[index].ID != row.id || rows[index].Status != row.status {
+			t.Errorf("Expected %v, received %v", row, rows[index])
+		}
+	}
+}
+
+func TestImportCSVHeader(t *testing.T) {
+	_, err := NewInputReader(models.ImportCSV, strings.NewReader("name,meaning\nBreak the ice,To start\n"))
+	var libError *lib.Error
+	if !errors.As(err, &libError) || libError.Code != lib.ErrorValidation {
+		t.Errorf("Expected a validation error without an idiom column, received %v", err)
+	}
+}
+
+func TestImportNDJSON(t *testing.T) {
+	body := `{"idiom": "Break the ice", "meaning": "To start a conversation"}
+
+{"idiom": "Break the ice"}
+{"idiom":
+{"idiom": "!!!"}
+{"idiom": "Piece of cake"}
+`
+	reader, err := NewInputReader(models.ImportNDJSON, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readAll(t, reader)
+	expected := []struct {
+		line   int
+		status string
+	}{
+		{1, models.ImportAccepted},
+		{3, models.ImportDuplicate},
+		{4, models.ImportInvalid},
+		{5, models.ImportInvalid},
+		{6, models.ImportAccepted},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("Expected %d rows, received %v", len(expected), rows)
+	}
+	for index, row := range expected {
+		if rows[index].Line != row.line || rows[index].Status != row.status {
+			t.Errorf("Expected %v, received %v", row, rows[index])
+		}
+	}
+	if rows[1].Problems[0] != "same id as line 1" {
+		t.Errorf("Expected the duplicate to point at line 1, received %v", rows[1].Problems)
+	}
+
+	reader, _ = NewInputReader(models.ImportNDJSON, strings.NewReader(`{"idiom": "`+strings.Repeat("a", maxImportLine)+`"}`))
+	_, err = reader.Read()
+	if err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Expected an error for a line over the limit, received %v", err)
+	}
+}
+
+func TestImportFormat(t *testing.T) {
+	if format := FormatByContentType("text/csv; charset=utf-8"); format != models.ImportCSV {
+		t.Errorf("Expected csv, received %s", format)
+	}
+	if format := FormatByExtension("inputs.JSONL"); format != models.ImportNDJSON {
+		t.Errorf("Expected ndjson, received %s", format)
+	}
+	if _, err := NewInputReader("xml", strings.NewReader("")); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
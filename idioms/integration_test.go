@@ -0,0 +1,252 @@
+//go:build integration
+
+package idioms
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/engine"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/openai"
+	"github.com/nw.lee/idioms-backend/repository"
+	"github.com/nw.lee/idioms-backend/revisions"
+)
+
+// The integration tests run against the database of TEST_DATABASE_URL with
+// `go test -tags integration ./idioms`. They empty its tables, so never point it at real data.
+func newIntegrationService(t *testing.T) (*Service, *Controller, *sqlx.DB) {
+	t.Helper()
+	source := os.Getenv("TEST_DATABASE_URL")
+	if len(source) == 0 {
+		t.Skip("TEST_DATABASE_URL is not set.")
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	if err = pool.Ping(ctx); err != nil {
+		t.Skipf("Postgres is not reachable: %v", err)
+	}
+	conn := sqlx.NewDb(stdlib.OpenDBFromPool(pool), "pgx")
+	loggerService := logger.NewService(log.Default())
+
+	migrator, err := engine.NewMigrator(conn, loggerService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.ExecContext(ctx, "truncate idioms, idiom_inputs, idiom_revisions, jobs, tags, categories restart identity cascade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisionService := revisions.NewService(conn, loggerService)
+	service := NewService(conn, repository.NewRepository(conn), loggerService, nil, nil, nil, revisionService)
+	paginator, err := NewPaginator("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return service, NewController(service, nil, paginator, loggerService), conn
+}
+
+// seedIdioms creates the idioms as an admin would and publishes all but every fifth an hour apart.
+func seedIdioms(t *testing.T, service *Service, conn *sqlx.DB, inputs []models.CreateIdiomInput) {
+	t.Helper()
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for index := range inputs {
+		idiom, err := service.CreateIdiom(&inputs[index], &ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if index%5 == 4 {
+			continue
+		}
+		_, err = conn.ExecContext(ctx, "update idioms set status = $1, published_at = $2 where id = $3", models.IdiomPublished, start.Add(time.Hour*time.Duration(index)), idiom.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func numberedInputs(count int) []models.CreateIdiomInput {
+	inputs := []models.CreateIdiomInput{}
+	for index := 0; index < count; index++ {
+		inputs = append(inputs, models.CreateIdiomInput{
+			Idiom:        fmt.Sprintf("Idiom %02d", index),
+			MeaningBrief: fmt.Sprintf("Meaning %02d", index),
+			MeaningFull:  fmt.Sprintf("The full meaning of idiom %02d.", index),
+			Examples:     []string{fmt.Sprintf("Example %02d.", index)},
+		})
+	}
+	return inputs
+}
+
+func TestIntegrationGetIdioms(t *testing.T) {
+	service, controller, conn := newIntegrationService(t)
+	seedIdioms(t, service, conn, numberedInputs(10))
+	// Pairs of idioms are published at the same time, which the id of the cursor tells apart.
+	_, err := conn.Exec("update idioms set published_at = date_trunc('day', published_at) + interval '2 hours' * (extract(hour from published_at)::int / 2)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		orderBy        string
+		orderDirection string
+		pages          [][]string
+	}{
+		{"idiom", "asc", [][]string{{"idiom-00", "idiom-01", "idiom-02"}, {"idiom-03", "idiom-05", "idiom-06"}, {"idiom-07", "idiom-08"}}},
+		{"", "desc", [][]string{{"idiom-08", "idiom-07", "idiom-06"}, {"idiom-05", "idiom-03", "idiom-02"}, {"idiom-01", "idiom-00"}}},
+	} {
+		query := url.Values{"orderBy": {test.orderBy}, "orderDirection": {test.orderDirection}, "count": {"3"}}
+		tokens := []*models.CursorToken{}
+		for _, expected := range test.pages {
+			ids, token := readPage(t, service, controller, query)
+			if !reflect.DeepEqual(ids, expected) {
+				t.Errorf("Expected %v by %s %s, received %v", expected, test.orderBy, test.orderDirection, ids)
+			}
+			tokens = append(tokens, token)
+			query.Set("nextToken", token.Next)
+		}
+		query.Del("nextToken")
+		query.Set("prevToken", tokens[2].Previous)
+		if ids, _ := readPage(t, service, controller, query); !reflect.DeepEqual(ids, test.pages[1]) {
+			t.Errorf("Expected the previous page %v by %s %s, received %v", test.pages[1], test.orderBy, test.orderDirection, ids)
+		}
+	}
+}
+
+func TestIntegrationSearchIdioms(t *testing.T) {
+	service, _, conn := newIntegrationService(t)
+	inputs := numberedInputs(3)
+	inputs = append(inputs, models.CreateIdiomInput{
+		Idiom:        "Piece of cake",
+		MeaningBrief: "Something very easy to do.",
+		MeaningFull:  "A task that takes no effort at all, like eating a piece of cake.",
+		Examples:     []string{"The exam was a piece of cake."},
+	})
+	seedIdioms(t, service, conn, inputs)
+
+	page, err := service.SearchIdioms(&QueryFilter{Keyword: "cake", OrderBy: "rank", OrderDirection: "desc", Count: 10}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idioms := page.Idioms
+	if page.HasNext || len(idioms) != 1 || idioms[0].ID != "piece-of-cake" {
+		t.Fatalf("Expected only piece-of-cake, received %v", idioms)
+	}
+	if idioms[0].Rank == nil || idioms[0].Highlights == nil || idioms[0].Highlights.Idiom != "Piece of <mark>cake</mark>" {
+		t.Errorf("Expected the rank and the highlights, received %+v", idioms[0])
+	}
+}
+
+func TestIntegrationGetRelatedIdioms(t *testing.T) {
+	service, _, conn := newIntegrationService(t)
+	seedIdioms(t, service, conn, numberedInputs(15))
+
+	idioms, err := service.GetRelatedIdioms("idiom-07")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, idiom := range idioms {
+		ids = append(ids, idiom.ID)
+	}
+	expected := []string{"idiom-12", "idiom-11", "idiom-10", "idiom-08", "idiom-06", "idiom-05", "idiom-03", "idiom-02"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected %v, received %v", expected, ids)
+	}
+}
+
+func TestIntegrationGetRelatedIdiomsByEmbedding(t *testing.T) {
+	service, _, conn := newIntegrationService(t)
+	inputs := numberedInputs(4)
+	inputs[2].MeaningBrief = inputs[0].MeaningBrief
+	inputs[2].MeaningFull = inputs[0].MeaningFull
+	seedIdioms(t, service, conn, inputs)
+	fake, err := openai.NewFakeServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fake.Close()
+	service.ai = openai.NewOpenAi(&openai.Config{BaseURL: fake.URL, ApiKey: "fake", EmbeddingModel: "fake-embedding"}, service.logger)
+
+	if err = service.EmbedIdioms(context.Background(), &models.Job{Kind: models.JobEmbedIdioms}); err != nil {
+		t.Fatal(err)
+	}
+	idioms, err := service.GetRelatedIdioms("idiom-00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idioms) != 3 || idioms[0].ID != "idiom-02" {
+		t.Errorf("Expected idiom-02 nearest to idiom-00, received %v", idioms)
+	}
+	embedded := 0
+	if err = conn.Get(&embedded, "select count(*) from idioms where embedding is not null"); err != nil {
+		t.Fatal(err)
+	}
+	if embedded != 4 {
+		t.Errorf("Expected the published idioms to be embedded, received %d", embedded)
+	}
+
+	// The content hash kept by the triggers marks an idiom whose examples change as stale.
+	sources, err := service.repository.ListIdiomsToEmbed(context.Background(), "fake-embedding", embedBatchSize)
+	if err != nil || len(sources) != 0 {
+		t.Fatalf("Expected nothing to embed, received %v %v", sources, err)
+	}
+	if _, err = conn.Exec("update idiom_examples set expression = 'A new example.' where idiom_id = 'idiom-01'"); err != nil {
+		t.Fatal(err)
+	}
+	sources, err = service.repository.ListIdiomsToEmbed(context.Background(), "fake-embedding", embedBatchSize)
+	if err != nil || len(sources) != 1 || sources[0].ID != "idiom-01" {
+		t.Errorf("Expected idiom-01 to be embedded again, received %v %v", sources, err)
+	}
+	if sources, _ = service.repository.ListIdiomsToEmbed(context.Background(), "another-model", embedBatchSize); len(sources) != 4 {
+		t.Errorf("Expected every published idiom to be embedded with another model, received %v", sources)
+	}
+}
+
+func TestIntegrationUpdateExamples(t *testing.T) {
+	service, _, conn := newIntegrationService(t)
+	seedIdioms(t, service, conn, numberedInputs(1))
+	ctx := context.Background()
+
+	_, err := service.UpdateExamples(&models.UpdateExamplesInput{
+		ID:           "idiom-00",
+		MeaningBrief: "A new meaning.",
+		MeaningFull:  "The new full meaning.",
+		Examples:     []string{"Second.", "First."},
+	}, &ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idiom, err := service.GetIdiomById("idiom-00", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idiom.MeaningBrief != "A new meaning." || !reflect.DeepEqual(idiom.Examples, []string{"Second.", "First."}) {
+		t.Errorf("Expected the examples to be replaced in order, received %+v", idiom)
+	}
+	revisions := 0
+	if err = conn.GetContext(ctx, &revisions, "select count(*) from idiom_revisions where idiom_id = $1", "idiom-00"); err != nil {
+		t.Fatal(err)
+	}
+	if revisions != 2 {
+		t.Errorf("Expected a revision for the creation and one for the update, received %d", revisions)
+	}
+}
@@ -0,0 +1,196 @@
+package idioms
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+// Cursor is the key of the idiom a page starts after: the value of the column the pages are
+// ordered by, and the id of the idiom to break ties on it.
+type Cursor struct {
+	OrderBy string            `json:"orderBy"`
+	Idiom   *string           `json:"idiom,omitempty"`
+	Time    *pgtype.Timestamp `json:"time,omitempty"`
+	Rank    *float64          `json:"rank,omitempty"`
+	ID      string            `json:"id"`
+
+	IsNext bool `json:"isNext"`
+}
+
+// Page is a page of idioms in the order of its filter.
+type Page struct {
+	Idioms      []models.Idiom
+	HasNext     bool
+	HasPrevious bool
+
+	orderBy string
+}
+
+// Paginator signs the cursors of the pages, so a client can only page from a cursor it was given.
+type Paginator struct {
+	secret []byte
+}
+
+// NewPaginator signs with a random secret when secret is empty, which is only valid in this process.
+func NewPaginator(secret string) (*Paginator, error) {
+	paginator := new(Paginator)
+	paginator.secret = []byte(secret)
+	if len(paginator.secret) == 0 {
+		paginator.secret = make([]byte, 32)
+		_, err := rand.Read(paginator.secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return paginator, nil
+}
+
+// Tokens signs the cursors of the first and the last idioms of the page, for the directions
+// which have another page.
+func (paginator *Paginator) Tokens(page *Page) (*models.CursorToken, error) {
+	token := &models.CursorToken{HasNext: page.HasNext, HasPrevious: page.HasPrevious}
+	if len(page.Idioms) == 0 {
+		return token, nil
+	}
+	var err error
+	if page.HasPrevious {
+		cursor := cursorOf(&page.Idioms[0], page.orderBy)
+		token.Previous, err = paginator.encode(cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if page.HasNext {
+		cursor := cursorOf(&page.Idioms[len(page.Idioms)-1], page.orderBy)
+		cursor.IsNext = true
+		token.Next, err = paginator.encode(cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return token, nil
+}
+
+// Decode verifies a token of Tokens and returns its cursor, which has the value of orderBy.
+func (paginator *Paginator) Decode(token string, orderBy string) (*Cursor, error) {
+	invalid := lib.NewValidationError("Invalid cursor.", map[string]string{"orderBy": orderBy})
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, invalid
+	}
+	body, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, invalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, paginator.sign(body)) {
+		return nil, invalid
+	}
+	cursor := new(Cursor)
+	err = json.Unmarshal(body, cursor)
+	if err != nil || cursor.OrderBy != orderBy || len(cursor.ID) == 0 {
+		return nil, invalid
+	}
+	switch orderBy {
+	case "idiom":
+		{
+			if cursor.Idiom == nil {
+				return nil, invalid
+			}
+			break
+		}
+	case "rank":
+		{
+			if cursor.Rank == nil {
+				return nil, invalid
+			}
+			break
+		}
+	default:
+		{
+			if cursor.Time == nil || !cursor.Time.Valid {
+				return nil, invalid
+			}
+		}
+	}
+	return cursor, nil
+}
+
+func (paginator *Paginator) encode(cursor *Cursor) (string, error) {
+	body, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(body) + "." + base64.RawURLEncoding.EncodeToString(paginator.sign(body)), nil
+}
+
+func (paginator *Paginator) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, paginator.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// paginate drops the idiom read beyond the count of the filter, which tells that another page
+// follows in the direction read, and puts a page read backwards from a cursor in the order of the filter.
+func paginate(idioms []models.Idiom, filter *QueryFilter) *Page {
+	page := &Page{orderBy: filter.OrderBy}
+	more := len(idioms) > filter.Count
+	if more {
+		idioms = idioms[:filter.Count]
+	}
+	if filter.cursor != nil && !filter.cursor.IsNext {
+		for left, right := 0, len(idioms)-1; left < right; left, right = left+1, right-1 {
+			idioms[left], idioms[right] = idioms[right], idioms[left]
+		}
+		page.HasPrevious = more
+		page.HasNext = true
+	} else {
+		page.HasNext = more
+		page.HasPrevious = filter.cursor != nil
+	}
+	page.Idioms = idioms
+	return page
+}
+
+// cursorOf is the key of the idiom in the order of orderBy. An idiom which was never
+// published is keyed at infinity, where the queries sort it.
+func cursorOf(idiom *models.Idiom, orderBy string) *Cursor {
+	cursor := &Cursor{OrderBy: orderBy, ID: idiom.ID}
+	switch orderBy {
+	case "idiom":
+		{
+			value := idiom.Idiom
+			cursor.Idiom = &value
+			break
+		}
+	case "rank":
+		{
+			cursor.Rank = idiom.Rank
+			break
+		}
+	case "created_at":
+		{
+			value := idiom.CreatedAt
+			cursor.Time = &value
+			break
+		}
+	default:
+		{
+			value := idiom.PublishedAt
+			if !value.Valid {
+				value = pgtype.Timestamp{InfinityModifier: pgtype.Infinity, Valid: true}
+			}
+			cursor.Time = &value
+		}
+	}
+	return cursor
+}
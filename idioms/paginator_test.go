@@ -0,0 +1,65 @@
+package idioms
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+func TestPaginatorTokens(t *testing.T) {
+	paginator, _ := NewPaginator("secret")
+	page := &Page{Idioms: []models.Idiom{{ID: "a"}, {ID: "b"}}, HasNext: true, orderBy: "published_at"}
+	token, err := paginator.Tokens(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(token.Previous) > 0 || !token.HasNext || token.HasPrevious {
+		t.Fatalf("Expected only a next token, received %+v", token)
+	}
+	cursor, err := paginator.Decode(token.Next, "published_at")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor.ID != "b" || !cursor.IsNext || cursor.Time.InfinityModifier != pgtype.Infinity {
+		t.Errorf("Expected the key of an unpublished idiom at infinity, received %+v", cursor)
+	}
+
+	payload, signature, _ := strings.Cut(token.Next, ".")
+	other, _ := NewPaginator("other")
+	for name, test := range map[string]struct {
+		paginator *Paginator
+		token     string
+		orderBy   string
+	}{
+		"another order":  {paginator, token.Next, "created_at"},
+		"another secret": {other, token.Next, "published_at"},
+		"no signature":   {paginator, payload, "published_at"},
+		"a payload":      {paginator, strings.ToUpper(payload) + "." + signature, "published_at"},
+	} {
+		if _, err := test.paginator.Decode(test.token, test.orderBy); err == nil {
+			t.Errorf("Expected a token with %s to be rejected", name)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	idioms := func() []models.Idiom {
+		return []models.Idiom{{ID: "c"}, {ID: "b"}, {ID: "a"}}
+	}
+	page := paginate(idioms(), &QueryFilter{Count: 2})
+	if len(page.Idioms) != 2 || !page.HasNext || page.HasPrevious {
+		t.Errorf("Expected a first page with a next page, received %+v", page)
+	}
+
+	// A previous page is read backwards, so the extra idiom is before the page.
+	page = paginate(idioms(), &QueryFilter{Count: 2, cursor: &Cursor{ID: "d"}})
+	if page.Idioms[0].ID != "b" || page.Idioms[1].ID != "c" || !page.HasNext || !page.HasPrevious {
+		t.Errorf("Expected a previous page in order, received %+v", page)
+	}
+	page = paginate(idioms(), &QueryFilter{Count: 3, cursor: &Cursor{ID: "d", IsNext: true}})
+	if len(page.Idioms) != 3 || page.HasNext || !page.HasPrevious {
+		t.Errorf("Expected a last page, received %+v", page)
+	}
+}
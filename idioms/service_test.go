@@ -0,0 +1,199 @@
+package idioms
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/openai"
+	"github.com/nw.lee/idioms-backend/repository"
+)
+
+// numberedIdioms are published an hour apart, apart from every fifth which is a draft.
+func numberedIdioms(count int) []models.Idiom {
+	idioms := []models.Idiom{}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for index := 0; index < count; index++ {
+		idiom := models.Idiom{
+			ID:           fmt.Sprintf("idiom-%02d", index),
+			Idiom:        fmt.Sprintf("Idiom %02d", index),
+			MeaningBrief: fmt.Sprintf("Meaning %02d", index),
+			Status:       models.IdiomPublished,
+			CreatedAt:    repository.ToTimestamp(start.Add(time.Hour * time.Duration(index))),
+			PublishedAt:  repository.ToTimestamp(start.Add(time.Hour * time.Duration(index))),
+			Examples:     []string{fmt.Sprintf("Example %02d.", index)},
+		}
+		if index%5 == 4 {
+			idiom.Status = models.IdiomDraft
+			idiom.PublishedAt = pgtype.Timestamp{}
+		}
+		idioms = append(idioms, idiom)
+	}
+	return idioms
+}
+
+func newMemoryService(idioms []models.Idiom) (*Service, *Controller) {
+	memory := repository.NewMemory()
+	for _, idiom := range idioms {
+		memory.AddIdiom(idiom)
+	}
+	loggerService := logger.NewService(log.Default())
+	service := NewService(nil, memory, loggerService, nil, nil, nil, nil)
+	paginator, _ := NewPaginator("secret")
+	return service, NewController(service, nil, paginator, loggerService)
+}
+
+func readPage(t *testing.T, service *Service, controller *Controller, query url.Values) ([]string, *models.CursorToken) {
+	t.Helper()
+	filter, err := controller.GetFilter(httptest.NewRequest("GET", "/idioms?"+query.Encode(), nil), "published_at")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := service.GetIdioms(filter, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := controller.paginator.Tokens(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, idiom := range page.Idioms {
+		ids = append(ids, idiom.ID)
+	}
+	return ids, token
+}
+
+func TestGetIdiomsPages(t *testing.T) {
+	// Pairs of idioms are published at the same time, which the id of the cursor tells apart.
+	idioms := numberedIdioms(10)
+	for index := range idioms {
+		if idioms[index].PublishedAt.Valid {
+			idioms[index].PublishedAt.Time = idioms[index].PublishedAt.Time.Truncate(time.Hour * 2)
+		}
+	}
+	service, controller := newMemoryService(idioms)
+	for _, test := range []struct {
+		orderBy        string
+		orderDirection string
+		pages          [][]string
+	}{
+		{"idiom", "asc", [][]string{{"idiom-00", "idiom-01", "idiom-02"}, {"idiom-03", "idiom-05", "idiom-06"}, {"idiom-07", "idiom-08"}}},
+		{"createdAt", "desc", [][]string{{"idiom-08", "idiom-07", "idiom-06"}, {"idiom-05", "idiom-03", "idiom-02"}, {"idiom-01", "idiom-00"}}},
+		{"", "desc", [][]string{{"idiom-08", "idiom-07", "idiom-06"}, {"idiom-05", "idiom-03", "idiom-02"}, {"idiom-01", "idiom-00"}}},
+		{"", "asc", [][]string{{"idiom-00", "idiom-01", "idiom-02"}, {"idiom-03", "idiom-05", "idiom-06"}, {"idiom-07", "idiom-08"}}},
+	} {
+		query := url.Values{"orderBy": {test.orderBy}, "orderDirection": {test.orderDirection}, "count": {"3"}}
+		tokens := []*models.CursorToken{}
+		for _, expected := range test.pages {
+			ids, token := readPage(t, service, controller, query)
+			if !reflect.DeepEqual(ids, expected) {
+				t.Errorf("Expected %v by %s %s, received %v", expected, test.orderBy, test.orderDirection, ids)
+			}
+			tokens = append(tokens, token)
+			query.Set("nextToken", token.Next)
+		}
+		if last := tokens[len(tokens)-1]; last.HasNext || len(last.Next) > 0 || tokens[0].HasPrevious {
+			t.Errorf("Expected no page after the last page and before the first, received %+v and %+v", last, tokens[0])
+		}
+
+		// The previous page of the last page is the one before it, in the same order.
+		query.Del("nextToken")
+		query.Set("prevToken", tokens[len(tokens)-1].Previous)
+		ids, _ := readPage(t, service, controller, query)
+		if expected := test.pages[len(test.pages)-2]; !reflect.DeepEqual(ids, expected) {
+			t.Errorf("Expected the previous page %v by %s %s, received %v", expected, test.orderBy, test.orderDirection, ids)
+		}
+	}
+}
+
+func TestGetIdiomById(t *testing.T) {
+	service, _ := newMemoryService(numberedIdioms(5))
+	idiom, err := service.GetIdiomById("idiom-01", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(idiom.Examples, []string{"Example 01."}) {
+		t.Errorf("Expected the examples of the idiom, received %v", idiom.Examples)
+	}
+	if _, err := service.GetIdiomById("idiom-04", true); err == nil {
+		t.Errorf("Expected a draft not to be found among the published idioms")
+	}
+	if _, err := service.GetIdiomById("idiom-04", false); err != nil {
+		t.Errorf("Expected the draft for an admin, received %v", err)
+	}
+}
+
+func TestGetRelatedIdioms(t *testing.T) {
+	service, _ := newMemoryService(numberedIdioms(15))
+	idioms, err := service.GetRelatedIdioms("idiom-07")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, idiom := range idioms {
+		ids = append(ids, idiom.ID)
+	}
+	expected := []string{"idiom-12", "idiom-11", "idiom-10", "idiom-08", "idiom-06", "idiom-05", "idiom-03", "idiom-02"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("Expected %v, received %v", expected, ids)
+	}
+}
+
+func TestGetRelatedIdiomsByEmbedding(t *testing.T) {
+	fake, err := openai.NewFakeServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fake.Close()
+	loggerService := logger.NewService(log.Default())
+	ai := openai.NewOpenAi(&openai.Config{BaseURL: fake.URL, ApiKey: "fake", EmbeddingModel: "fake-embedding"}, loggerService)
+
+	memory := repository.NewMemory()
+	idioms := numberedIdioms(5)
+	idioms[0].MeaningBrief = "Something very easy to do."
+	idioms[0].Examples = []string{"The exam was easy to do."}
+	idioms[2].MeaningBrief = "Something very easy to do."
+	for _, idiom := range idioms {
+		memory.AddIdiom(idiom)
+	}
+	service := NewService(nil, memory, loggerService, ai, nil, nil, nil)
+
+	err = service.EmbedIdioms(context.Background(), &models.Job{Kind: models.JobEmbedIdioms})
+	if err != nil {
+		t.Fatal(err)
+	}
+	related, err := service.GetRelatedIdioms("idiom-00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, idiom := range related {
+		ids = append(ids, idiom.ID)
+	}
+	// The draft idiom-04 is never related, and idiom-02 shares the most words with idiom-00.
+	if len(ids) != 3 || ids[0] != "idiom-02" {
+		t.Errorf("Expected idiom-02 first among the published idioms, received %v", ids)
+	}
+
+	sources, err := memory.ListIdiomsToEmbed(context.Background(), ai.EmbeddingModel(), embedBatchSize)
+	if err != nil || len(sources) != 0 {
+		t.Errorf("Expected every published idiom to be embedded, received %v %v", sources, err)
+	}
+	idioms[1].MeaningFull = "A new meaning."
+	memory.AddIdiom(idioms[1])
+	if sources, _ = memory.ListIdiomsToEmbed(context.Background(), ai.EmbeddingModel(), embedBatchSize); len(sources) != 1 || sources[0].ID != "idiom-01" {
+		t.Errorf("Expected the changed idiom to be embedded again, received %v", sources)
+	}
+	if sources, _ = memory.ListIdiomsToEmbed(context.Background(), "another-model", embedBatchSize); len(sources) != 4 {
+		t.Errorf("Expected every published idiom to be embedded with another model, received %v", sources)
+	}
+}
@@ -0,0 +1,96 @@
+package idioms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/repository"
+)
+
+// transitions lists the statuses an idiom may move to from each status.
+var transitions = map[string][]string{
+	models.IdiomDraft:     {models.IdiomInReview, models.IdiomArchived},
+	models.IdiomInReview:  {models.IdiomDraft, models.IdiomPublished},
+	models.IdiomPublished: {models.IdiomArchived},
+	models.IdiomArchived:  {models.IdiomDraft},
+}
+
+func CanTransition(from string, to string) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+func isStatus(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// UpdateStatus moves an idiom to another status. Publishing with a future publishAt
+// keeps the idiom in review until PublishScheduled publishes it at that time.
+func (service *Service) UpdateStatus(idiomId string, input *models.UpdateIdiomStatusInput, ctx *context.Context) (*models.Idiom, error) {
+	if !isStatus(input.Status) {
+		return nil, lib.NewValidationError("Unknown status.", map[string]string{"status": input.Status})
+	}
+	if input.PublishAt != nil && input.Status != models.IdiomPublished {
+		return nil, lib.NewValidationError("publishAt is only allowed when publishing.", nil)
+	}
+	current, err := service.findStatus(*ctx, idiomId)
+	if err != nil {
+		return nil, err
+	}
+	if !CanTransition(current, input.Status) {
+		return nil, lib.NewConflictError(fmt.Sprintf("An idiom in %s can not move to %s.", current, input.Status), map[string]interface{}{
+			"id":      idiomId,
+			"status":  current,
+			"allowed": transitions[current],
+		})
+	}
+
+	var idiom *models.Idiom
+	if input.Status == models.IdiomPublished && input.PublishAt != nil && input.PublishAt.After(time.Now()) {
+		idiom, err = service.repository.ScheduleIdiom(*ctx, idiomId, current, *input.PublishAt)
+	} else {
+		idiom, err = service.repository.UpdateIdiomStatus(*ctx, idiomId, current, input.Status)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, lib.NewConflictError("The status of the idiom was changed at the same time.", map[string]string{"id": idiomId})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to update the status of the idiom.", idiomId, input.Status)
+		return nil, err
+	}
+	return idiom, nil
+}
+
+// PublishScheduled is the job handler publishing idioms in review whose publish_at has passed.
+func (service *Service) PublishScheduled(ctx context.Context, job *models.Job) error {
+	published, err := service.repository.PublishScheduledIdioms(ctx)
+	if err != nil {
+		service.logger.Error(err, "Failed to publish scheduled idioms.")
+		return err
+	}
+	if len(published) > 0 {
+		service.logger.Info("Published scheduled idioms.", published)
+	}
+	return nil
+}
+
+func (service *Service) findStatus(ctx context.Context, idiomId string) (string, error) {
+	status, err := service.repository.GetIdiomStatus(ctx, idiomId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", lib.NewNotFoundError("Idiom not found.", map[string]string{"id": idiomId})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to query the idiom with id.", idiomId)
+		return "", err
+	}
+	return status, nil
+}
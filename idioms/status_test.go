@@ -0,0 +1,80 @@
+package idioms
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+func TestCanTransition(t *testing.T) {
+	for _, test := range []struct {
+		from     string
+		to       string
+		expected bool
+	}{
+		{models.IdiomDraft, models.IdiomInReview, true},
+		{models.IdiomDraft, models.IdiomPublished, false},
+		{models.IdiomInReview, models.IdiomPublished, true},
+		{models.IdiomInReview, models.IdiomDraft, true},
+		{models.IdiomPublished, models.IdiomArchived, true},
+		{models.IdiomPublished, models.IdiomDraft, false},
+		{models.IdiomArchived, models.IdiomDraft, true},
+		{models.IdiomArchived, models.IdiomPublished, false},
+		{models.IdiomPublished, models.IdiomPublished, false},
+		{"deleted", models.IdiomDraft, false},
+	} {
+		if CanTransition(test.from, test.to) != test.expected {
+			t.Errorf("Expected %s to %s to be %t", test.from, test.to, test.expected)
+		}
+	}
+}
+
+func TestUpdateStatus(t *testing.T) {
+	idioms := numberedIdioms(5)
+	idioms[0].Status = models.IdiomInReview
+	idioms[0].PublishedAt = pgtype.Timestamp{}
+	idioms[1].Status = models.IdiomInReview
+	idioms[1].PublishedAt = pgtype.Timestamp{}
+	firstPublished := idioms[2].PublishedAt
+	service, _ := newMemoryService(idioms)
+	ctx := context.Background()
+
+	later := time.Now().Add(time.Hour)
+	scheduled, err := service.UpdateStatus(idioms[0].ID, &models.UpdateIdiomStatusInput{Status: models.IdiomPublished, PublishAt: &later}, &ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scheduled.Status != models.IdiomInReview || !scheduled.PublishAt.Valid {
+		t.Errorf("Expected a scheduled idiom to stay in review until %v, received %s at %v", later, scheduled.Status, scheduled.PublishAt)
+	}
+
+	published, err := service.UpdateStatus(idioms[1].ID, &models.UpdateIdiomStatusInput{Status: models.IdiomPublished}, &ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published.Status != models.IdiomPublished || !published.PublishedAt.Valid {
+		t.Errorf("Expected a published idiom with a publication date, received %s at %v", published.Status, published.PublishedAt)
+	}
+
+	// An idiom archived and published again keeps its first publication date.
+	for _, status := range []string{models.IdiomArchived, models.IdiomDraft, models.IdiomInReview, models.IdiomPublished} {
+		published, err = service.UpdateStatus(idioms[2].ID, &models.UpdateIdiomStatusInput{Status: status}, &ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if published.PublishedAt != firstPublished {
+		t.Errorf("Expected the publication date %v to be kept, received %v", firstPublished, published.PublishedAt)
+	}
+
+	_, err = service.UpdateStatus(idioms[3].ID, &models.UpdateIdiomStatusInput{Status: models.IdiomDraft}, &ctx)
+	var libError *lib.Error
+	if !errors.As(err, &libError) || libError.Code != lib.ErrorConflict {
+		t.Errorf("Expected a conflict moving a published idiom to draft, received %v", err)
+	}
+}
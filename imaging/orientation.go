@@ -0,0 +1,100 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"image"
+)
+
+// jpegOrientation reads the EXIF orientation tag of a JPEG, or returns 1 when it is missing.
+func jpegOrientation(body []byte) int {
+	if len(body) < 4 || body[0] != 0xFF || body[1] != 0xD8 {
+		return 1
+	}
+	offset := 2
+	for offset+4 <= len(body) {
+		if body[offset] != 0xFF {
+			return 1
+		}
+		marker := body[offset+1]
+		length := int(binary.BigEndian.Uint16(body[offset+2:]))
+		if marker == 0xDA || length < 2 || offset+2+length > len(body) {
+			return 1
+		}
+		segment := body[offset+4 : offset+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		offset += 2 + length
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for index := 0; index < entries; index++ {
+		entry := ifd + 2 + index*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+	return 1
+}
+
+// orient rotates and flips an image so it is displayed upright without its EXIF orientation.
+func orient(source image.Image, orientation int) image.Image {
+	if orientation == 1 {
+		return source
+	}
+	bounds := source.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if orientation >= 5 {
+		width, height = height, width
+	}
+	oriented := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sourceX, sourceY := x, y
+			switch orientation {
+			case 2:
+				sourceX = width - 1 - x
+			case 3:
+				sourceX, sourceY = width-1-x, height-1-y
+			case 4:
+				sourceY = height - 1 - y
+			case 5:
+				sourceX, sourceY = y, x
+			case 6:
+				sourceX, sourceY = y, width-1-x
+			case 7:
+				sourceX, sourceY = height-1-y, width-1-x
+			case 8:
+				sourceX, sourceY = height-1-y, x
+			}
+			oriented.Set(x, y, source.At(bounds.Min.X+sourceX, bounds.Min.Y+sourceY))
+		}
+	}
+	return oriented
+}
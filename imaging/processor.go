@@ -0,0 +1,158 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"sort"
+
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+	ErrTooLarge          = errors.New("image is too large")
+	ErrTooSmall          = errors.New("image is too small")
+)
+
+var DefaultWidths = []int{320, 640, 960, 1280}
+
+const (
+	DefaultQuality   = 82
+	DefaultMaxBytes  = 20 << 20
+	DefaultMaxPixels = 40_000_000
+	DefaultMinWidth  = 64
+)
+
+type Option struct {
+	Widths    []int
+	Quality   int
+	MaxBytes  int64
+	MaxPixels int
+	MinWidth  int
+}
+
+type Variant struct {
+	Width       int
+	Height      int
+	ContentType string
+	Extension   string
+	Body        []byte
+}
+
+type Processor struct {
+	widths    []int
+	quality   int
+	maxBytes  int64
+	maxPixels int
+	minWidth  int
+}
+
+func NewProcessor(option *Option) *Processor {
+	processor := new(Processor)
+	processor.widths = append([]int{}, option.Widths...)
+	if len(processor.widths) == 0 {
+		processor.widths = append(processor.widths, DefaultWidths...)
+	}
+	sort.Ints(processor.widths)
+	processor.quality = option.Quality
+	if processor.quality <= 0 || processor.quality > 100 {
+		processor.quality = DefaultQuality
+	}
+	processor.maxBytes = option.MaxBytes
+	if processor.maxBytes <= 0 {
+		processor.maxBytes = DefaultMaxBytes
+	}
+	processor.maxPixels = option.MaxPixels
+	if processor.maxPixels <= 0 {
+		processor.maxPixels = DefaultMaxPixels
+	}
+	processor.minWidth = option.MinWidth
+	if processor.minWidth <= 0 {
+		processor.minWidth = DefaultMinWidth
+	}
+
+	return processor
+}
+
+func (processor *Processor) MaxBytes() int64 {
+	return processor.maxBytes
+}
+
+// Process decodes a JPEG, PNG, GIF or WebP image and re-encodes it as a JPEG for
+// every configured width up to the width of the source. Re-encoding drops every
+// metadata block, so the EXIF orientation is applied to the pixels first.
+func (processor *Processor) Process(reader io.Reader) ([]Variant, error) {
+	body, err := io.ReadAll(io.LimitReader(reader, processor.maxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > processor.maxBytes {
+		return nil, ErrTooLarge
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if config.Width*config.Height > processor.maxPixels {
+		return nil, ErrTooLarge
+	}
+	if config.Width < processor.minWidth || config.Height < processor.minWidth {
+		return nil, ErrTooSmall
+	}
+	source, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if format == "jpeg" {
+		source = orient(source, jpegOrientation(body))
+	}
+
+	variants := []Variant{}
+	sourceWidth := source.Bounds().Dx()
+	for _, width := range processor.widths {
+		if width > sourceWidth {
+			width = sourceWidth
+		}
+		if len(variants) > 0 && variants[len(variants)-1].Width >= width {
+			break
+		}
+		variant, err := processor.encode(source, width)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, *variant)
+	}
+	return variants, nil
+}
+
+func (processor *Processor) encode(source image.Image, width int) (*Variant, error) {
+	bounds := source.Bounds()
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(canvas, canvas.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(canvas, canvas.Bounds(), source, bounds, draw.Over, nil)
+
+	buf := new(bytes.Buffer)
+	err := jpeg.Encode(buf, canvas, &jpeg.Options{Quality: processor.quality})
+	if err != nil {
+		return nil, err
+	}
+	return &Variant{
+		Width:       width,
+		Height:      height,
+		ContentType: "image/jpeg",
+		Extension:   "jpg",
+		Body:        buf.Bytes(),
+	}, nil
+}
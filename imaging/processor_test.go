@@ -0,0 +1,86 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func newImage(width int, height int) *image.RGBA {
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			canvas.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	return canvas
+}
+
+func TestProcess(t *testing.T) {
+	buf := new(bytes.Buffer)
+	png.Encode(buf, newImage(1000, 500))
+
+	variants, err := NewProcessor(&Option{}).Process(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][2]int{{320, 160}, {640, 320}, {960, 480}, {1000, 500}}
+	if len(variants) != len(expected) {
+		t.Fatalf("expected %d variants, got %d", len(expected), len(variants))
+	}
+	for index, variant := range variants {
+		decoded, format, err := image.Decode(bytes.NewReader(variant.Body))
+		if err != nil || format != "jpeg" {
+			t.Fatalf("expected a jpeg, got %s %v", format, err)
+		}
+		size := decoded.Bounds().Size()
+		if size.X != expected[index][0] || size.Y != expected[index][1] || variant.Width != size.X {
+			t.Errorf("expected %v, got %v", expected[index], size)
+		}
+	}
+}
+
+func TestProcessInvalid(t *testing.T) {
+	processor := NewProcessor(&Option{MaxBytes: 1 << 10})
+	_, err := processor.Process(strings.NewReader("not an image"))
+	if !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("expected unsupported format, got %v", err)
+	}
+	_, err = processor.Process(bytes.NewReader(make([]byte, 2<<10)))
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected too large, got %v", err)
+	}
+	buf := new(bytes.Buffer)
+	png.Encode(buf, newImage(32, 32))
+	_, err = processor.Process(buf)
+	if !errors.Is(err, ErrTooSmall) {
+		t.Errorf("expected too small, got %v", err)
+	}
+}
+
+func TestProcessOrientation(t *testing.T) {
+	buf := new(bytes.Buffer)
+	jpeg.Encode(buf, newImage(200, 100), nil)
+	encoded := buf.Bytes()
+
+	// An APP1 segment with a big endian EXIF block holding orientation 6.
+	exif := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01\x01\x12\x00\x03\x00\x00\x00\x01\x00\x06\x00\x00\x00\x00\x00\x00")
+	segment := append([]byte{0xFF, 0xE1, 0x00, byte(len(exif) + 2)}, exif...)
+	rotated := append(append(append([]byte{}, encoded[:2]...), segment...), encoded[2:]...)
+
+	if orientation := jpegOrientation(rotated); orientation != 6 {
+		t.Fatalf("expected orientation 6, got %d", orientation)
+	}
+	variants, err := NewProcessor(&Option{Widths: []int{64}}).Process(bytes.NewReader(rotated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variants[0].Width != 64 || variants[0].Height != 128 {
+		t.Errorf("expected a portrait variant, got %dx%d", variants[0].Width, variants[0].Height)
+	}
+}
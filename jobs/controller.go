@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type JobController interface {
+	GetJobs(writer http.ResponseWriter, request *http.Request)
+	RetryJob(writer http.ResponseWriter, request *http.Request)
+}
+
+type Controller struct {
+	queue JobQueue
+
+	logger logger.LoggerService
+}
+
+func NewController(queue JobQueue, logger logger.LoggerService) *Controller {
+	controller := new(Controller)
+	controller.queue = queue
+	controller.logger = logger
+
+	return controller
+}
+
+func (controller *Controller) GetJobs(writer http.ResponseWriter, request *http.Request) {
+	params := request.URL.Query()
+	status := params.Get("status")
+	switch status {
+	case "", models.JobPending, models.JobRunning, models.JobSucceeded, models.JobFailed, models.JobDead:
+		break
+	default:
+		lib.WriteError(writer, lib.NewValidationError("Unknown job status.", map[string]string{"status": status}))
+		return
+	}
+	count, err := strconv.Atoi(params.Get("count"))
+	if err != nil || count < 1 || count > 100 {
+		count = 20
+	}
+	jobs, err := controller.queue.GetJobs(request.Context(), status, count)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"jobs": jobs,
+	})
+}
+
+func (controller *Controller) RetryJob(writer http.ResponseWriter, request *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(request, "id"), 10, 64)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid job id.", nil))
+		return
+	}
+	job, err := controller.queue.RetryJob(request.Context(), id)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"job": job,
+	})
+}
@@ -0,0 +1,261 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type JobQueue interface {
+	Enqueue(ctx context.Context, input *models.EnqueueJobInput) (*models.Job, error)
+	EnqueueTx(ctx context.Context, tx *sqlx.Tx, input *models.EnqueueJobInput) (*models.Job, error)
+	Claim(ctx context.Context, kinds []string, workerId string) (*models.Job, error)
+	Complete(ctx context.Context, job *models.Job) error
+	Fail(ctx context.Context, job *models.Job, jobError error) error
+	GetJobs(ctx context.Context, status string, count int) ([]models.Job, error)
+	RetryJob(ctx context.Context, id int64) (*models.Job, error)
+}
+
+type Queue struct {
+	db     *sqlx.DB
+	logger logger.LoggerService
+
+	lease time.Duration
+}
+
+var jobColumns = []string{"id", "kind", "payload", "status", "dedupe_key", "attempts", "max_attempts", "run_at", "locked_at", "locked_by", "last_error", "created_at", "updated_at", "finished_at"}
+
+const defaultMaxAttempts = 5
+
+// succeededRetention is how long succeeded jobs are kept before Prune deletes them.
+const succeededRetention = time.Hour * 24 * 7
+
+const utcNow = "now() at time zone 'utc'"
+
+func NewQueue(db *sqlx.DB, logger logger.LoggerService) *Queue {
+	queue := new(Queue)
+	queue.db = db
+	queue.logger = logger
+	queue.lease = time.Minute * time.Duration(15)
+
+	return queue
+}
+
+func (queue *Queue) Enqueue(ctx context.Context, input *models.EnqueueJobInput) (*models.Job, error) {
+	return queue.enqueue(ctx, queue.db, input)
+}
+
+func (queue *Queue) EnqueueTx(ctx context.Context, tx *sqlx.Tx, input *models.EnqueueJobInput) (*models.Job, error) {
+	return queue.enqueue(ctx, tx, input)
+}
+
+func (queue *Queue) enqueue(ctx context.Context, executor sqlx.QueryerContext, input *models.EnqueueJobInput) (*models.Job, error) {
+	payload, err := json.Marshal(input.Payload)
+	if err != nil {
+		return nil, err
+	}
+	maxAttempts := input.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = defaultMaxAttempts
+	}
+	var dedupeKey any
+	if len(input.DedupeKey) > 0 {
+		dedupeKey = input.DedupeKey
+	}
+	var runAt any = sq.Expr(utcNow)
+	if input.RunAt != nil {
+		runAt = input.RunAt.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	query, args, err := sq.Insert("jobs").
+		Columns("kind", "payload", "dedupe_key", "max_attempts", "run_at").
+		Values(input.Kind, payload, dedupeKey, maxAttempts, runAt).
+		Suffix(fmt.Sprintf("on conflict (dedupe_key) where status in ('pending', 'running', 'failed') do nothing returning %s", strings.Join(jobColumns, ", "))).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		queue.logger.Error(err, "Failed to create a query to enqueue job.", input.Kind)
+		return nil, err
+	}
+	jobs := []models.Job{}
+	err = sqlx.SelectContext(ctx, executor, &jobs, query, args...)
+	if err != nil {
+		queue.logger.Error(err, "Failed to enqueue job.", input.Kind, input.DedupeKey)
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return &jobs[0], nil
+}
+
+func (queue *Queue) Claim(ctx context.Context, kinds []string, workerId string) (*models.Job, error) {
+	selectQuery, selectArgs, err := sq.Select("id").
+		From("jobs").
+		Where(sq.Eq{"kind": kinds}).
+		Where(sq.Or{
+			sq.And{
+				sq.Eq{"status": []string{models.JobPending, models.JobFailed}},
+				sq.Expr(fmt.Sprintf("run_at <= %s", utcNow)),
+			},
+			sq.And{
+				sq.Eq{"status": models.JobRunning},
+				sq.Expr(fmt.Sprintf("locked_at < %s - ?::interval", utcNow), fmt.Sprintf("%d seconds", int(queue.lease.Seconds()))),
+			},
+		}).
+		OrderBy("run_at asc").
+		Limit(1).
+		Suffix("for update skip locked").
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	query, args, err := sq.Update("jobs").
+		Set("status", models.JobRunning).
+		Set("attempts", sq.Expr("attempts + 1")).
+		Set("locked_at", sq.Expr(utcNow)).
+		Set("locked_by", workerId).
+		Set("updated_at", sq.Expr(utcNow)).
+		Where(fmt.Sprintf("id = (%s)", selectQuery), selectArgs...).
+		Suffix(fmt.Sprintf("returning %s", strings.Join(jobColumns, ", "))).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	jobs := []models.Job{}
+	err = queue.db.SelectContext(ctx, &jobs, query, args...)
+	if err != nil {
+		queue.logger.Error(err, "Failed to claim a job.", kinds)
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return &jobs[0], nil
+}
+
+func (queue *Queue) Complete(ctx context.Context, job *models.Job) error {
+	query, args, _ := sq.Update("jobs").
+		Set("status", models.JobSucceeded).
+		Set("locked_at", nil).
+		Set("locked_by", nil).
+		Set("last_error", nil).
+		Set("updated_at", sq.Expr(utcNow)).
+		Set("finished_at", sq.Expr(utcNow)).
+		Where("id = ?", job.ID).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	_, err := queue.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		queue.logger.Error(err, "Failed to complete job with id.", job.ID)
+	}
+	return err
+}
+
+func (queue *Queue) Fail(ctx context.Context, job *models.Job, jobError error) error {
+	builder := sq.Update("jobs").
+		Set("locked_at", nil).
+		Set("locked_by", nil).
+		Set("last_error", jobError.Error()).
+		Set("updated_at", sq.Expr(utcNow))
+	if job.Attempts >= job.MaxAttempts {
+		builder = builder.Set("status", models.JobDead).Set("finished_at", sq.Expr(utcNow))
+	} else {
+		runAt := time.Now().UTC().Add(Backoff(job.Attempts)).Format(time.RFC3339Nano)
+		builder = builder.Set("status", models.JobFailed).Set("run_at", runAt)
+	}
+	query, args, _ := builder.Where("id = ?", job.ID).PlaceholderFormat(sq.Dollar).ToSql()
+	_, err := queue.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		queue.logger.Error(err, "Failed to record failure of job with id.", job.ID)
+	}
+	return err
+}
+
+func (queue *Queue) GetJobs(ctx context.Context, status string, count int) ([]models.Job, error) {
+	jobs := []models.Job{}
+	builder := sq.Select(jobColumns...).From("jobs").OrderBy("updated_at desc").Limit(uint64(count))
+	if len(status) > 0 {
+		builder = builder.Where("status = ?", status)
+	}
+	query, args, err := builder.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		queue.logger.Error(err, "Failed to create a query.")
+		return nil, err
+	}
+	err = queue.db.SelectContext(ctx, &jobs, query, args...)
+	if err != nil {
+		queue.logger.Error(err, "Failed to query jobs with status.", status)
+		return nil, err
+	}
+	return jobs, nil
+}
+
+func (queue *Queue) RetryJob(ctx context.Context, id int64) (*models.Job, error) {
+	query, args, _ := sq.Update("jobs").
+		Set("status", models.JobPending).
+		Set("attempts", 0).
+		Set("run_at", sq.Expr(utcNow)).
+		Set("updated_at", sq.Expr(utcNow)).
+		Set("finished_at", nil).
+		Where("id = ?", id).
+		Where(sq.Eq{"status": []string{models.JobDead, models.JobFailed}}).
+		Suffix(fmt.Sprintf("returning %s", strings.Join(jobColumns, ", "))).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	jobs := []models.Job{}
+	err := queue.db.SelectContext(ctx, &jobs, query, args...)
+	if lib.IsUniqueViolation(err) {
+		return nil, lib.NewConflictError("Another job with the same key is already queued.", map[string]int64{"id": id})
+	}
+	if err != nil {
+		queue.logger.Error(err, "Failed to retry job with id.", id)
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, lib.NewNotFoundError("Failed or dead job not found.", map[string]int64{"id": id})
+	}
+	return &jobs[0], nil
+}
+
+// Prune is the job handler deleting the jobs which succeeded more than succeededRetention ago,
+// since the scheduler adds a row for every run of a periodic job. Dead jobs are kept to be retried.
+func (queue *Queue) Prune(ctx context.Context, job *models.Job) error {
+	query, args, _ := sq.Delete("jobs").
+		Where("status = ?", models.JobSucceeded).
+		Where(fmt.Sprintf("updated_at < %s - ?::interval", utcNow), fmt.Sprintf("%d seconds", int(succeededRetention.Seconds()))).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	result, err := queue.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		queue.logger.Error(err, "Failed to prune succeeded jobs.")
+		return err
+	}
+	if pruned, _ := result.RowsAffected(); pruned > 0 {
+		queue.logger.Info("Pruned succeeded jobs.", pruned)
+	}
+	return nil
+}
+
+// Backoff doubles the delay from 30 seconds for every attempt up to an hour, with 20% jitter.
+func Backoff(attempts int) time.Duration {
+	base := time.Second * time.Duration(30)
+	limit := time.Hour
+	delay := time.Duration(float64(base) * math.Pow(2, float64(attempts-1)))
+	if delay > limit || delay <= 0 {
+		delay = limit
+	}
+	jitter := (rand.Float64()*0.4 - 0.2) * float64(delay)
+	return delay + time.Duration(jitter)
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type schedule struct {
+	kind     string
+	interval time.Duration
+}
+
+// Scheduler enqueues periodic jobs. The kind is the dedupe key of the job, so an
+// instance enqueues nothing while another instance's job is still queued.
+type Scheduler struct {
+	queue     JobQueue
+	logger    logger.LoggerService
+	schedules []schedule
+}
+
+func NewScheduler(queue JobQueue, logger logger.LoggerService) *Scheduler {
+	scheduler := new(Scheduler)
+	scheduler.queue = queue
+	scheduler.logger = logger
+
+	return scheduler
+}
+
+func (scheduler *Scheduler) Every(kind string, interval time.Duration) *Scheduler {
+	scheduler.schedules = append(scheduler.schedules, schedule{kind: kind, interval: interval})
+	return scheduler
+}
+
+func (scheduler *Scheduler) Run(ctx context.Context) {
+	group := new(sync.WaitGroup)
+	for _, item := range scheduler.schedules {
+		group.Add(1)
+		go func(item schedule) {
+			defer group.Done()
+			scheduler.loop(ctx, item)
+		}(item)
+	}
+	group.Wait()
+}
+
+func (scheduler *Scheduler) loop(ctx context.Context, item schedule) {
+	ticker := time.NewTicker(item.interval)
+	defer ticker.Stop()
+	for {
+		_, err := scheduler.queue.Enqueue(ctx, &models.EnqueueJobInput{
+			Kind:      item.kind,
+			DedupeKey: item.kind,
+		})
+		if err != nil {
+			scheduler.logger.Error(err, "Failed to schedule job.", item.kind)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/logger"
+)
+
+func TestScheduler(t *testing.T) {
+	queue := &memoryQueue{failed: map[int64]string{}}
+	scheduler := NewScheduler(queue, logger.NewService(log.Default())).
+		Every("often", time.Millisecond*time.Duration(20)).
+		Every("rarely", time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(110))
+	defer cancel()
+	scheduler.Run(ctx)
+
+	counts := map[string]int{}
+	for _, input := range queue.enqueued {
+		if input.DedupeKey != input.Kind {
+			t.Errorf("Expected the dedupe key %s, received %s", input.Kind, input.DedupeKey)
+		}
+		counts[input.Kind]++
+	}
+	if counts["often"] < 3 {
+		t.Errorf("Expected the frequent job to be enqueued at least 3 times, received %d", counts["often"])
+	}
+	if counts["rarely"] != 1 {
+		t.Errorf("Expected the hourly job to be enqueued once at start, received %d", counts["rarely"])
+	}
+}
@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type Handler func(ctx context.Context, job *models.Job) error
+
+type WorkerOption struct {
+	Concurrency  int
+	PollInterval time.Duration
+	Timeout      time.Duration
+}
+
+type Worker struct {
+	queue    JobQueue
+	logger   logger.LoggerService
+	option   *WorkerOption
+	handlers map[string]Handler
+	id       string
+}
+
+func NewWorker(queue JobQueue, logger logger.LoggerService, option *WorkerOption) *Worker {
+	hostname, _ := os.Hostname()
+
+	worker := new(Worker)
+	worker.queue = queue
+	worker.logger = logger
+	worker.option = option
+	worker.handlers = map[string]Handler{}
+	worker.id = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	if worker.option.Concurrency < 1 {
+		worker.option.Concurrency = 1
+	}
+	if worker.option.PollInterval <= 0 {
+		worker.option.PollInterval = time.Second * time.Duration(10)
+	}
+	if worker.option.Timeout <= 0 {
+		worker.option.Timeout = time.Minute * time.Duration(10)
+	}
+
+	return worker
+}
+
+func (worker *Worker) Handle(kind string, handler Handler) *Worker {
+	worker.handlers[kind] = handler
+	return worker
+}
+
+func (worker *Worker) Run(ctx context.Context) {
+	kinds := []string{}
+	for kind := range worker.handlers {
+		kinds = append(kinds, kind)
+	}
+	group := new(sync.WaitGroup)
+	for index := 0; index < worker.option.Concurrency; index++ {
+		group.Add(1)
+		go func(workerId string) {
+			defer group.Done()
+			worker.loop(ctx, kinds, workerId)
+		}(fmt.Sprintf("%s-%d", worker.id, index))
+	}
+	group.Wait()
+}
+
+func (worker *Worker) loop(ctx context.Context, kinds []string, workerId string) {
+	for {
+		job, err := worker.queue.Claim(ctx, kinds, workerId)
+		if err == nil && job != nil {
+			worker.process(ctx, job)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(worker.option.PollInterval):
+		}
+	}
+}
+
+func (worker *Worker) process(ctx context.Context, job *models.Job) {
+	jobContext, cancel := context.WithTimeout(ctx, worker.option.Timeout)
+	defer cancel()
+
+	err := worker.run(jobContext, job)
+	if err != nil {
+		worker.logger.Error(err, "Failed to run job.", job.ID, job.Kind, job.Attempts)
+		worker.queue.Fail(ctx, job, err)
+		return
+	}
+	worker.queue.Complete(ctx, job)
+}
+
+func (worker *Worker) run(ctx context.Context, job *models.Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	handler, ok := worker.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("no handler for job kind %s", job.Kind)
+	}
+	return handler(ctx, job)
+}
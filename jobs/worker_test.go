@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type memoryQueue struct {
+	mutex     sync.Mutex
+	pending   []*models.Job
+	completed []int64
+	failed    map[int64]string
+	enqueued  []*models.EnqueueJobInput
+}
+
+func (queue *memoryQueue) Enqueue(ctx context.Context, input *models.EnqueueJobInput) (*models.Job, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.enqueued = append(queue.enqueued, input)
+	return nil, nil
+}
+
+func (queue *memoryQueue) EnqueueTx(ctx context.Context, tx *sqlx.Tx, input *models.EnqueueJobInput) (*models.Job, error) {
+	return nil, nil
+}
+
+func (queue *memoryQueue) Claim(ctx context.Context, kinds []string, workerId string) (*models.Job, error) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if len(queue.pending) == 0 {
+		return nil, nil
+	}
+	job := queue.pending[0]
+	queue.pending = queue.pending[1:]
+	job.Attempts++
+	return job, nil
+}
+
+func (queue *memoryQueue) Complete(ctx context.Context, job *models.Job) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.completed = append(queue.completed, job.ID)
+	return nil
+}
+
+func (queue *memoryQueue) Fail(ctx context.Context, job *models.Job, jobError error) error {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.failed[job.ID] = jobError.Error()
+	return nil
+}
+
+func (queue *memoryQueue) GetJobs(ctx context.Context, status string, count int) ([]models.Job, error) {
+	return nil, nil
+}
+
+func (queue *memoryQueue) RetryJob(ctx context.Context, id int64) (*models.Job, error) {
+	return nil, nil
+}
+
+func TestWorker(t *testing.T) {
+	queue := &memoryQueue{
+		pending: []*models.Job{
+			{ID: 1, Kind: "succeed", MaxAttempts: 5},
+			{ID: 2, Kind: "fail", MaxAttempts: 5},
+			{ID: 3, Kind: "panic", MaxAttempts: 5},
+			{ID: 4, Kind: "unknown", MaxAttempts: 5},
+		},
+		failed: map[int64]string{},
+	}
+	worker := NewWorker(queue, logger.NewService(log.Default()), &WorkerOption{Concurrency: 2, PollInterval: time.Millisecond * time.Duration(10)})
+	worker.Handle("succeed", func(ctx context.Context, job *models.Job) error {
+		return nil
+	})
+	worker.Handle("fail", func(ctx context.Context, job *models.Job) error {
+		return errors.New("model is overloaded")
+	})
+	worker.Handle("panic", func(ctx context.Context, job *models.Job) error {
+		panic("unexpected response")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*time.Duration(100))
+	defer cancel()
+	worker.Run(ctx)
+
+	if len(queue.completed) != 1 || queue.completed[0] != 1 {
+		t.Errorf("Expected job 1 to complete, received %v", queue.completed)
+	}
+	for _, id := range []int64{2, 3, 4} {
+		if _, ok := queue.failed[id]; !ok {
+			t.Errorf("Expected job %d to fail", id)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, expected := range map[int]time.Duration{1: time.Second * 30, 2: time.Minute, 5: time.Minute * 8, 20: time.Hour} {
+		delay := Backoff(attempts)
+		if delay < expected*8/10 || delay > expected*12/10 {
+			t.Errorf("Expected about %s for %d attempts, received %s", expected, attempts, delay)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package lib
+
+import (
+	"errors"
+	"net/http"
+)
+
+type ErrorCode string
+
+const (
+	ErrorNotFound     ErrorCode = "not_found"
+	ErrorValidation   ErrorCode = "validation_failed"
+	ErrorConflict     ErrorCode = "conflict"
+	ErrorUnauthorized ErrorCode = "unauthorized"
+	ErrorForbidden    ErrorCode = "forbidden"
+	ErrorUpstream     ErrorCode = "upstream_failed"
+	ErrorInternal     ErrorCode = "internal_error"
+)
+
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Details any
+	Err     error
+}
+
+func (err *Error) Error() string {
+	if err.Err != nil {
+		return err.Message + ": " + err.Err.Error()
+	}
+	return err.Message
+}
+
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+func (err *Error) Status() int {
+	switch err.Code {
+	case ErrorNotFound:
+		return http.StatusNotFound
+	case ErrorValidation:
+		return http.StatusBadRequest
+	case ErrorConflict:
+		return http.StatusConflict
+	case ErrorUnauthorized:
+		return http.StatusUnauthorized
+	case ErrorForbidden:
+		return http.StatusForbidden
+	case ErrorUpstream:
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func NewNotFoundError(message string, details any) *Error {
+	return &Error{Code: ErrorNotFound, Message: message, Details: details}
+}
+
+func NewValidationError(message string, details any) *Error {
+	return &Error{Code: ErrorValidation, Message: message, Details: details}
+}
+
+func NewConflictError(message string, details any) *Error {
+	return &Error{Code: ErrorConflict, Message: message, Details: details}
+}
+
+func NewUnauthorizedError(message string) *Error {
+	return &Error{Code: ErrorUnauthorized, Message: message}
+}
+
+func NewForbiddenError(message string, details any) *Error {
+	return &Error{Code: ErrorForbidden, Message: message, Details: details}
+}
+
+func NewUpstreamError(message string, err error) *Error {
+	return &Error{Code: ErrorUpstream, Message: message, Err: err}
+}
+
+func NewInternalError(message string, err error) *Error {
+	return &Error{Code: ErrorInternal, Message: message, Err: err}
+}
+
+func AsError(err error) *Error {
+	var appError *Error
+	if errors.As(err, &appError) {
+		return appError
+	}
+	return NewInternalError("Internal server error.", err)
+}
+
+func IsErrorCode(err error, code ErrorCode) bool {
+	var appError *Error
+	return errors.As(err, &appError) && appError.Code == code
+}
+
+func IsUniqueViolation(err error) bool {
+	var sqlError interface{ SQLState() string }
+	return errors.As(err, &sqlError) && sqlError.SQLState() == "23505"
+}
@@ -17,3 +17,8 @@ func ToIdiomID(idiom string) string {
 
 	return strings.Join(lowered, "-")
 }
+
+// ToTagID is the id of a tag or a category, the name in lowercase words joined by dashes.
+func ToTagID(name string) string {
+	return strings.Trim(ToIdiomID(strings.TrimSpace(name)), "-")
+}
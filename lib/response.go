@@ -0,0 +1,29 @@
+package lib
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type ErrorBody struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Details any       `json:"details"`
+}
+
+func WriteJSON(writer http.ResponseWriter, status int, body any) {
+	str, _ := json.Marshal(body)
+	writer.Header().Set("content-type", "application/json")
+	writer.WriteHeader(status)
+	writer.Write(str)
+}
+
+func WriteError(writer http.ResponseWriter, err error) {
+	appError := AsError(err)
+	body := ErrorBody{
+		Code:    appError.Code,
+		Message: appError.Message,
+		Details: appError.Details,
+	}
+	WriteJSON(writer, appError.Status(), map[string]ErrorBody{"error": body})
+}
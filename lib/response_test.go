@@ -0,0 +1,40 @@
+package lib
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteError(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   ErrorCode
+	}{
+		{NewNotFoundError("Idiom not found.", map[string]string{"id": "break-a-leg"}), http.StatusNotFound, ErrorNotFound},
+		{NewValidationError("Invalid JSON body.", nil), http.StatusBadRequest, ErrorValidation},
+		{NewConflictError("Idiom already exists.", nil), http.StatusConflict, ErrorConflict},
+		{NewUpstreamError("Failed to create examples.", errors.New("timeout")), http.StatusBadGateway, ErrorUpstream},
+		{errors.New("connection refused"), http.StatusInternalServerError, ErrorInternal},
+	}
+
+	for _, testCase := range cases {
+		recorder := httptest.NewRecorder()
+		WriteError(recorder, testCase.err)
+
+		if recorder.Code != testCase.status {
+			t.Errorf("Expected status %d, received %d", testCase.status, recorder.Code)
+		}
+		body := map[string]ErrorBody{}
+		err := json.Unmarshal(recorder.Body.Bytes(), &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if body["error"].Code != testCase.code {
+			t.Errorf("Expected code %s, received %s", testCase.code, body["error"].Code)
+		}
+	}
+}
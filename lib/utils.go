@@ -6,3 +6,10 @@ func IfNil[T any](value *T, fallback T) T {
 	}
 	return *value
 }
+
+func IfEmpty(value string, fallback string) string {
+	if len(value) == 0 {
+		return fallback
+	}
+	return value
+}
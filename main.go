@@ -5,18 +5,34 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
-	_ "github.com/jackc/pgx/stdlib"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
 
+	"github.com/nw.lee/idioms-backend/auth"
+	"github.com/nw.lee/idioms-backend/engine"
+	"github.com/nw.lee/idioms-backend/exports"
+	"github.com/nw.lee/idioms-backend/fetcher"
 	"github.com/nw.lee/idioms-backend/handler"
 	"github.com/nw.lee/idioms-backend/idioms"
+	"github.com/nw.lee/idioms-backend/imaging"
+	"github.com/nw.lee/idioms-backend/jobs"
+	"github.com/nw.lee/idioms-backend/lib"
 	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
 	"github.com/nw.lee/idioms-backend/openai"
+	"github.com/nw.lee/idioms-backend/orphans"
+	"github.com/nw.lee/idioms-backend/prompts"
+	"github.com/nw.lee/idioms-backend/repository"
+	"github.com/nw.lee/idioms-backend/revisions"
 	"github.com/nw.lee/idioms-backend/storage"
+	"github.com/nw.lee/idioms-backend/tags"
 	"github.com/nw.lee/idioms-backend/tasks"
 	"github.com/nw.lee/idioms-backend/thumbnail"
 )
@@ -33,7 +49,13 @@ func main() {
 	dbSslmode := os.Getenv("DB_SSLMODE")
 	dbSource := fmt.Sprintf("user=%s password=%s host=%s port=%s dbname=%s sslmode=%s", dbUser, dbPassword, dbHost, dbPort, dbName, dbSslmode)
 
-	conn, err := sqlx.Connect("pgx", dbSource)
+	// sqlx runs on the pgx v5 driver, which encodes and scans the pgtype types of the generated queries.
+	pool, err := pgxpool.New(context.Background(), dbSource)
+	if err != nil {
+		panic(err)
+	}
+	conn := sqlx.NewDb(stdlib.OpenDBFromPool(pool), "pgx")
+	err = conn.Ping()
 	if err != nil {
 		panic(err)
 	}
@@ -46,6 +68,22 @@ func main() {
 	}
 	loggerService := logger.NewService(log.Default())
 
+	migrator, err := engine.NewMigrator(conn, loggerService)
+	if err != nil {
+		panic(err)
+	}
+	// migrate runs before the other services, which expect the schema to exist.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		commands := &cli{migrator: migrator}
+		commands.exit(context.Background(), os.Args[1:])
+	}
+	if os.Getenv("MIGRATE_ON_START") == "true" {
+		_, err = migrator.Up(context.Background())
+		if err != nil {
+			panic(err)
+		}
+	}
+
 	aiKey := os.Getenv("OPENAI_API_KEY")
 	orgId := os.Getenv("OPENAI_ORG")
 	awsRoleArn := os.Getenv("AWS_ROLE_ARN")
@@ -57,27 +95,138 @@ func main() {
 	} else {
 		isAdmin = false
 	}
-	aiService := openai.NewOpenAi(aiKey, orgId, loggerService)
-	storageService := storage.NewService(&awsConfig, awsId, awsKey, awsRoleArn)
+	aiTimeout, _ := strconv.Atoi(os.Getenv("LLM_TIMEOUT"))
+	aiMaxRetries, err := strconv.Atoi(os.Getenv("LLM_MAX_RETRIES"))
+	if err != nil {
+		aiMaxRetries = openai.DefaultMaxRetries
+	}
+	aiRateLimit, _ := strconv.ParseFloat(os.Getenv("LLM_RATE_LIMIT"), 64)
+	aiService, err := openai.NewProvider(&openai.Config{
+		Provider:   os.Getenv("LLM_PROVIDER"),
+		BaseURL:    os.Getenv("LLM_BASE_URL"),
+		ApiKey:     aiKey,
+		OrgId:      orgId,
+		ChatModel:  os.Getenv("LLM_CHAT_MODEL"),
+		ImageModel: os.Getenv("LLM_IMAGE_MODEL"),
+		Timeout:    time.Second * time.Duration(aiTimeout),
+		MaxRetries: aiMaxRetries,
+		RateLimit:  aiRateLimit,
+
+		EmbeddingModel: os.Getenv("LLM_EMBEDDING_MODEL"),
+	}, loggerService)
+	if err != nil {
+		panic(err)
+	}
+	storageService, err := storage.NewStorage(&storage.Option{
+		Driver:        os.Getenv("STORAGE_DRIVER"),
+		Bucket:        lib.IfEmpty(os.Getenv("STORAGE_BUCKET"), "austin-idioms"),
+		PublicURL:     os.Getenv("STORAGE_PUBLIC_URL"),
+		LocalDir:      lib.IfEmpty(os.Getenv("STORAGE_LOCAL_DIR"), "./data/storage"),
+		SigningSecret: os.Getenv("STORAGE_SIGNING_SECRET"),
+		AwsConfig:     &awsConfig,
+		AwsId:         awsId,
+		AwsKey:        awsKey,
+		AwsRoleArn:    awsRoleArn,
+	})
+	if err != nil {
+		panic(err)
+	}
 
-	idiomService := idioms.NewService(conn, loggerService, aiService)
+	jobQueue := jobs.NewQueue(conn, loggerService)
+	promptService, err := prompts.NewService(conn, loggerService)
+	if err != nil {
+		panic(err)
+	}
+	revisionService := revisions.NewService(conn, loggerService)
+	idiomRepository := repository.NewRepository(conn)
+	idiomService := idioms.NewService(conn, idiomRepository, loggerService, aiService, jobQueue, promptService, revisionService)
 
 	thumbnailContext := context.Background()
 
-	thumbnailService := thumbnail.NewService(conn, loggerService, storageService, aiService, &thumbnailContext)
-	idiomController := idioms.NewController(idiomService, thumbnailService, loggerService)
+	thumbnailWidths := []int{}
+	for _, width := range strings.Split(os.Getenv("THUMBNAIL_WIDTHS"), ",") {
+		if parsed, err := strconv.Atoi(strings.TrimSpace(width)); err == nil && parsed > 0 {
+			thumbnailWidths = append(thumbnailWidths, parsed)
+		}
+	}
+	thumbnailQuality, _ := strconv.Atoi(os.Getenv("THUMBNAIL_QUALITY"))
+	imageProcessor := imaging.NewProcessor(&imaging.Option{
+		Widths:  thumbnailWidths,
+		Quality: thumbnailQuality,
+	})
+	fetchTimeout, _ := strconv.Atoi(os.Getenv("FETCH_TIMEOUT"))
+	fetchMaxBytes, _ := strconv.ParseInt(os.Getenv("FETCH_MAX_BYTES"), 10, 64)
+	fetchAllowedHosts := []string{}
+	for _, host := range strings.Split(os.Getenv("FETCH_ALLOWED_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); len(host) > 0 {
+			fetchAllowedHosts = append(fetchAllowedHosts, host)
+		}
+	}
+	if os.Getenv("LLM_PROVIDER") == openai.ProviderFake {
+		fetchAllowedHosts = append(fetchAllowedHosts, "127.0.0.1")
+	}
+	imageFetcher := fetcher.NewFetcher(&fetcher.Option{
+		Timeout:      time.Second * time.Duration(fetchTimeout),
+		MaxBytes:     fetchMaxBytes,
+		AllowedHosts: fetchAllowedHosts,
+	})
+	draftTTL, _ := strconv.Atoi(os.Getenv("THUMBNAIL_DRAFT_TTL"))
+	thumbnailService := thumbnail.NewService(conn, idiomRepository, loggerService, storageService, aiService, revisionService, imageProcessor, imageFetcher, time.Hour*time.Duration(draftTTL), &thumbnailContext)
+	paginator, err := idioms.NewPaginator(os.Getenv("CURSOR_SECRET"))
+	if err != nil {
+		panic(err)
+	}
+	idiomController := idioms.NewController(idiomService, thumbnailService, paginator, loggerService)
+
+	authService := auth.NewService(conn, loggerService, os.Getenv("JWT_SECRET"), os.Getenv("JWT_ISSUER"))
+	authController := auth.NewController(authService, loggerService)
+	authMiddleware := auth.NewMiddleware(authService)
+
+	jobController := jobs.NewController(jobQueue, loggerService)
+	promptController := prompts.NewController(promptService, loggerService)
+	revisionController := revisions.NewController(revisionService, loggerService)
+	orphanService := orphans.NewService(conn, loggerService, storageService)
+	orphanController := orphans.NewController(orphanService, loggerService)
+	exportService := exports.NewService(conn, loggerService, storageService)
+	tagService := tags.NewService(conn, loggerService)
+	tagController := tags.NewController(tagService, loggerService)
+	exportController := exports.NewController(exportService, loggerService)
+
+	if len(os.Args) > 1 {
+		commands := &cli{migrator: migrator, orphanService: orphanService, idiomService: idiomService, exportService: exportService, authService: authService}
+		commands.exit(context.Background(), os.Args[1:])
+	}
 
-	handler := handler.NewHandler(isAdmin).AddIdiomController(idiomController)
+	handler := handler.NewHandler().AddIdiomController(idiomController).AddAuth(authController, authMiddleware).AddJobController(jobController).AddPromptController(promptController).AddRevisionController(revisionController).AddOrphanController(orphanController).AddExportController(exportController).AddTagController(tagController).AddStorage(storageService)
 
 	if isAdmin {
-		idiomTask := tasks.NewIdiomTask(conn, loggerService, aiService)
-
-		go func() {
-			for {
-				idiomTask.CreateIdiomMeanings(time.Second * time.Duration(4))
-				time.Sleep(time.Minute * time.Duration(2))
-			}
-		}()
+		idiomTask := tasks.NewIdiomTask(conn, idiomRepository, loggerService, aiService, promptService, revisionService, tagService)
+		concurrency, _ := strconv.Atoi(os.Getenv("WORKER_CONCURRENCY"))
+		pollInterval, _ := strconv.Atoi(os.Getenv("WORKER_POLL_INTERVAL"))
+
+		worker := jobs.NewWorker(jobQueue, loggerService, &jobs.WorkerOption{
+			Concurrency:  concurrency,
+			PollInterval: time.Second * time.Duration(pollInterval),
+		})
+		worker.Handle(models.JobGenerateIdiom, idiomTask.GenerateIdiom)
+		worker.Handle(models.JobExpireThumbnailDrafts, thumbnailService.ExpireDrafts)
+
+		worker.Handle(models.JobReconcileStorage, orphanService.ReconcileJob)
+		worker.Handle(models.JobPublishScheduled, idiomService.PublishScheduled)
+		worker.Handle(models.JobEmbedIdioms, idiomService.EmbedIdioms)
+		worker.Handle(models.JobPruneJobs, jobQueue.Prune)
+
+		scheduler := jobs.NewScheduler(jobQueue, loggerService).
+			Every(models.JobExpireThumbnailDrafts, time.Hour).
+			Every(models.JobPublishScheduled, time.Minute).
+			Every(models.JobEmbedIdioms, time.Minute*5).
+			Every(models.JobPruneJobs, time.Hour)
+		if reconcileInterval, _ := strconv.Atoi(os.Getenv("STORAGE_GC_INTERVAL")); reconcileInterval > 0 {
+			scheduler.Every(models.JobReconcileStorage, time.Hour*time.Duration(reconcileInterval))
+		}
+
+		go worker.Run(context.Background())
+		go scheduler.Run(context.Background())
 	}
 
 	handler.Run()
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type ApiKey struct {
+	ID         string           `db:"id" json:"id"`
+	Name       string           `db:"name" json:"name"`
+	Prefix     string           `db:"prefix" json:"prefix"`
+	KeyHash    string           `db:"key_hash" json:"-"`
+	Scopes     TextArray        `db:"scopes" json:"scopes"`
+	CreatedBy  pgtype.Text      `db:"created_by" json:"createdBy"`
+	CreatedAt  pgtype.Timestamp `db:"created_at" json:"createdAt"`
+	LastUsedAt pgtype.Timestamp `db:"last_used_at" json:"lastUsedAt"`
+	RevokedAt  pgtype.Timestamp `db:"revoked_at" json:"revokedAt"`
+}
+
+type CreateApiKeyInput struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type CreatedApiKey struct {
+	ApiKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+)
+
+type IdiomExample struct {
+	ID         int64  `db:"id" json:"id"`
+	IdiomID    string `db:"idiom_id" json:"idiomId"`
+	Expression string `db:"expression" json:"expression"`
+	Position   int    `db:"position" json:"position"`
+}
+
+type CreateIdiomInput struct {
+	Idiom           string   `json:"idiom"`
+	MeaningBrief    string   `json:"meaningBrief"`
+	MeaningFull     string   `json:"meaningFull"`
+	Description     *string  `json:"description"`
+	ThumbnailPrompt *string  `json:"thumbnailPrompt"`
+	Examples        []string `json:"examples"`
+}
+
+func (input *CreateIdiomInput) Validate() []string {
+	problems := validateMeanings(input.Idiom, input.MeaningBrief, input.MeaningFull)
+	if input.Description != nil {
+		problems = append(problems, validateLength("description", *input.Description, 1, MaxDescriptionLength)...)
+	}
+	for index, example := range input.Examples {
+		problems = append(problems, validateLength(fmt.Sprintf("examples[%d]", index), example, 1, MaxExampleLength)...)
+	}
+	return problems
+}
+
+// PatchIdiomInput changes only the fields which are present.
+type PatchIdiomInput struct {
+	Idiom           *string `json:"idiom"`
+	MeaningBrief    *string `json:"meaningBrief"`
+	MeaningFull     *string `json:"meaningFull"`
+	Description     *string `json:"description"`
+	ThumbnailPrompt *string `json:"thumbnailPrompt"`
+}
+
+func (input *PatchIdiomInput) Validate() []string {
+	problems := []string{}
+	if input.Idiom == nil && input.MeaningBrief == nil && input.MeaningFull == nil && input.Description == nil && input.ThumbnailPrompt == nil {
+		problems = append(problems, "at least one field must be changed")
+	}
+	if input.Idiom != nil {
+		problems = append(problems, validateLength("idiom", *input.Idiom, 1, MaxIdiomLength)...)
+	}
+	if input.MeaningBrief != nil {
+		problems = append(problems, validateLength("meaningBrief", *input.MeaningBrief, 1, MaxMeaningBriefLength)...)
+	}
+	if input.MeaningFull != nil {
+		problems = append(problems, validateLength("meaningFull", *input.MeaningFull, 1, MaxMeaningFullLength)...)
+	}
+	if input.Description != nil {
+		problems = append(problems, validateLength("description", *input.Description, 1, MaxDescriptionLength)...)
+	}
+	return problems
+}
+
+type CreateExampleInput struct {
+	Expression string `json:"expression"`
+	Position   *int   `json:"position"`
+}
+
+func (input *CreateExampleInput) Validate() []string {
+	problems := validateLength("expression", input.Expression, 1, MaxExampleLength)
+	if input.Position != nil && *input.Position < 0 {
+		problems = append(problems, "position must not be negative")
+	}
+	return problems
+}
+
+type PatchExampleInput struct {
+	Expression *string `json:"expression"`
+	Position   *int    `json:"position"`
+}
+
+func (input *PatchExampleInput) Validate() []string {
+	problems := []string{}
+	if input.Expression == nil && input.Position == nil {
+		problems = append(problems, "at least one field must be changed")
+	}
+	if input.Expression != nil {
+		problems = append(problems, validateLength("expression", *input.Expression, 1, MaxExampleLength)...)
+	}
+	if input.Position != nil && *input.Position < 0 {
+		problems = append(problems, "position must not be negative")
+	}
+	return problems
+}
+
+type ReorderExamplesInput struct {
+	IDs []int64 `json:"ids"`
+}
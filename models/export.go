@@ -0,0 +1,22 @@
+package models
+
+import "github.com/jackc/pgx/v5/pgtype"
+
+const (
+	ExportNDJSON = "ndjson"
+	ExportCSV    = "csv"
+	ExportAnki   = "apkg"
+)
+
+type ExportedIdiom struct {
+	ID           string           `db:"id" json:"id"`
+	Idiom        string           `db:"idiom" json:"idiom"`
+	MeaningBrief string           `db:"meaning_brief" json:"meaningBrief"`
+	MeaningFull  string           `db:"meaning_full" json:"meaningFull"`
+	Description  pgtype.Text      `db:"description" json:"description"`
+	Examples     TextArray        `db:"examples" json:"examples"`
+	Thumbnail    pgtype.Text      `db:"thumbnail" json:"-"`
+	Thumbnails   TextArray        `db:"thumbnails" json:"-"`
+	ThumbnailURL string           `db:"-" json:"thumbnailUrl"`
+	PublishedAt  pgtype.Timestamp `db:"published_at" json:"publishedAt"`
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Content rules for generated idioms. Lengths are counted in letters and
+// are looser than the limits in the prompts, which models tend to overshoot.
+const (
+	GeneratedExampleCount = 10
+	MaxIdiomLength        = 200
+	MaxMeaningBriefLength = 600
+	MaxMeaningFullLength  = 6000
+	MaxExampleLength      = 600
+	MinDescriptionLength  = 200
+	MaxDescriptionLength  = 600
+)
+
+type GeneratedIdiom struct {
+	Idiom        string   `json:"idiom"`
+	MeaningBrief string   `json:"meaningBrief" description:"A short representation of the meaning."`
+	MeaningFull  string   `json:"meaningFull" description:"A long representation of the meaning, along with an exemplary situation."`
+	Description  string   `json:"description" description:"A specific situation explained with the idiom."`
+	Examples     []string `json:"examples" description:"10 example sentences using the idiom."`
+}
+
+type GeneratedExamples struct {
+	Idiom        string   `json:"idiom"`
+	MeaningBrief string   `json:"meaningBrief" description:"A short representation of the meaning."`
+	MeaningFull  string   `json:"meaningFull" description:"A long representation of the meaning, along with an exemplary situation."`
+	Examples     []string `json:"examples" description:"10 example sentences using the idiom."`
+}
+
+type GeneratedDescription struct {
+	Description string `json:"description" description:"A specific situation explained with the idiom."`
+}
+
+func (content *GeneratedIdiom) Validate() []string {
+	problems := validateMeanings(content.Idiom, content.MeaningBrief, content.MeaningFull)
+	problems = append(problems, validateDescription(content.Description)...)
+	return append(problems, validateExamples(content.Examples)...)
+}
+
+func (content *GeneratedExamples) Validate() []string {
+	problems := validateMeanings(content.Idiom, content.MeaningBrief, content.MeaningFull)
+	return append(problems, validateExamples(content.Examples)...)
+}
+
+func (content *GeneratedDescription) Validate() []string {
+	return validateDescription(content.Description)
+}
+
+func validateMeanings(idiom string, meaningBrief string, meaningFull string) []string {
+	problems := []string{}
+	problems = append(problems, validateLength("idiom", idiom, 1, MaxIdiomLength)...)
+	problems = append(problems, validateLength("meaningBrief", meaningBrief, 1, MaxMeaningBriefLength)...)
+	return append(problems, validateLength("meaningFull", meaningFull, 1, MaxMeaningFullLength)...)
+}
+
+func validateDescription(description string) []string {
+	return validateLength("description", description, MinDescriptionLength, MaxDescriptionLength)
+}
+
+func validateExamples(examples []string) []string {
+	problems := []string{}
+	if len(examples) != GeneratedExampleCount {
+		problems = append(problems, fmt.Sprintf("examples must have exactly %d sentences, not %d", GeneratedExampleCount, len(examples)))
+	}
+	for index, example := range examples {
+		problems = append(problems, validateLength(fmt.Sprintf("examples[%d]", index), example, 1, MaxExampleLength)...)
+	}
+	return problems
+}
+
+func validateLength(name string, value string, min int, max int) []string {
+	length := utf8.RuneCountInString(strings.TrimSpace(value))
+	if length == 0 {
+		return []string{fmt.Sprintf("%s must not be empty", name)}
+	}
+	if length < min {
+		return []string{fmt.Sprintf("%s must be at least %d letters, not %d", name, min, length)}
+	}
+	if length > max {
+		return []string{fmt.Sprintf("%s must be at most %d letters, not %d", name, max, length)}
+	}
+	return []string{}
+}
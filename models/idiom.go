@@ -1,57 +1,53 @@
 package models
 
 import (
+	"time"
+
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const (
+	IdiomDraft     = "draft"
+	IdiomInReview  = "in_review"
+	IdiomPublished = "published"
+	IdiomArchived  = "archived"
+)
+
 type Idiom struct {
-	ID           string           `db:"id" json:"id"`
-	Idiom        string           `db:"idiom" json:"idiom"`
-	MeaningBrief string           `db:"meaning_brief" json:"meaningBrief"`
-	MeaningFull  string           `db:"meaning_full" json:"meaningFull"`
-	CreatedAt    pgtype.Timestamp `db:"created_at" json:"createdAt"`
-	PublishedAt  pgtype.Timestamp `db:"published_at" json:"publishedAt"`
-	Thumbnail    pgtype.Text      `db:"thumbnail" json:"thumbnail"`
-	Thumbnails   TextArray        `db:"thumbnails" json:"thumbnails"`
-	Description  pgtype.Text      `db:"description" json:"description"`
-	NumID        int64            `db:"num_id" json:"numId"`
-	Examples     []string         `json:"examples"`
-}
+	ID            string           `db:"id" json:"id"`
+	Idiom         string           `db:"idiom" json:"idiom"`
+	MeaningBrief  string           `db:"meaning_brief" json:"meaningBrief"`
+	MeaningFull   string           `db:"meaning_full" json:"meaningFull"`
+	CreatedAt     pgtype.Timestamp `db:"created_at" json:"createdAt"`
+	PublishedAt   pgtype.Timestamp `db:"published_at" json:"publishedAt"`
+	Thumbnail     pgtype.Text      `db:"thumbnail" json:"thumbnail"`
+	Thumbnails    TextArray        `db:"thumbnails" json:"thumbnails"`
+	Description   pgtype.Text      `db:"description" json:"description"`
+	NumID         int64            `db:"num_id" json:"numId"`
+	PromptVersion pgtype.Text      `db:"prompt_version" json:"promptVersion"`
+	Status        string           `db:"status" json:"status"`
+	PublishAt     pgtype.Timestamp `db:"publish_at" json:"publishAt"`
+	Examples      []string         `json:"examples"`
+	Tags          []Tag            `json:"tags,omitempty"`
+	Categories    []Category       `json:"categories,omitempty"`
 
-type IdiomDB struct {
-	ID           string           `db:"id" json:"id"`
-	Idiom        string           `db:"idiom" json:"idiom"`
-	MeaningBrief string           `db:"meaning_brief" json:"meaningBrief"`
-	MeaningFull  string           `db:"meaning_full" json:"meaningFull"`
-	CreatedAt    pgtype.Timestamp `db:"created_at" json:"createdAt"`
-	PublishedAt  pgtype.Timestamp `db:"published_at" json:"publishedAt"`
-	Thumbnail    pgtype.Text      `db:"thumbnail" json:"thumbnail"`
-	Thumbnails   TextArray        `db:"thumbnails" json:"thumbnails"`
-	Description  pgtype.Text      `db:"description" json:"description"`
-	NumID        int64            `db:"num_id" json:"numId"`
-	Expression   string           `json:"expression" db:"expression"`
+	Rank       *float64        `json:"rank,omitempty"`
+	Highlights *IdiomHighlight `json:"highlights,omitempty"`
 }
 
-func (res *IdiomDB) ToIdiom() *Idiom {
-	idiom := &Idiom{
-		ID:           res.ID,
-		Idiom:        res.Idiom,
-		MeaningBrief: res.MeaningBrief,
-		MeaningFull:  res.MeaningFull,
-		CreatedAt:    res.CreatedAt,
-		PublishedAt:  res.PublishedAt,
-		Thumbnail:    res.Thumbnail,
-		Thumbnails:   res.Thumbnails,
-		Description:  res.Description,
-		NumID:        res.NumID,
-		Examples:     []string{},
-	}
-	return idiom
+type IdiomHighlight struct {
+	Idiom        string `json:"idiom"`
+	MeaningBrief string `json:"meaningBrief"`
+	MeaningFull  string `json:"meaningFull"`
+	Description  string `json:"description"`
+	Examples     string `json:"examples"`
 }
 
 type CursorToken struct {
-	Next     string `json:"next"`
-	Previous string `json:"previous"`
+	Next        string `json:"next"`
+	Previous    string `json:"previous"`
+	HasNext     bool   `json:"hasNext"`
+	HasPrevious bool   `json:"hasPrevious"`
 }
 
 type IdiomResponse struct {
@@ -99,3 +95,8 @@ type UpdateExamplesInput struct {
 	MeaningFull  string   `json:"meaningFull"`
 	Examples     []string `json:"examples"`
 }
+
+type UpdateIdiomStatusInput struct {
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publishAt"`
+}
@@ -0,0 +1,57 @@
+package models
+
+const (
+	ImportCSV    = "csv"
+	ImportNDJSON = "ndjson"
+)
+
+const (
+	ImportAccepted  = "accepted"
+	ImportDuplicate = "duplicate"
+	ImportInvalid   = "invalid"
+)
+
+type ImportRow struct {
+	Line     int      `json:"line"`
+	ID       string   `json:"id,omitempty"`
+	Idiom    string   `json:"idiom,omitempty"`
+	Status   string   `json:"status"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+type ImportReport struct {
+	DryRun     bool        `json:"dryRun"`
+	Accepted   int         `json:"accepted"`
+	Duplicates int         `json:"duplicates"`
+	Invalid    int         `json:"invalid"`
+	Rows       []ImportRow `json:"rows"`
+}
+
+// Count sets the totals from the statuses of the rows.
+func (report *ImportReport) Count() {
+	report.Accepted, report.Duplicates, report.Invalid = 0, 0, 0
+	for _, row := range report.Rows {
+		switch row.Status {
+		case ImportAccepted:
+			{
+				report.Accepted++
+			}
+		case ImportDuplicate:
+			{
+				report.Duplicates++
+			}
+		default:
+			{
+				report.Invalid++
+			}
+		}
+	}
+}
+
+func (input *IdiomInput) Validate() []string {
+	problems := validateLength("idiom", input.Idiom, 1, MaxIdiomLength)
+	if len(input.Meaning) > 0 {
+		problems = append(problems, validateLength("meaning", input.Meaning, 1, MaxMeaningFullLength)...)
+	}
+	return problems
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobDead      = "dead"
+)
+
+const (
+	JobGenerateIdiom         = "idiom.generate"
+	JobExpireThumbnailDrafts = "thumbnail.drafts.expire"
+	JobReconcileStorage      = "storage.reconcile"
+	JobPublishScheduled      = "idiom.publish_scheduled"
+	JobEmbedIdioms           = "idiom.embed"
+	JobPruneJobs             = "jobs.prune"
+)
+
+type Job struct {
+	ID          int64            `db:"id" json:"id"`
+	Kind        string           `db:"kind" json:"kind"`
+	Payload     json.RawMessage  `db:"payload" json:"payload"`
+	Status      string           `db:"status" json:"status"`
+	DedupeKey   pgtype.Text      `db:"dedupe_key" json:"dedupeKey"`
+	Attempts    int              `db:"attempts" json:"attempts"`
+	MaxAttempts int              `db:"max_attempts" json:"maxAttempts"`
+	RunAt       pgtype.Timestamp `db:"run_at" json:"runAt"`
+	LockedAt    pgtype.Timestamp `db:"locked_at" json:"lockedAt"`
+	LockedBy    pgtype.Text      `db:"locked_by" json:"lockedBy"`
+	LastError   pgtype.Text      `db:"last_error" json:"lastError"`
+	CreatedAt   pgtype.Timestamp `db:"created_at" json:"createdAt"`
+	UpdatedAt   pgtype.Timestamp `db:"updated_at" json:"updatedAt"`
+	FinishedAt  pgtype.Timestamp `db:"finished_at" json:"finishedAt"`
+}
+
+type EnqueueJobInput struct {
+	Kind        string
+	Payload     any
+	DedupeKey   string
+	MaxAttempts int
+	RunAt       *pgtype.Timestamp
+}
+
+type GenerateIdiomPayload struct {
+	InputID string `json:"inputId"`
+}
+
+type ReconcileStoragePayload struct {
+	DryRun     bool `json:"dryRun"`
+	GraceHours int  `json:"graceHours"`
+}
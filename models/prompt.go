@@ -0,0 +1,33 @@
+package models
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	PromptSourceEmbedded = "embedded"
+	PromptSourceDatabase = "database"
+)
+
+type PromptTemplate struct {
+	Name      string           `db:"name" json:"name"`
+	Version   int              `db:"version" json:"version"`
+	Body      string           `db:"body" json:"body"`
+	Active    bool             `db:"active" json:"active"`
+	Source    string           `db:"-" json:"source"`
+	CreatedBy pgtype.Text      `db:"created_by" json:"createdBy"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"createdAt"`
+}
+
+type CreatePromptTemplateInput struct {
+	Name string `json:"name"`
+	Body string `json:"body"`
+}
+
+type PreviewPromptInput struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	Idiom   string `json:"idiom"`
+	Meaning string `json:"meaning"`
+	Locale  string `json:"locale"`
+}
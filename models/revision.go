@@ -0,0 +1,44 @@
+package models
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	RevisionSourceAi    = "ai"
+	RevisionSourceAdmin = "admin"
+)
+
+var IdiomRevisionColumns = []string{"id", "idiom_id", "idiom", "meaning_brief", "meaning_full", "description", "examples", "thumbnail", "thumbnails", "prompt_version", "source", "author", "restored_from", "created_at"}
+
+type IdiomRevision struct {
+	ID            int64            `db:"id" json:"id"`
+	IdiomID       string           `db:"idiom_id" json:"idiomId"`
+	Idiom         string           `db:"idiom" json:"idiom"`
+	MeaningBrief  pgtype.Text      `db:"meaning_brief" json:"meaningBrief"`
+	MeaningFull   pgtype.Text      `db:"meaning_full" json:"meaningFull"`
+	Description   pgtype.Text      `db:"description" json:"description"`
+	Examples      TextArray        `db:"examples" json:"examples"`
+	Thumbnail     pgtype.Text      `db:"thumbnail" json:"thumbnail"`
+	Thumbnails    TextArray        `db:"thumbnails" json:"thumbnails"`
+	PromptVersion pgtype.Text      `db:"prompt_version" json:"promptVersion"`
+	Source        string           `db:"source" json:"source"`
+	Author        pgtype.Text      `db:"author" json:"author"`
+	RestoredFrom  pgtype.Int8      `db:"restored_from" json:"restoredFrom"`
+	CreatedAt     pgtype.Timestamp `db:"created_at" json:"createdAt"`
+}
+
+type RevisionChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type RevisionDiff struct {
+	IdiomID         string           `json:"idiomId"`
+	From            int64            `json:"from"`
+	To              int64            `json:"to"`
+	Changes         []RevisionChange `json:"changes"`
+	ExamplesAdded   []string         `json:"examplesAdded"`
+	ExamplesRemoved []string         `json:"examplesRemoved"`
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	TagSourceAi    = "ai"
+	TagSourceAdmin = "admin"
+)
+
+const (
+	MaxTagLength          = 40
+	MaxCategoryLength     = 80
+	MaxTagsPerIdiom       = 10
+	MaxGeneratedTagsCount = 5
+)
+
+var TagColumns = []string{"id", "name", "created_at"}
+
+var CategoryColumns = []string{"id", "name", "description", "created_at"}
+
+func SelectCategoryColumns(table string) []string {
+	columns := []string{}
+	for _, column := range CategoryColumns {
+		columns = append(columns, fmt.Sprintf("%s.%s", table, column))
+	}
+	return columns
+}
+
+type Tag struct {
+	ID        string           `db:"id" json:"id"`
+	Name      string           `db:"name" json:"name"`
+	CreatedAt pgtype.Timestamp `db:"created_at" json:"createdAt"`
+	Count     *int             `db:"count" json:"count,omitempty"`
+}
+
+type Category struct {
+	ID          string           `db:"id" json:"id"`
+	Name        string           `db:"name" json:"name"`
+	Description pgtype.Text      `db:"description" json:"description"`
+	CreatedAt   pgtype.Timestamp `db:"created_at" json:"createdAt"`
+	Count       *int             `db:"count" json:"count,omitempty"`
+}
+
+type TagInput struct {
+	Name string `json:"name"`
+}
+
+func (input *TagInput) Validate() []string {
+	return validateLength("name", input.Name, 1, MaxTagLength)
+}
+
+type CategoryInput struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+}
+
+func (input *CategoryInput) Validate() []string {
+	problems := validateLength("name", input.Name, 1, MaxCategoryLength)
+	if input.Description != nil && len(*input.Description) > 0 {
+		problems = append(problems, validateLength("description", *input.Description, 1, MaxDescriptionLength)...)
+	}
+	return problems
+}
+
+// IdiomTagsInput replaces the tags of an idiom. Tags which do not exist yet are created.
+type IdiomTagsInput struct {
+	Tags []string `json:"tags"`
+}
+
+func (input *IdiomTagsInput) Validate() []string {
+	problems := []string{}
+	if len(input.Tags) > MaxTagsPerIdiom {
+		problems = append(problems, fmt.Sprintf("tags must have at most %d names, not %d", MaxTagsPerIdiom, len(input.Tags)))
+	}
+	for index, tag := range input.Tags {
+		problems = append(problems, validateLength(fmt.Sprintf("tags[%d]", index), tag, 1, MaxTagLength)...)
+	}
+	return problems
+}
+
+// IdiomCategoriesInput replaces the categories of an idiom with existing categories.
+type IdiomCategoriesInput struct {
+	Categories []string `json:"categories"`
+}
+
+type GeneratedTags struct {
+	Tags []string `json:"tags" description:"1 to 5 short lowercase topics of the idiom, such as business or money. Reuse the existing tags when they fit."`
+}
+
+func (content *GeneratedTags) Validate() []string {
+	problems := []string{}
+	if len(content.Tags) == 0 || len(content.Tags) > MaxGeneratedTagsCount {
+		problems = append(problems, fmt.Sprintf("tags must have 1 to %d topics, not %d", MaxGeneratedTagsCount, len(content.Tags)))
+	}
+	for index, tag := range content.Tags {
+		problems = append(problems, validateLength(fmt.Sprintf("tags[%d]", index), tag, 1, MaxTagLength)...)
+	}
+	return problems
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ThumbnailDraftColumns = []string{"id", "idiom_id", "prompt", "model", "storage_key", "content_type", "created_by", "created_at", "expires_at", "promoted_at", "promoted_by"}
+
+type ThumbnailDraft struct {
+	ID          int64            `db:"id" json:"id"`
+	IdiomID     string           `db:"idiom_id" json:"idiomId"`
+	Prompt      string           `db:"prompt" json:"prompt"`
+	Model       string           `db:"model" json:"model"`
+	StorageKey  string           `db:"storage_key" json:"storageKey"`
+	ContentType string           `db:"content_type" json:"contentType"`
+	URL         string           `db:"-" json:"url"`
+	CreatedBy   pgtype.Text      `db:"created_by" json:"createdBy"`
+	CreatedAt   pgtype.Timestamp `db:"created_at" json:"createdAt"`
+	ExpiresAt   pgtype.Timestamp `db:"expires_at" json:"expiresAt"`
+	PromotedAt  pgtype.Timestamp `db:"promoted_at" json:"promotedAt"`
+	PromotedBy  pgtype.Text      `db:"promoted_by" json:"promotedBy"`
+}
+
+type ReconcileThumbnailsInput struct {
+	DryRun     *bool  `json:"dryRun"`
+	GraceHours int    `json:"graceHours"`
+	Prefix     string `json:"prefix"`
+}
+
+type CreateThumbnailUploadInput struct {
+	ContentType   string `json:"contentType"`
+	ContentLength int64  `json:"contentLength"`
+}
+
+type ConfirmThumbnailUploadInput struct {
+	Key string `json:"key"`
+}
+
+type ThumbnailUpload struct {
+	Key       string            `json:"key"`
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
@@ -0,0 +1,82 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ApiError struct {
+	StatusCode int    `json:"-"`
+	Type       string `json:"type"`
+	Code       string `json:"code"`
+	Param      string `json:"param"`
+	Message    string `json:"message"`
+
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (err *ApiError) Error() string {
+	if len(err.Code) > 0 {
+		return fmt.Sprintf("openai: %d %s (%s): %s", err.StatusCode, err.Type, err.Code, err.Message)
+	}
+	return fmt.Sprintf("openai: %d %s: %s", err.StatusCode, err.Type, err.Message)
+}
+
+// Retryable reports whether the request may succeed when sent again.
+// An exhausted quota is reported with 429 as well but never recovers by retrying.
+func (err *ApiError) Retryable() bool {
+	if err.Code == "insufficient_quota" {
+		return false
+	}
+	return err.StatusCode == http.StatusTooManyRequests ||
+		err.StatusCode == http.StatusRequestTimeout ||
+		err.StatusCode == http.StatusConflict ||
+		err.StatusCode >= http.StatusInternalServerError
+}
+
+func (err *ApiError) RateLimited() bool {
+	return err.StatusCode == http.StatusTooManyRequests
+}
+
+func toApiError(resp *http.Response, body []byte) *ApiError {
+	envelope := struct {
+		Error *ApiError `json:"error"`
+	}{}
+	apiError := new(ApiError)
+	if json.Unmarshal(body, &envelope) == nil && envelope.Error != nil {
+		apiError = envelope.Error
+	} else {
+		apiError.Message = string(body)
+	}
+	if len(apiError.Type) == 0 {
+		apiError.Type = http.StatusText(resp.StatusCode)
+	}
+	apiError.StatusCode = resp.StatusCode
+	apiError.RetryAfter = retryAfter(resp.Header)
+	return apiError
+}
+
+func retryAfter(header http.Header) time.Duration {
+	if value := header.Get("retry-after-ms"); len(value) > 0 {
+		milliseconds, err := strconv.ParseFloat(value, 64)
+		if err == nil && milliseconds > 0 {
+			return time.Duration(milliseconds * float64(time.Millisecond))
+		}
+	}
+	value := header.Get("retry-after")
+	if len(value) == 0 {
+		return 0
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	date, err := http.ParseTime(value)
+	if err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
@@ -0,0 +1,224 @@
+package openai
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FakeServer answers the OpenAI chat completion, image generation and embedding endpoints
+// with deterministic content derived from the request, for tests and offline development.
+type FakeServer struct {
+	URL string
+
+	listener net.Listener
+	server   *http.Server
+}
+
+func NewFakeServer() (*FakeServer, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	fake := new(FakeServer)
+	fake.listener = listener
+	fake.URL = fmt.Sprintf("http://%s/v1", listener.Addr().String())
+	fake.server = &http.Server{Handler: fake}
+
+	go fake.server.Serve(listener)
+	return fake, nil
+}
+
+func (fake *FakeServer) Close() error {
+	return fake.server.Close()
+}
+
+func (fake *FakeServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch {
+	case request.Method == http.MethodPost && request.URL.Path == "/v1/chat/completions":
+		{
+			fake.textCompletion(writer, request)
+		}
+	case request.Method == http.MethodPost && request.URL.Path == "/v1/images/generations":
+		{
+			fake.imageGeneration(writer, request)
+		}
+	case request.Method == http.MethodPost && request.URL.Path == "/v1/embeddings":
+		{
+			fake.embeddings(writer, request)
+		}
+	case request.Method == http.MethodGet && strings.HasPrefix(request.URL.Path, "/images/"):
+		{
+			fake.image(writer, request)
+		}
+	default:
+		{
+			http.NotFound(writer, request)
+		}
+	}
+}
+
+func (fake *FakeServer) textCompletion(writer http.ResponseWriter, request *http.Request) {
+	args := new(TextCompletionArgs)
+	err := json.NewDecoder(request.Body).Decode(args)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fields := FakeContent(args.Messages)
+	if args.ResponseFormat != nil && args.ResponseFormat.JsonSchema != nil {
+		properties, _ := args.ResponseFormat.JsonSchema.Schema["properties"].(map[string]interface{})
+		for key := range fields {
+			if _, ok := properties[key]; !ok {
+				delete(fields, key)
+			}
+		}
+	}
+	content, _ := json.Marshal(fields)
+
+	response := map[string]interface{}{
+		"model": args.Model,
+		"choices": []map[string]interface{}{
+			{
+				"index":         0,
+				"finish_reason": "stop",
+				"message": map[string]string{
+					"role":    "assistant",
+					"content": string(content),
+				},
+			},
+		},
+	}
+	writer.Header().Set("content-type", "application/json")
+	json.NewEncoder(writer).Encode(response)
+}
+
+func (fake *FakeServer) imageGeneration(writer http.ResponseWriter, request *http.Request) {
+	body := new(ImageBody)
+	err := json.NewDecoder(request.Body).Decode(body)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sum := sha256.Sum256([]byte(body.Prompt))
+	imageURL := fmt.Sprintf("%s/images/%s.png", strings.TrimSuffix(fake.URL, "/v1"), hex.EncodeToString(sum[:8]))
+
+	response := ImageResponse{CreatedAt: time.Now().Unix()}
+	response.Data = append(response.Data, struct {
+		URL string `json:"url"`
+	}{URL: imageURL})
+	writer.Header().Set("content-type", "application/json")
+	json.NewEncoder(writer).Encode(response)
+}
+
+func (fake *FakeServer) embeddings(writer http.ResponseWriter, request *http.Request) {
+	body := new(EmbeddingBody)
+	err := json.NewDecoder(request.Body).Decode(body)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	response := map[string]interface{}{"model": body.Model}
+	data := []map[string]interface{}{}
+	for index, input := range body.Input {
+		data = append(data, map[string]interface{}{
+			"index":     index,
+			"embedding": FakeEmbedding(input),
+		})
+	}
+	response["data"] = data
+	writer.Header().Set("content-type", "application/json")
+	json.NewEncoder(writer).Encode(response)
+}
+
+func (fake *FakeServer) image(writer http.ResponseWriter, request *http.Request) {
+	seed := []byte(strings.TrimSuffix(strings.TrimPrefix(request.URL.Path, "/images/"), ".png"))
+	sum := sha256.Sum256(seed)
+	size := 1024
+	canvas := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			canvas.Set(x, y, color.RGBA{
+				R: sum[0] + uint8(x*255/size),
+				G: sum[1] + uint8(y*255/size),
+				B: sum[2],
+				A: 255,
+			})
+		}
+	}
+	writer.Header().Set("content-type", "image/png")
+	png.Encode(writer, canvas)
+}
+
+// FakeEmbedding hashes the words of the input into a unit vector, so texts sharing
+// more words have a higher cosine similarity.
+func FakeEmbedding(input string) []float32 {
+	embedding := make([]float32, EmbeddingDimensions)
+	words := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		sum := sha256.Sum256([]byte(word))
+		embedding[binary.BigEndian.Uint32(sum[:4])%EmbeddingDimensions]++
+	}
+	norm := 0.0
+	for _, value := range embedding {
+		norm += float64(value * value)
+	}
+	if norm == 0 {
+		embedding[0] = 1
+		return embedding
+	}
+	for index := range embedding {
+		embedding[index] /= float32(math.Sqrt(norm))
+	}
+	return embedding
+}
+
+// FakeContent builds a response containing every field our prompts ask for,
+// using the idiom given to the model in the messages.
+func FakeContent(messages []TextCompletionMessage) map[string]interface{} {
+	idiom := "A piece of cake"
+	meaning := "something that is very easy to do"
+	for index := len(messages) - 1; index >= 0; index-- {
+		content := messages[index].Content
+		start := strings.Index(content, "{")
+		end := strings.LastIndex(content, "}")
+		if start < 0 || end < start {
+			continue
+		}
+		subject := map[string]string{}
+		if json.Unmarshal([]byte(content[start:end+1]), &subject) != nil || len(subject["idiom"]) == 0 {
+			continue
+		}
+		idiom = subject["idiom"]
+		if len(subject["meaning"]) > 0 {
+			meaning = subject["meaning"]
+		}
+		break
+	}
+
+	examples := []string{}
+	for index := 1; index <= 10; index++ {
+		examples = append(examples, fmt.Sprintf("Example %d shows how to say \"%s\" when something is %s.", index, idiom, meaning))
+	}
+	return map[string]interface{}{
+		"idiom":        idiom,
+		"meaningBrief": fmt.Sprintf("\"%s\" describes %s.", idiom, meaning),
+		"meaningFull":  fmt.Sprintf("People use \"%s\" to describe %s. It is common in both casual and business conversations.", idiom, meaning),
+		"description":  fmt.Sprintf("Imagine a coworker who finishes a difficult report two days before the deadline. Everyone in the office expected the work to take the whole week. When the manager asks how it went, the coworker smiles and says \"%s\". The phrase fits because the task turned out to be %s.", idiom, meaning),
+		"examples":     examples,
+		"tags":         []string{"conversation", "everyday"},
+	}
+}
@@ -0,0 +1,94 @@
+package openai
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+func TestFakeProvider(t *testing.T) {
+	fake, err := NewFakeServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fake.Close()
+
+	ai := NewOpenAi(&Config{BaseURL: fake.URL, ApiKey: "fake"}, logger.NewService(log.Default()))
+
+	textArgs := new(TextCompletionArgs)
+	textArgs.AddMessage("system", "Response should be json format to {\"idiom\": \"A Idiom\", \"examples\": []}")
+	textArgs.AddMessage("user", "Create me 10 example sentences with {\"idiom\":\"Break the ice\",\"meaning\":\"to start a conversation\"}")
+	idiom := new(models.GeneratedIdiom)
+	err = CompleteJSON(context.Background(), ai, "idiom", textArgs, idiom, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idiom.Idiom != "Break the ice" || len(idiom.Examples) != models.GeneratedExampleCount {
+		t.Errorf("unexpected idiom %+v", idiom)
+	}
+	description := new(models.GeneratedDescription)
+	err = CompleteJSON(context.Background(), ai, "description", new(TextCompletionArgs).AddMessage("assistant", `{"idiom":"Break the ice"}`), description, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := ai.TextCompletion(context.Background(), textArgs)
+	second, _ := ai.TextCompletion(context.Background(), textArgs)
+	if first == nil || second == nil || *first != *second {
+		t.Errorf("expected deterministic content")
+	}
+
+	imageURL, err := ai.Image(context.Background(), "A cartoon of people breaking the ice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get(*imageURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || http.DetectContentType(body) != "image/png" {
+		t.Errorf("unexpected image response %d %s", resp.StatusCode, http.DetectContentType(body))
+	}
+}
+
+func TestFakeEmbedding(t *testing.T) {
+	fake, err := NewFakeServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fake.Close()
+
+	ai := NewOpenAi(&Config{BaseURL: fake.URL, ApiKey: "fake", EmbeddingModel: "fake-embedding"}, logger.NewService(log.Default()))
+	embeddings, err := ai.Embedding(context.Background(), []string{
+		"A piece of cake. Something very easy to do.",
+		"Easy as pie. Something very easy to do.",
+		"Break the ice. To start a conversation.",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(embeddings) != 3 || len(embeddings[0]) != EmbeddingDimensions {
+		t.Fatalf("unexpected embeddings %d", len(embeddings))
+	}
+	similarity := func(left []float32, right []float32) float32 {
+		sum := float32(0)
+		for index := range left {
+			sum += left[index] * right[index]
+		}
+		return sum
+	}
+	if similarity(embeddings[0], embeddings[1]) <= similarity(embeddings[0], embeddings[2]) {
+		t.Errorf("expected texts sharing words to be more similar")
+	}
+	again, _ := ai.Embedding(context.Background(), []string{"Break the ice. To start a conversation."})
+	if len(again) != 1 || similarity(again[0], embeddings[2]) < 0.999 {
+		t.Errorf("expected deterministic embeddings")
+	}
+}
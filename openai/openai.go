@@ -2,22 +2,49 @@ package openai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
+	"github.com/nw.lee/idioms-backend/lib"
 	"github.com/nw.lee/idioms-backend/logger"
+	"golang.org/x/time/rate"
 )
 
 type OpenAi struct {
-	apiKey string
-	orgId  string
-	logger logger.LoggerService
+	apiKey         string
+	orgId          string
+	baseURL        string
+	chatModel      string
+	imageModel     string
+	embeddingModel string
+	timeout        time.Duration
+	maxRetries     int
+	client         *http.Client
+	limiter        *rate.Limiter
+	logger         logger.LoggerService
 }
 
+const (
+	retryBase     = time.Millisecond * 500
+	retryLimit    = time.Second * 20
+	maxRetryAfter = time.Minute
+)
+
 type OpenAiInterface interface {
-	TextCompletion(args *TextCompletionArgs) (*string, error)
-	Image(prompt string) (*string, error)
+	TextCompletion(ctx context.Context, args *TextCompletionArgs) (*string, error)
+	Image(ctx context.Context, prompt string) (*string, error)
+	ImageModel() string
+	Embedding(ctx context.Context, input []string) ([][]float32, error)
+	EmbeddingModel() string
 }
 
 type TextCompletionMessage struct {
@@ -30,9 +57,7 @@ type TextCompletionArgs struct {
 	Temperature    float64                 `json:"temperature"`
 	MaxTokens      int                     `json:"max_tokens"`
 	Messages       []TextCompletionMessage `json:"messages"`
-	ResponseFormat struct {
-		Type string `json:"type"`
-	} `json:"response_format"`
+	ResponseFormat *ResponseFormat         `json:"response_format,omitempty"`
 }
 
 type TextCompletionResponse struct {
@@ -47,9 +72,9 @@ type TextCompletionResponse struct {
 type ImageBody struct {
 	Prompt         string `json:"prompt"`
 	Model          string `json:"model"`
-	Quality        string `json:"quality"`
+	Quality        string `json:"quality,omitempty"`
 	ResponseFormat string `json:"response_format"`
-	Style          string `json:"style"`
+	Style          string `json:"style,omitempty"`
 }
 
 type ImageResponse struct {
@@ -59,6 +84,19 @@ type ImageResponse struct {
 	} `json:"data"`
 }
 
+type EmbeddingBody struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+type EmbeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
 func (args *TextCompletionArgs) AddMessage(role string, content string) *TextCompletionArgs {
 	if args.Messages == nil {
 		args.Messages = []TextCompletionMessage{}
@@ -68,91 +106,208 @@ func (args *TextCompletionArgs) AddMessage(role string, content string) *TextCom
 	return args
 }
 
-func NewOpenAi(apiKey string, orgId string, logger logger.LoggerService) *OpenAi {
+func NewOpenAi(config *Config, logger logger.LoggerService) *OpenAi {
 	openAi := new(OpenAi)
-	openAi.apiKey = apiKey
-	openAi.orgId = orgId
+	openAi.apiKey = config.ApiKey
+	openAi.orgId = config.OrgId
+	openAi.baseURL = strings.TrimRight(lib.IfEmpty(config.BaseURL, DefaultBaseURL), "/")
+	openAi.chatModel = lib.IfEmpty(config.ChatModel, DefaultChatModel)
+	openAi.imageModel = lib.IfEmpty(config.ImageModel, DefaultImageModel)
+	openAi.embeddingModel = lib.IfEmpty(config.EmbeddingModel, DefaultEmbeddingModel)
+	openAi.timeout = config.Timeout
+	if openAi.timeout <= 0 {
+		openAi.timeout = DefaultTimeout
+	}
+	openAi.maxRetries = config.MaxRetries
+	if openAi.maxRetries < 0 {
+		openAi.maxRetries = 0
+	}
+	openAi.limiter = rate.NewLimiter(rate.Inf, 1)
+	if config.RateLimit > 0 {
+		openAi.limiter = rate.NewLimiter(rate.Limit(config.RateLimit/60), 1)
+	}
+	openAi.client = new(http.Client)
 	openAi.logger = logger
 
 	return openAi
 }
 
-func (openAi *OpenAi) TextCompletion(args *TextCompletionArgs) (*string, error) {
-	url := "https://api.openai.com/v1/chat/completions"
-
-	buf, err := json.Marshal(args)
-	if err != nil {
-		openAi.logger.Error(err, "Invalid arguments.")
-		return nil, err
+func (openAi *OpenAi) TextCompletion(ctx context.Context, args *TextCompletionArgs) (*string, error) {
+	if len(args.Model) == 0 {
+		args.Model = openAi.chatModel
 	}
-	body := bytes.NewBuffer(buf)
-	token := fmt.Sprintf("Bearer %s", openAi.apiKey)
-
-	req, _ := http.NewRequest(http.MethodPost, url, body)
-	req.Header.Add("content-type", "application/json")
-	req.Header.Add("authorization", token)
-	req.Header.Add("OpenAI-Organization", openAi.orgId)
-	client := new(http.Client)
-	resp, err := client.Do(req)
-
+	response := new(TextCompletionResponse)
+	err := openAi.post(ctx, "/chat/completions", args, response)
 	if err != nil {
 		openAi.logger.Error(err, "Failed to run text completion.")
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	response := new(TextCompletionResponse)
-	err = json.NewDecoder(resp.Body).Decode(response)
-	if err != nil || len(response.Choices) == 0 {
-		openAi.logger.Error(err, "Failed to decode response.")
-		return nil, err
+	if len(response.Choices) == 0 {
+		return nil, errors.New("openai: text completion returned no choices")
 	}
 	content := response.Choices[0].Message.Content
 	return &content, nil
 }
 
-func (openAi *OpenAi) Image(prompt string) (*string, error) {
-	url := "https://api.openai.com/v1/images/generations"
+func (openAi *OpenAi) ImageModel() string {
+	return openAi.imageModel
+}
+
+func (openAi *OpenAi) Image(ctx context.Context, prompt string) (*string, error) {
 	message := fmt.Sprintf("Here are the instructions you must follow. \n%s", prompt)
 
 	data := &ImageBody{
 		Prompt:         message,
-		Model:          "dall-e-3",
-		Quality:        "hd",
+		Model:          openAi.imageModel,
 		ResponseFormat: "url",
-		Style:          "vivid",
+	}
+	if openAi.imageModel == "dall-e-3" {
+		data.Quality = "hd"
+		data.Style = "vivid"
 	}
 
-	buf, err := json.Marshal(data)
+	response := new(ImageResponse)
+	err := openAi.post(ctx, "/images/generations", data, response)
 	if err != nil {
-		openAi.logger.Error(err, "Failed to create a new http request.")
+		openAi.logger.Error(err, "Failed to create a new image from prompt", prompt)
 		return nil, err
 	}
-	body := bytes.NewBuffer(buf)
-	token := fmt.Sprintf("Bearer %s", openAi.apiKey)
-	req, _ := http.NewRequest(http.MethodPost, url, body)
+	if len(response.Data) == 0 {
+		return nil, errors.New("openai: image generation returned no images")
+	}
 
-	req.Header.Add("content-type", "application/json")
-	req.Header.Add("authorization", token)
-	req.Header.Add("OpenAI-Organization", openAi.orgId)
-	client := new(http.Client)
-	resp, err := client.Do(req)
+	image := response.Data[0].URL
+	return &image, nil
+}
+
+func (openAi *OpenAi) EmbeddingModel() string {
+	return openAi.embeddingModel
+}
 
+// Embedding returns an embedding of EmbeddingDimensions for each input, in the order of the input.
+// Only the text-embedding-3 models can be asked for fewer dimensions than they return by default.
+func (openAi *OpenAi) Embedding(ctx context.Context, input []string) ([][]float32, error) {
+	data := &EmbeddingBody{
+		Model: openAi.embeddingModel,
+		Input: input,
+	}
+	if strings.HasPrefix(openAi.embeddingModel, "text-embedding-3") {
+		data.Dimensions = EmbeddingDimensions
+	}
+
+	response := new(EmbeddingResponse)
+	err := openAi.post(ctx, "/embeddings", data, response)
 	if err != nil {
-		openAi.logger.Error(err, "Failed to create a new image from prompt", prompt)
+		openAi.logger.Error(err, "Failed to create embeddings.", len(input))
 		return nil, err
 	}
+	if len(response.Data) != len(input) {
+		return nil, fmt.Errorf("openai: received %d embeddings for %d inputs", len(response.Data), len(input))
+	}
+	embeddings := make([][]float32, len(input))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(input) {
+			return nil, fmt.Errorf("openai: embedding index %d is out of range", item.Index)
+		}
+		if len(item.Embedding) != EmbeddingDimensions {
+			return nil, fmt.Errorf("openai: embedding has %d dimensions, expected %d", len(item.Embedding), EmbeddingDimensions)
+		}
+		embeddings[item.Index] = item.Embedding
+	}
+	return embeddings, nil
+}
 
+// post sends the request until it succeeds, fails with an error that cannot be
+// retried, or runs out of retries. Every attempt waits for the rate limiter and
+// has its own deadline.
+func (openAi *OpenAi) post(ctx context.Context, path string, data interface{}, response interface{}) error {
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		err = openAi.limiter.Wait(ctx)
+		if err != nil {
+			return err
+		}
+		err = openAi.send(ctx, path, buf, response)
+		if err == nil {
+			return nil
+		}
+		delay, retryable := retryDelay(ctx, err, attempt)
+		if !retryable || attempt >= openAi.maxRetries {
+			return err
+		}
+		openAi.logger.Warn("Retrying the OpenAI request.", path, attempt+1, delay.String(), err.Error())
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			{
+				timer.Stop()
+				return err
+			}
+		case <-timer.C:
+		}
+	}
+}
+
+func (openAi *OpenAi) send(ctx context.Context, path string, buf []byte, response interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, openAi.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, openAi.baseURL+path, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("content-type", "application/json")
+	req.Header.Add("authorization", fmt.Sprintf("Bearer %s", openAi.apiKey))
+	if len(openAi.orgId) > 0 {
+		req.Header.Add("OpenAI-Organization", openAi.orgId)
+	}
+	resp, err := openAi.client.Do(req)
+	if err != nil {
+		return err
+	}
 	defer resp.Body.Close()
 
-	response := new(ImageResponse)
-	err = json.NewDecoder(resp.Body).Decode(response)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return toApiError(resp, body)
+	}
+	return json.Unmarshal(body, response)
+}
 
-	if err != nil || len(response.Data) == 0 {
-		openAi.logger.Error(err, "Failed to decode response.", response)
-		return nil, err
+func retryDelay(ctx context.Context, err error, attempt int) (time.Duration, bool) {
+	if ctx.Err() != nil {
+		return 0, false
+	}
+	delay := backoff(attempt)
+	var apiError *ApiError
+	if errors.As(err, &apiError) {
+		if !apiError.Retryable() || apiError.RetryAfter > maxRetryAfter {
+			return 0, false
+		}
+		if apiError.RetryAfter > delay {
+			delay = apiError.RetryAfter
+		}
+		return delay, true
+	}
+	var urlError *url.Error
+	if errors.As(err, &urlError) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return delay, true
 	}
+	return 0, false
+}
 
-	image := response.Data[0].URL
-	return &image, nil
+func backoff(attempt int) time.Duration {
+	delay := time.Duration(float64(retryBase) * math.Pow(2, float64(attempt)))
+	if delay > retryLimit || delay <= 0 {
+		delay = retryLimit
+	}
+	jitter := (rand.Float64()*0.4 - 0.2) * float64(delay)
+	return delay + time.Duration(jitter)
 }
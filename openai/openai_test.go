@@ -0,0 +1,94 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/logger"
+)
+
+func newTestServer(t *testing.T, handler func(attempt int32, writer http.ResponseWriter)) (*httptest.Server, *int32) {
+	attempts := new(int32)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		handler(atomic.AddInt32(attempts, 1), writer)
+	}))
+	t.Cleanup(server.Close)
+	return server, attempts
+}
+
+func TestRetryRateLimited(t *testing.T) {
+	server, attempts := newTestServer(t, func(attempt int32, writer http.ResponseWriter) {
+		if attempt == 1 {
+			writer.Header().Set("retry-after-ms", "10")
+			writer.WriteHeader(http.StatusTooManyRequests)
+			writer.Write([]byte(`{"error":{"type":"requests","code":"rate_limit_exceeded","message":"Slow down."}}`))
+			return
+		}
+		writer.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	})
+	ai := NewOpenAi(&Config{BaseURL: server.URL, MaxRetries: 2}, logger.NewService(log.Default()))
+
+	content, err := ai.TextCompletion(context.Background(), new(TextCompletionArgs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *content != "ok" || *attempts != 2 {
+		t.Errorf("expected ok after 2 attempts, got %s after %d", *content, *attempts)
+	}
+}
+
+func TestNoRetryOnClientError(t *testing.T) {
+	for _, test := range []struct {
+		status int
+		body   string
+		code   string
+	}{
+		{http.StatusBadRequest, `{"error":{"type":"invalid_request_error","code":"invalid_value","message":"Bad model."}}`, "invalid_value"},
+		{http.StatusTooManyRequests, `{"error":{"type":"insufficient_quota","code":"insufficient_quota","message":"No quota."}}`, "insufficient_quota"},
+	} {
+		server, attempts := newTestServer(t, func(attempt int32, writer http.ResponseWriter) {
+			writer.WriteHeader(test.status)
+			writer.Write([]byte(test.body))
+		})
+		ai := NewOpenAi(&Config{BaseURL: server.URL, MaxRetries: 3}, logger.NewService(log.Default()))
+
+		_, err := ai.Image(context.Background(), "prompt")
+		var apiError *ApiError
+		if !errors.As(err, &apiError) {
+			t.Fatalf("expected an api error, got %v", err)
+		}
+		if apiError.StatusCode != test.status || apiError.Code != test.code || *attempts != 1 {
+			t.Errorf("unexpected error %+v after %d attempts", apiError, *attempts)
+		}
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	server, attempts := newTestServer(t, func(attempt int32, writer http.ResponseWriter) {
+		time.Sleep(time.Millisecond * 200)
+	})
+	ai := NewOpenAi(&Config{BaseURL: server.URL, Timeout: time.Millisecond * 20}, logger.NewService(log.Default()))
+
+	_, err := ai.TextCompletion(context.Background(), new(TextCompletionArgs))
+	if !errors.Is(err, context.DeadlineExceeded) || *attempts != 1 {
+		t.Errorf("expected a deadline error after 1 attempt, got %v after %d", err, *attempts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("retry-after", "2")
+	if delay := retryAfter(header); delay != time.Second*2 {
+		t.Errorf("expected 2s, got %s", delay)
+	}
+	header.Set("retry-after-ms", "150")
+	if delay := retryAfter(header); delay != time.Millisecond*150 {
+		t.Errorf("expected 150ms, got %s", delay)
+	}
+}
@@ -0,0 +1,92 @@
+package openai
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/logger"
+)
+
+const (
+	ProviderOpenAi     = "openai"
+	ProviderCompatible = "compatible"
+	ProviderFake       = "fake"
+)
+
+const (
+	DefaultBaseURL    = "https://api.openai.com/v1"
+	DefaultChatModel  = "gpt-4o"
+	DefaultImageModel = "dall-e-3"
+	DefaultTimeout    = time.Minute * 2
+	DefaultMaxRetries = 3
+
+	DefaultEmbeddingModel = "text-embedding-3-small"
+	// EmbeddingDimensions is the size of the idioms.embedding column.
+	EmbeddingDimensions = 1536
+)
+
+type Config struct {
+	Provider   string
+	BaseURL    string
+	ApiKey     string
+	OrgId      string
+	ChatModel  string
+	ImageModel string
+
+	EmbeddingModel string
+
+	// Timeout bounds a single attempt, MaxRetries the attempts after the first one
+	// and RateLimit the requests per minute. A zero RateLimit disables the limiter.
+	Timeout    time.Duration
+	MaxRetries int
+	RateLimit  float64
+}
+
+// NewProvider creates the client selected by config.Provider. The fake provider
+// starts an in-process server which lives as long as the process.
+func NewProvider(config *Config, logger logger.LoggerService) (OpenAiInterface, error) {
+	switch config.Provider {
+	case ProviderOpenAi, "":
+		{
+			return NewOpenAi(&Config{
+				ApiKey:     config.ApiKey,
+				OrgId:      config.OrgId,
+				ChatModel:  config.ChatModel,
+				ImageModel: config.ImageModel,
+				Timeout:    config.Timeout,
+				MaxRetries: config.MaxRetries,
+				RateLimit:  config.RateLimit,
+
+				EmbeddingModel: config.EmbeddingModel,
+			}, logger), nil
+		}
+	case ProviderCompatible:
+		{
+			if len(config.BaseURL) == 0 || len(config.ChatModel) == 0 {
+				return nil, errors.New("base url and chat model are required for an openai compatible provider")
+			}
+			return NewOpenAi(config, logger), nil
+		}
+	case ProviderFake:
+		{
+			fake, err := NewFakeServer()
+			if err != nil {
+				return nil, err
+			}
+			return NewOpenAi(&Config{
+				BaseURL:    fake.URL,
+				ApiKey:     "fake",
+				ChatModel:  "fake-chat",
+				ImageModel: "fake-image",
+				Timeout:    config.Timeout,
+
+				EmbeddingModel: "fake-embedding",
+			}, logger), nil
+		}
+	default:
+		{
+			return nil, fmt.Errorf("unknown llm provider %q", config.Provider)
+		}
+	}
+}
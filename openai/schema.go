@@ -0,0 +1,82 @@
+package openai
+
+import (
+	"reflect"
+	"strings"
+)
+
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JsonSchema *JsonSchema `json:"json_schema,omitempty"`
+}
+
+type JsonSchema struct {
+	Name   string                 `json:"name"`
+	Strict bool                   `json:"strict"`
+	Schema map[string]interface{} `json:"schema"`
+}
+
+// SchemaFor derives a strict JSON schema from the json tags of a struct. Every
+// field is required and no other properties are allowed, as structured outputs require.
+// A `description` tag is copied to the property.
+func SchemaFor(value interface{}) map[string]interface{} {
+	return schemaOf(reflect.TypeOf(value))
+}
+
+func schemaOf(kind reflect.Type) map[string]interface{} {
+	for kind.Kind() == reflect.Pointer {
+		kind = kind.Elem()
+	}
+	switch kind.Kind() {
+	case reflect.Struct:
+		{
+			properties := map[string]interface{}{}
+			required := []string{}
+			for index := 0; index < kind.NumField(); index++ {
+				field := kind.Field(index)
+				name := strings.Split(field.Tag.Get("json"), ",")[0]
+				if !field.IsExported() || name == "-" {
+					continue
+				}
+				if len(name) == 0 {
+					name = field.Name
+				}
+				property := schemaOf(field.Type)
+				if description := field.Tag.Get("description"); len(description) > 0 {
+					property["description"] = description
+				}
+				properties[name] = property
+				required = append(required, name)
+			}
+			return map[string]interface{}{
+				"type":                 "object",
+				"properties":           properties,
+				"required":             required,
+				"additionalProperties": false,
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		{
+			return map[string]interface{}{
+				"type":  "array",
+				"items": schemaOf(kind.Elem()),
+			}
+		}
+	case reflect.Bool:
+		{
+			return map[string]interface{}{"type": "boolean"}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		{
+			return map[string]interface{}{"type": "integer"}
+		}
+	case reflect.Float32, reflect.Float64:
+		{
+			return map[string]interface{}{"type": "number"}
+		}
+	default:
+		{
+			return map[string]interface{}{"type": "string"}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const ValidationAttempts = 3
+
+// Validator is implemented by generated content with rules the JSON schema can not express.
+type Validator interface {
+	Validate() []string
+}
+
+type InvalidContentError struct {
+	Problems []string
+	Content  string
+}
+
+func (err *InvalidContentError) Error() string {
+	return fmt.Sprintf("openai: invalid content: %s", strings.Join(err.Problems, "; "))
+}
+
+var fencePattern = regexp.MustCompile("(?s)```[a-zA-Z]*\\s*(.*?)\\s*```")
+
+func (args *TextCompletionArgs) WithSchema(name string, value interface{}) *TextCompletionArgs {
+	args.ResponseFormat = &ResponseFormat{
+		Type: "json_schema",
+		JsonSchema: &JsonSchema{
+			Name:   name,
+			Strict: true,
+			Schema: SchemaFor(value),
+		},
+	}
+	return args
+}
+
+// ExtractJSON returns the JSON document of a response, unwrapping markdown code
+// fences and any prose around the object.
+func ExtractJSON(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if match := fencePattern.FindStringSubmatch(trimmed); match != nil {
+		trimmed = match[1]
+	}
+	start := strings.Index(trimmed, "{")
+	end := strings.LastIndex(trimmed, "}")
+	if start >= 0 && end > start {
+		return trimmed[start : end+1]
+	}
+	return trimmed
+}
+
+// CompleteJSON asks for content matching the schema of target and decodes it.
+// When the response can not be decoded or fails validation, the problems are sent
+// back to the model, up to attempts times in total.
+func CompleteJSON(ctx context.Context, ai OpenAiInterface, name string, args *TextCompletionArgs, target interface{}, attempts int) error {
+	args.WithSchema(name, target)
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var invalid *InvalidContentError
+	for attempt := 0; attempt < attempts; attempt++ {
+		content, err := ai.TextCompletion(ctx, args)
+		if err != nil {
+			return err
+		}
+		problems := []string{}
+		err = json.Unmarshal([]byte(ExtractJSON(*content)), target)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("the response is not valid JSON: %s", err.Error()))
+		} else if validator, ok := target.(Validator); ok {
+			problems = validator.Validate()
+		}
+		if len(problems) == 0 {
+			return nil
+		}
+		invalid = &InvalidContentError{Problems: problems, Content: *content}
+
+		args.AddMessage("assistant", *content)
+		args.AddMessage("user", fmt.Sprintf("Your answer has problems below.\n- %s\nFix every problem and answer again in the same JSON format.", strings.Join(problems, "\n- ")))
+	}
+	return invalid
+}
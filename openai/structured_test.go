@@ -0,0 +1,118 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testContent struct {
+	Name     string   `json:"name"`
+	Count    int      `json:"count" description:"How many."`
+	Tags     []string `json:"tags"`
+	Internal string   `json:"-"`
+}
+
+func (content *testContent) Validate() []string {
+	if content.Count != 2 {
+		return []string{"count must be 2"}
+	}
+	return nil
+}
+
+type scriptedAi struct {
+	responses []string
+	requests  []*TextCompletionArgs
+}
+
+func (ai *scriptedAi) TextCompletion(ctx context.Context, args *TextCompletionArgs) (*string, error) {
+	copied := *args
+	copied.Messages = append([]TextCompletionMessage{}, args.Messages...)
+	ai.requests = append(ai.requests, &copied)
+	response := ai.responses[len(ai.requests)-1]
+	return &response, nil
+}
+
+func (ai *scriptedAi) Image(ctx context.Context, prompt string) (*string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (ai *scriptedAi) ImageModel() string {
+	return DefaultImageModel
+}
+
+func (ai *scriptedAi) Embedding(ctx context.Context, input []string) ([][]float32, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (ai *scriptedAi) EmbeddingModel() string {
+	return DefaultEmbeddingModel
+}
+
+func TestExtractJSON(t *testing.T) {
+	for _, test := range []struct {
+		content  string
+		expected string
+	}{
+		{`{"json":"son"}`, `{"json":"son"}`},
+		{"```json\n{\"json\":\"son\"}\n```", `{"json":"son"}`},
+		{"```\n{\"a\":1}\n```\n", `{"a":1}`},
+		{"Here it is:\n```json\n{\"a\":{\"b\":2}}\n```\nEnjoy.", `{"a":{"b":2}}`},
+		{`Sure! {"a":1} Done.`, `{"a":1}`},
+	} {
+		if extracted := ExtractJSON(test.content); extracted != test.expected {
+			t.Errorf("expected %s, received %s", test.expected, extracted)
+		}
+	}
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema := SchemaFor(&testContent{})
+	expected := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name":  map[string]interface{}{"type": "string"},
+			"count": map[string]interface{}{"type": "integer", "description": "How many."},
+			"tags":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+		"required":             []string{"name", "count", "tags"},
+		"additionalProperties": false,
+	}
+	if !reflect.DeepEqual(schema, expected) {
+		t.Errorf("unexpected schema %v", schema)
+	}
+}
+
+func TestCompleteJSON(t *testing.T) {
+	ai := &scriptedAi{responses: []string{
+		"not json",
+		"```json\n{\"name\":\"a\",\"count\":1,\"tags\":[]}\n```",
+		`{"name":"a","count":2,"tags":["b"]}`,
+	}}
+	args := new(TextCompletionArgs).AddMessage("user", "Give me content.")
+	content := new(testContent)
+
+	err := CompleteJSON(context.Background(), ai, "content", args, content, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content.Count != 2 || len(ai.requests) != 3 {
+		t.Errorf("expected valid content after 3 requests, got %+v after %d", content, len(ai.requests))
+	}
+	if ai.requests[0].ResponseFormat == nil || ai.requests[0].ResponseFormat.JsonSchema.Name != "content" {
+		t.Errorf("expected a json schema response format")
+	}
+	last := ai.requests[2].Messages[len(ai.requests[2].Messages)-1]
+	if last.Role != "user" || !strings.Contains(last.Content, "count must be 2") {
+		t.Errorf("expected the validation problems in the prompt, got %s", last.Content)
+	}
+
+	ai = &scriptedAi{responses: []string{`{"count":1}`, `{"count":3}`}}
+	err = CompleteJSON(context.Background(), ai, "content", new(TextCompletionArgs), new(testContent), 2)
+	var invalid *InvalidContentError
+	if !errors.As(err, &invalid) || invalid.Content != `{"count":3}` {
+		t.Errorf("expected an invalid content error, got %v", err)
+	}
+}
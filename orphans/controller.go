@@ -0,0 +1,56 @@
+package orphans
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type OrphanController interface {
+	Reconcile(writer http.ResponseWriter, request *http.Request)
+}
+
+type Controller struct {
+	orphanService OrphanService
+
+	logger logger.LoggerService
+}
+
+func NewController(orphanService OrphanService, logger logger.LoggerService) *Controller {
+	controller := new(Controller)
+	controller.orphanService = orphanService
+	controller.logger = logger
+
+	return controller
+}
+
+// Reconcile is a dry run unless the body sets "dryRun": false.
+func (controller *Controller) Reconcile(writer http.ResponseWriter, request *http.Request) {
+	input := new(models.ReconcileThumbnailsInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil && err != io.EOF {
+		lib.WriteError(writer, lib.NewValidationError("Invalid JSON body.", err.Error()))
+		return
+	}
+	if input.GraceHours < 0 {
+		lib.WriteError(writer, lib.NewValidationError("graceHours must not be negative.", nil))
+		return
+	}
+	report, err := controller.orphanService.Reconcile(request.Context(), &Option{
+		DryRun: input.DryRun == nil || *input.DryRun,
+		Grace:  time.Hour * time.Duration(input.GraceHours),
+		Prefix: input.Prefix,
+	})
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"report": report,
+	})
+}
@@ -0,0 +1,80 @@
+package orphans
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/storage"
+)
+
+const (
+	SourceIdiom    = "idiom"
+	SourceDraft    = "draft"
+	SourceRevision = "revision"
+)
+
+type Reference struct {
+	Key     string `db:"key" json:"key"`
+	Source  string `db:"source" json:"source"`
+	OwnerID string `db:"owner_id" json:"ownerId"`
+}
+
+type Orphan struct {
+	storage.Object
+	Expired bool `json:"expired"`
+	Deleted bool `json:"deleted"`
+}
+
+type Report struct {
+	DryRun     bool        `json:"dryRun"`
+	Grace      string      `json:"grace"`
+	Objects    int         `json:"objects"`
+	References int         `json:"references"`
+	Orphans    []Orphan    `json:"orphans"`
+	Missing    []Reference `json:"missing"`
+	Deleted    int         `json:"deleted"`
+	Failed     []string    `json:"failed"`
+}
+
+// Compare matches the listed objects against the references. Orphans last modified
+// before now minus grace are expired and may be deleted; the rest may belong to an
+// upload whose row is not committed yet.
+func Compare(objects []storage.Object, references []Reference, now time.Time, grace time.Duration) *Report {
+	report := &Report{
+		Grace:      grace.String(),
+		Objects:    len(objects),
+		References: len(references),
+		Orphans:    []Orphan{},
+		Missing:    []Reference{},
+		Failed:     []string{},
+	}
+	referenced := map[string]bool{}
+	for _, reference := range references {
+		referenced[normalizeKey(reference.Key)] = true
+	}
+	listed := map[string]bool{}
+	for _, object := range objects {
+		listed[object.Key] = true
+		if referenced[object.Key] {
+			continue
+		}
+		report.Orphans = append(report.Orphans, Orphan{
+			Object:  object,
+			Expired: object.LastModified.Before(now.Add(-grace)),
+		})
+	}
+	for _, reference := range references {
+		if reference.Source != SourceRevision && !listed[normalizeKey(reference.Key)] {
+			report.Missing = append(report.Missing, reference)
+		}
+	}
+	sort.Slice(report.Orphans, func(i, j int) bool {
+		return report.Orphans[i].Key < report.Orphans[j].Key
+	})
+	return report
+}
+
+func normalizeKey(key string) string {
+	return strings.TrimPrefix(strings.TrimSpace(key), "/")
+}
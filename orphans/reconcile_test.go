@@ -0,0 +1,46 @@
+package orphans
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nw.lee/idioms-backend/storage"
+)
+
+func TestCompare(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	objects := []storage.Object{
+		{Key: "2024/5/1/break-the-ice-1a2b3c4d-640w.jpg", LastModified: now.Add(-time.Hour * 30)},
+		{Key: "2024/5/1/break-the-ice-1a2b3c4d-1280w.jpg", LastModified: now.Add(-time.Hour * 30)},
+		{Key: "2024/4/1/break-the-ice-ffffffff-1280w.jpg", LastModified: now.Add(-time.Hour * 24 * 31)},
+		{Key: "2024/5/2/spill-the-beans-00000000-1280w.jpg", LastModified: now.Add(-time.Minute)},
+		{Key: "drafts/break-the-ice/draft.png", LastModified: now.Add(-time.Hour * 48)},
+		{Key: "2024/3/1/old-revision-1280w.jpg", LastModified: now.Add(-time.Hour * 24 * 60)},
+	}
+	references := []Reference{
+		{Key: "2024/5/1/break-the-ice-1a2b3c4d-1280w.jpg", Source: SourceIdiom, OwnerID: "break-the-ice"},
+		{Key: "/2024/5/1/break-the-ice-1a2b3c4d-640w.jpg", Source: SourceIdiom, OwnerID: "break-the-ice"},
+		{Key: "drafts/break-the-ice/draft.png", Source: SourceDraft, OwnerID: "break-the-ice"},
+		{Key: "2024/3/1/old-revision-1280w.jpg", Source: SourceRevision, OwnerID: "old-revision"},
+		{Key: "2024/1/1/missing-1280w.jpg", Source: SourceIdiom, OwnerID: "missing"},
+		{Key: "2023/1/1/pruned-1280w.jpg", Source: SourceRevision, OwnerID: "pruned"},
+	}
+
+	report := Compare(objects, references, now, time.Hour*24)
+
+	if report.Objects != 6 || report.References != 6 {
+		t.Errorf("Expected 6 objects and 6 references, received %d and %d", report.Objects, report.References)
+	}
+	if len(report.Orphans) != 2 {
+		t.Fatalf("Expected 2 orphans, received %v", report.Orphans)
+	}
+	if orphan := report.Orphans[0]; orphan.Key != "2024/4/1/break-the-ice-ffffffff-1280w.jpg" || !orphan.Expired {
+		t.Errorf("Expected the replaced thumbnail to be an expired orphan, received %v", orphan)
+	}
+	if orphan := report.Orphans[1]; orphan.Key != "2024/5/2/spill-the-beans-00000000-1280w.jpg" || orphan.Expired {
+		t.Errorf("Expected the fresh upload to be kept by the grace period, received %v", orphan)
+	}
+	if len(report.Missing) != 1 || report.Missing[0].OwnerID != "missing" {
+		t.Errorf("Expected only the idiom reference to be missing, received %v", report.Missing)
+	}
+}
@@ -0,0 +1,127 @@
+package orphans
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/storage"
+)
+
+const (
+	DefaultGrace             = time.Hour * 24
+	DefaultRevisionRetention = time.Hour * 24 * 30
+)
+
+// Revisions keep their thumbnail referenced for a while so a recent restore finds its
+// images, while images replaced long ago can still be collected.
+const referenceQuery = `
+select thumbnail as key, 'idiom' as source, id as owner_id from idioms where thumbnail is not null and thumbnail <> ''
+union
+select jsonb_array_elements_text(thumbnails), 'idiom', id from idioms where jsonb_typeof(thumbnails) = 'array'
+union
+select storage_key, 'draft', idiom_id from thumbnail_drafts
+union
+select thumbnail, 'revision', idiom_id from idiom_revisions where thumbnail is not null and thumbnail <> '' and created_at > $1
+union
+select jsonb_array_elements_text(thumbnails), 'revision', idiom_id from idiom_revisions where jsonb_typeof(thumbnails) = 'array' and created_at > $1
+`
+
+type Option struct {
+	DryRun bool
+	Grace  time.Duration
+	Prefix string
+}
+
+type OrphanService interface {
+	Reconcile(ctx context.Context, option *Option) (*Report, error)
+	ReconcileJob(ctx context.Context, job *models.Job) error
+}
+
+type Service struct {
+	db      *sqlx.DB
+	logger  logger.LoggerService
+	storage storage.StorageService
+
+	revisionRetention time.Duration
+}
+
+func NewService(db *sqlx.DB, logger logger.LoggerService, storage storage.StorageService) *Service {
+	service := new(Service)
+	service.db = db
+	service.logger = logger
+	service.storage = storage
+	service.revisionRetention = DefaultRevisionRetention
+
+	return service
+}
+
+// Reconcile lists the bucket and reports the objects nothing references and the
+// references without an object. Unless it is a dry run, expired orphans are deleted.
+func (service *Service) Reconcile(ctx context.Context, option *Option) (*Report, error) {
+	grace := option.Grace
+	if grace <= 0 {
+		grace = DefaultGrace
+	}
+	now := time.Now().UTC()
+	// References are read before listing, so an object uploaded in between is
+	// at most seconds old and protected by the grace period.
+	references := []Reference{}
+	revisionsSince := now.Add(-service.revisionRetention).Format(time.RFC3339Nano)
+	err := service.db.SelectContext(ctx, &references, referenceQuery, revisionsSince)
+	if err != nil {
+		service.logger.Error(err, "Failed to query storage references.")
+		return nil, err
+	}
+	objects, err := service.storage.ListObjects(ctx, option.Prefix)
+	if err != nil {
+		service.logger.Error(err, "Failed to list storage objects.", option.Prefix)
+		return nil, err
+	}
+
+	report := Compare(objects, references, now, grace)
+	report.DryRun = option.DryRun
+	if option.DryRun {
+		return report, nil
+	}
+	for index, orphan := range report.Orphans {
+		if !orphan.Expired {
+			continue
+		}
+		err = service.storage.DeleteObject(ctx, orphan.Key)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			service.logger.Error(err, "Failed to delete the orphaned object.", orphan.Key)
+			report.Failed = append(report.Failed, orphan.Key)
+			continue
+		}
+		report.Orphans[index].Deleted = true
+		report.Deleted++
+	}
+	return report, nil
+}
+
+func (service *Service) ReconcileJob(ctx context.Context, job *models.Job) error {
+	payload := new(models.ReconcileStoragePayload)
+	if len(job.Payload) > 0 {
+		err := json.Unmarshal(job.Payload, payload)
+		if err != nil {
+			return err
+		}
+	}
+	report, err := service.Reconcile(ctx, &Option{
+		DryRun: payload.DryRun,
+		Grace:  time.Hour * time.Duration(payload.GraceHours),
+	})
+	if err != nil {
+		return err
+	}
+	service.logger.Info("Reconciled storage.", "objects", report.Objects, "orphans", len(report.Orphans), "missing", len(report.Missing), "deleted", report.Deleted)
+	if len(report.Failed) > 0 {
+		return errors.New("failed to delete some orphaned objects")
+	}
+	return nil
+}
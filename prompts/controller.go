@@ -0,0 +1,84 @@
+package prompts
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nw.lee/idioms-backend/auth"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type PromptController interface {
+	GetTemplates(writer http.ResponseWriter, request *http.Request)
+	CreateTemplate(writer http.ResponseWriter, request *http.Request)
+	PreviewPrompt(writer http.ResponseWriter, request *http.Request)
+}
+
+type Controller struct {
+	promptService PromptService
+
+	logger logger.LoggerService
+}
+
+func NewController(promptService PromptService, logger logger.LoggerService) *Controller {
+	controller := new(Controller)
+	controller.promptService = promptService
+	controller.logger = logger
+
+	return controller
+}
+
+func (controller *Controller) GetTemplates(writer http.ResponseWriter, request *http.Request) {
+	templates, err := controller.promptService.GetTemplates(request.Context())
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"templates": templates,
+	})
+}
+
+func (controller *Controller) CreateTemplate(writer http.ResponseWriter, request *http.Request) {
+	input := new(models.CreatePromptTemplateInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		controller.logger.Error(err, "Failed to decode JSON.")
+		lib.WriteError(writer, lib.NewValidationError("Invalid JSON body.", err.Error()))
+		return
+	}
+	principal := auth.PrincipalFromContext(request.Context())
+	promptTemplate, err := controller.promptService.CreateTemplate(request.Context(), input, principal.Subject)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusCreated, map[string]interface{}{
+		"template": promptTemplate,
+	})
+}
+
+func (controller *Controller) PreviewPrompt(writer http.ResponseWriter, request *http.Request) {
+	input := new(models.PreviewPromptInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		controller.logger.Error(err, "Failed to decode JSON.")
+		lib.WriteError(writer, lib.NewValidationError("Invalid JSON body.", err.Error()))
+		return
+	}
+	prompt, err := controller.promptService.RenderVersion(request.Context(), input.Name, input.Version, &Variables{
+		Idiom:   input.Idiom,
+		Meaning: input.Meaning,
+		Locale:  input.Locale,
+	})
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"prompt":  prompt,
+		"version": prompt.ID(),
+	})
+}
@@ -0,0 +1,313 @@
+package prompts
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/openai"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+const (
+	PromptGenerateIdiom = "idiom.generate"
+	PromptExamples      = "idiom.examples"
+	PromptDescription   = "idiom.description"
+	PromptTags          = "idiom.tags"
+
+	DefaultLocale = "en"
+)
+
+var sectionPattern = regexp.MustCompile(`(?m)^--- (system|user|assistant)[ \t]*$`)
+
+type Variables struct {
+	Idiom   string
+	Meaning string
+	Locale  string
+	// Tags are the existing tags the model may reuse.
+	Tags []string
+}
+
+// Subject is the idiom and its meaning as the JSON object the prompts hand to the model.
+func (variables *Variables) Subject() string {
+	formatted, _ := json.Marshal(map[string]string{
+		"idiom":   variables.Idiom,
+		"meaning": variables.Meaning,
+	})
+	return string(formatted)
+}
+
+type Prompt struct {
+	Name     string                         `json:"name"`
+	Version  int                            `json:"version"`
+	Source   string                         `json:"source"`
+	Messages []openai.TextCompletionMessage `json:"messages"`
+}
+
+// ID identifies the template which rendered the prompt, as stored in idioms.prompt_version.
+func (prompt *Prompt) ID() string {
+	return fmt.Sprintf("%s@%d", prompt.Name, prompt.Version)
+}
+
+func (prompt *Prompt) Apply(args *openai.TextCompletionArgs) *openai.TextCompletionArgs {
+	for _, message := range prompt.Messages {
+		args.AddMessage(message.Role, message.Content)
+	}
+	return args
+}
+
+type PromptService interface {
+	Render(ctx context.Context, name string, variables *Variables) (*Prompt, error)
+	RenderVersion(ctx context.Context, name string, version int, variables *Variables) (*Prompt, error)
+	GetTemplates(ctx context.Context) ([]models.PromptTemplate, error)
+	CreateTemplate(ctx context.Context, input *models.CreatePromptTemplateInput, createdBy string) (*models.PromptTemplate, error)
+}
+
+type Service struct {
+	db       *sqlx.DB
+	logger   logger.LoggerService
+	partials []string
+	embedded map[string][]models.PromptTemplate
+}
+
+// NewService loads the templates embedded under templates/. Files in the root are
+// partials shared by every template and templates/<name>/<version>.tmpl are the prompts.
+func NewService(db *sqlx.DB, logger logger.LoggerService) (*Service, error) {
+	service := new(Service)
+	service.db = db
+	service.logger = logger
+	service.embedded = map[string][]models.PromptTemplate{}
+
+	err := fs.WalkDir(templateFiles, "templates", func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || path.Ext(filePath) != ".tmpl" {
+			return err
+		}
+		body, err := templateFiles.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		directory := path.Dir(filePath)
+		if directory == "templates" {
+			service.partials = append(service.partials, string(body))
+			return nil
+		}
+		version, err := strconv.Atoi(strings.TrimSuffix(path.Base(filePath), ".tmpl"))
+		if err != nil || version < 1 {
+			return fmt.Errorf("invalid prompt template version %s", filePath)
+		}
+		name := path.Base(directory)
+		service.embedded[name] = append(service.embedded[name], models.PromptTemplate{
+			Name:    name,
+			Version: version,
+			Body:    string(body),
+			Active:  true,
+			Source:  models.PromptSourceEmbedded,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for name, templates := range service.embedded {
+		sort.Slice(templates, func(i, j int) bool {
+			return templates[i].Version < templates[j].Version
+		})
+		for _, promptTemplate := range templates {
+			_, err = service.render(&promptTemplate, &Variables{Idiom: "Idiom", Meaning: "Meaning", Locale: DefaultLocale})
+			if err != nil {
+				return nil, fmt.Errorf("invalid prompt template %s@%d: %w", name, promptTemplate.Version, err)
+			}
+		}
+	}
+	return service, nil
+}
+
+func (service *Service) Render(ctx context.Context, name string, variables *Variables) (*Prompt, error) {
+	return service.RenderVersion(ctx, name, 0, variables)
+}
+
+// RenderVersion renders the given version of a template, or the latest active one
+// when version is 0. A template stored in the database replaces an embedded one
+// with the same version.
+func (service *Service) RenderVersion(ctx context.Context, name string, version int, variables *Variables) (*Prompt, error) {
+	templates, err := service.findTemplates(ctx, name, true)
+	if err != nil {
+		return nil, err
+	}
+	var found *models.PromptTemplate
+	for index := range templates {
+		if version == 0 || templates[index].Version == version {
+			found = &templates[index]
+		}
+	}
+	if found == nil {
+		return nil, lib.NewNotFoundError("Prompt template not found.", map[string]interface{}{"name": name, "version": version})
+	}
+	if len(variables.Locale) == 0 {
+		variables.Locale = DefaultLocale
+	}
+	return service.render(found, variables)
+}
+
+func (service *Service) GetTemplates(ctx context.Context) ([]models.PromptTemplate, error) {
+	names := []string{}
+	for name := range service.embedded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	templates := []models.PromptTemplate{}
+	for _, name := range names {
+		found, err := service.findTemplates(ctx, name, false)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, found...)
+	}
+	return templates, nil
+}
+
+func (service *Service) CreateTemplate(ctx context.Context, input *models.CreatePromptTemplateInput, createdBy string) (*models.PromptTemplate, error) {
+	embedded, ok := service.embedded[input.Name]
+	if !ok {
+		return nil, lib.NewValidationError("Unknown prompt template name.", map[string]string{"name": input.Name})
+	}
+	promptTemplate := &models.PromptTemplate{Name: input.Name, Body: input.Body}
+	_, err := service.render(promptTemplate, &Variables{Idiom: "Idiom", Meaning: "Meaning", Locale: DefaultLocale})
+	if err != nil {
+		return nil, lib.NewValidationError("Invalid prompt template.", err.Error())
+	}
+
+	latest := embedded[len(embedded)-1].Version
+	version := sq.Expr("greatest((select coalesce(max(version), 0) from prompt_templates where name = ?), ?) + 1", input.Name, latest)
+	query, args, err := sq.Insert("prompt_templates").
+		Columns("name", "version", "body", "created_by").
+		Values(input.Name, version, input.Body, createdBy).
+		Suffix("returning name, version, body, active, created_by, created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	err = service.db.GetContext(ctx, promptTemplate, query, args...)
+	if lib.IsUniqueViolation(err) {
+		return nil, lib.NewConflictError("The prompt template was changed at the same time.", map[string]string{"name": input.Name})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to insert the prompt template.", input.Name)
+		return nil, err
+	}
+	promptTemplate.Source = models.PromptSourceDatabase
+	return promptTemplate, nil
+}
+
+// findTemplates returns the embedded and stored versions of a template sorted by version.
+func (service *Service) findTemplates(ctx context.Context, name string, active bool) ([]models.PromptTemplate, error) {
+	embedded, ok := service.embedded[name]
+	if !ok {
+		return nil, lib.NewNotFoundError("Prompt template not found.", map[string]string{"name": name})
+	}
+	builder := sq.Select("name", "version", "body", "active", "created_by", "created_at").
+		From("prompt_templates").
+		Where("name = ?", name).
+		OrderBy("version")
+	if active {
+		builder = builder.Where("active")
+	}
+	query, args, err := builder.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	stored := []models.PromptTemplate{}
+	err = service.db.SelectContext(ctx, &stored, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to query prompt templates.", name)
+		return nil, err
+	}
+
+	versions := map[int]models.PromptTemplate{}
+	for _, promptTemplate := range embedded {
+		versions[promptTemplate.Version] = promptTemplate
+	}
+	for _, promptTemplate := range stored {
+		promptTemplate.Source = models.PromptSourceDatabase
+		versions[promptTemplate.Version] = promptTemplate
+	}
+	templates := []models.PromptTemplate{}
+	for _, promptTemplate := range versions {
+		templates = append(templates, promptTemplate)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Version < templates[j].Version
+	})
+	return templates, nil
+}
+
+func (service *Service) render(promptTemplate *models.PromptTemplate, variables *Variables) (*Prompt, error) {
+	parsed := template.New(promptTemplate.Name).Option("missingkey=error")
+	for _, partial := range service.partials {
+		_, err := parsed.Parse(partial)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err := parsed.Parse(promptTemplate.Body)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	err = parsed.Execute(buf, variables)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := toMessages(buf.String())
+	if err != nil {
+		return nil, err
+	}
+	return &Prompt{
+		Name:     promptTemplate.Name,
+		Version:  promptTemplate.Version,
+		Source:   promptTemplate.Source,
+		Messages: messages,
+	}, nil
+}
+
+// toMessages splits a rendered template into messages at lines like "--- system".
+func toMessages(rendered string) ([]openai.TextCompletionMessage, error) {
+	sections := sectionPattern.FindAllStringSubmatchIndex(rendered, -1)
+	if len(sections) == 0 || len(strings.TrimSpace(rendered[:sections[0][0]])) > 0 {
+		return nil, fmt.Errorf("a prompt must start with a role line like \"--- system\"")
+	}
+	messages := []openai.TextCompletionMessage{}
+	for index, section := range sections {
+		end := len(rendered)
+		if index+1 < len(sections) {
+			end = sections[index+1][0]
+		}
+		content := strings.TrimSpace(rendered[section[1]:end])
+		if len(content) == 0 {
+			continue
+		}
+		messages = append(messages, openai.TextCompletionMessage{
+			Role:    rendered[section[2]:section[3]],
+			Content: content,
+		})
+	}
+	return messages, nil
+}
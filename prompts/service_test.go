@@ -0,0 +1,55 @@
+package prompts
+
+import (
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/openai"
+)
+
+func TestEmbeddedTemplates(t *testing.T) {
+	service, err := NewService(nil, logger.NewService(log.Default()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{PromptGenerateIdiom, PromptExamples, PromptDescription, PromptTags} {
+		templates := service.embedded[name]
+		if len(templates) == 0 {
+			t.Fatalf("expected an embedded %s template", name)
+		}
+		prompt, err := service.render(&templates[len(templates)-1], &Variables{Idiom: "Break the ice", Meaning: "to start a conversation", Locale: "ko", Tags: []string{"business", "small talk"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := prompt.Messages[0]
+		if first.Role != "system" || !strings.Contains(first.Content, "English instructor") || !strings.Contains(first.Content, "locale ko") {
+			t.Errorf("expected the persona in %s, got %s", name, first.Content)
+		}
+		fake := openai.FakeContent(prompt.Messages)
+		if fake["idiom"] != "Break the ice" {
+			t.Errorf("expected the idiom in %s, got %v", name, fake["idiom"])
+		}
+		if name == PromptTags && !strings.Contains(prompt.Messages[1].Content, "business, small talk") {
+			t.Errorf("expected the existing tags in %s, got %s", name, prompt.Messages[1].Content)
+		}
+		if prompt.ID() != name+"@1" {
+			t.Errorf("unexpected prompt id %s", prompt.ID())
+		}
+	}
+}
+
+func TestToMessages(t *testing.T) {
+	messages, err := toMessages("--- system\nBe nice.\n--- user\n\n--- assistant\nHello.\nWorld.\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[0].Content != "Be nice." || messages[1].Role != "assistant" || messages[1].Content != "Hello.\nWorld." {
+		t.Errorf("unexpected messages %+v", messages)
+	}
+	_, err = toMessages("Be nice.\n--- user\nHi")
+	if err == nil {
+		t.Errorf("expected an error for text before the first role")
+	}
+}
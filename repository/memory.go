@@ -0,0 +1,922 @@
+package repository
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+const (
+	relatedCount  = 4
+	mainPageCount = 24
+)
+
+// Memory keeps idioms in memory for the unit tests of the services. It filters, orders and
+// pages like engine/query.sql, but a search only matches substrings of the idiom and its meanings.
+type vector struct {
+	embedding []float32
+	model     string
+	hash      string
+}
+
+type Memory struct {
+	mutex    sync.Mutex
+	idioms   map[string]models.Idiom
+	examples map[string][]models.IdiomExample
+	prompts  map[string]string
+	drafts   map[int64]models.ThumbnailDraft
+	vectors  map[string]vector
+	inputs   map[string]models.IdiomInput
+
+	exampleId int64
+	draftId   int64
+}
+
+func NewMemory() *Memory {
+	memory := new(Memory)
+	memory.idioms = map[string]models.Idiom{}
+	memory.examples = map[string][]models.IdiomExample{}
+	memory.prompts = map[string]string{}
+	memory.drafts = map[int64]models.ThumbnailDraft{}
+	memory.vectors = map[string]vector{}
+	memory.inputs = map[string]models.IdiomInput{}
+
+	return memory
+}
+
+// AddIdiom stores the idiom with its tags, categories and examples, replacing an idiom with the same id.
+func (memory *Memory) AddIdiom(idiom models.Idiom) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	memory.examples[idiom.ID] = memory.newExamples(idiom.ID, idiom.Examples)
+	memory.idioms[idiom.ID] = idiom
+}
+
+// AddIdiomInput stores the input, replacing an input with the same id.
+func (memory *Memory) AddIdiomInput(input models.IdiomInput) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	memory.inputs[input.ID] = input
+}
+
+func (memory *Memory) newExamples(idiomId string, expressions []string) []models.IdiomExample {
+	examples := []models.IdiomExample{}
+	for position, expression := range expressions {
+		memory.exampleId++
+		examples = append(examples, models.IdiomExample{ID: memory.exampleId, IdiomID: idiomId, Expression: expression, Position: position})
+	}
+	return examples
+}
+
+// WithTx returns the memory itself, whose writes apply at once.
+func (memory *Memory) WithTx(tx *sqlx.Tx) IdiomRepository {
+	return memory
+}
+
+func (memory *Memory) GetIdiom(ctx context.Context, id string, published bool) (*models.Idiom, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	idiom, ok := memory.idioms[id]
+	if !ok || (published && idiom.Status != models.IdiomPublished) {
+		return nil, ErrNotFound
+	}
+	row := toRow(idiom)
+	return &row, nil
+}
+
+func (memory *Memory) GetIdiomStatus(ctx context.Context, id string) (string, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	idiom, ok := memory.idioms[id]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return idiom.Status, nil
+}
+
+func (memory *Memory) IdiomExists(ctx context.Context, id string) (bool, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	_, ok := memory.idioms[id]
+	return ok, nil
+}
+
+func (memory *Memory) GetExamples(ctx context.Context, idiomId string) ([]models.IdiomExample, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	examples := append([]models.IdiomExample{}, memory.examples[idiomId]...)
+	sort.Slice(examples, func(i, j int) bool {
+		if examples[i].Position != examples[j].Position {
+			return examples[i].Position < examples[j].Position
+		}
+		return examples[i].ID < examples[j].ID
+	})
+	return examples, nil
+}
+
+func (memory *Memory) GetExample(ctx context.Context, idiomId string, id int64) (*models.IdiomExample, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	for _, example := range memory.examples[idiomId] {
+		if example.ID == id {
+			return &example, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (memory *Memory) CountExamples(ctx context.Context, idiomId string) (int, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	return len(memory.examples[idiomId]), nil
+}
+
+func (memory *Memory) CreateExample(ctx context.Context, idiomId string, expression string, position int) (*models.IdiomExample, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	memory.exampleId++
+	example := models.IdiomExample{ID: memory.exampleId, IdiomID: idiomId, Expression: expression, Position: position}
+	memory.examples[idiomId] = append(memory.examples[idiomId], example)
+	return &example, nil
+}
+
+func (memory *Memory) ReplaceExamples(ctx context.Context, idiomId string, expressions []string) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	memory.examples[idiomId] = memory.newExamples(idiomId, expressions)
+	return nil
+}
+
+func (memory *Memory) UpdateExample(ctx context.Context, id int64, expression *string, position *int) (*models.IdiomExample, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	for idiomId, examples := range memory.examples {
+		for index := range examples {
+			example := &examples[index]
+			if example.ID != id {
+				continue
+			}
+			if expression != nil {
+				example.Expression = *expression
+			}
+			if position != nil {
+				example.Position = *position
+			}
+			memory.examples[idiomId] = examples
+			updated := *example
+			return &updated, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (memory *Memory) ShiftExamples(ctx context.Context, idiomId string, from int, to int, shift int) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	examples := memory.examples[idiomId]
+	for index := range examples {
+		if examples[index].Position >= from && examples[index].Position < to {
+			examples[index].Position += shift
+		}
+	}
+	return nil
+}
+
+func (memory *Memory) ReorderExamples(ctx context.Context, idiomId string, ids []int64) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	examples := memory.examples[idiomId]
+	for position, id := range ids {
+		for index := range examples {
+			if examples[index].ID == id {
+				examples[index].Position = position
+			}
+		}
+	}
+	return nil
+}
+
+func (memory *Memory) DeleteExample(ctx context.Context, id int64) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	for idiomId, examples := range memory.examples {
+		kept := []models.IdiomExample{}
+		for _, example := range examples {
+			if example.ID != id {
+				kept = append(kept, example)
+			}
+		}
+		memory.examples[idiomId] = kept
+	}
+	return nil
+}
+
+func (memory *Memory) GetTags(ctx context.Context, idiomId string) ([]models.Tag, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	tags := append([]models.Tag{}, memory.idioms[idiomId].Tags...)
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+func (memory *Memory) GetCategories(ctx context.Context, idiomId string) ([]models.Category, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	categories := append([]models.Category{}, memory.idioms[idiomId].Categories...)
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Name < categories[j].Name
+	})
+	return categories, nil
+}
+
+func (memory *Memory) ListIdioms(ctx context.Context, page *Page) ([]models.Idiom, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	idioms := []models.Idiom{}
+	for _, idiom := range memory.idioms {
+		if page.matches(&idiom) && page.after(&idiom) {
+			idioms = append(idioms, toRow(idiom))
+		}
+	}
+	return page.sorted(idioms), nil
+}
+
+func (memory *Memory) SearchIdioms(ctx context.Context, page *Page) ([]models.Idiom, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	keyword := strings.ToLower(page.Keyword)
+	idioms := []models.Idiom{}
+	for _, idiom := range memory.idioms {
+		rank := 0.0
+		if strings.Contains(strings.ToLower(idiom.Idiom), keyword) {
+			rank = 1
+		} else if strings.Contains(strings.ToLower(idiom.MeaningBrief+" "+idiom.MeaningFull), keyword) {
+			rank = 0.5
+		}
+		row := toRow(idiom)
+		row.Rank = &rank
+		if rank == 0 || !page.matches(&row) || !page.after(&row) {
+			continue
+		}
+		row.Highlights = &models.IdiomHighlight{Idiom: row.Idiom, MeaningBrief: row.MeaningBrief, MeaningFull: row.MeaningFull}
+		idioms = append(idioms, row)
+	}
+	return page.sorted(idioms), nil
+}
+
+func (memory *Memory) ListRelatedIdioms(ctx context.Context, id string) ([]models.Idiom, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	target, ok := memory.idioms[id]
+	if !ok || !target.PublishedAt.Valid {
+		return []models.Idiom{}, nil
+	}
+	after := &Page{Published: true, OrderBy: "published_at", Count: relatedCount}
+	before := &Page{Published: true, OrderBy: "published_at", Descending: true, Count: relatedCount}
+	newer := []models.Idiom{}
+	older := []models.Idiom{}
+	for _, idiom := range memory.idioms {
+		if !after.matches(&idiom) {
+			continue
+		}
+		switch compareTimestamp(idiom.PublishedAt, target.PublishedAt) {
+		case 1:
+			{
+				newer = append(newer, toRow(idiom))
+				break
+			}
+		case -1:
+			{
+				older = append(older, toRow(idiom))
+			}
+		}
+	}
+	idioms := append(after.sorted(newer), before.sorted(older)...)
+	sort.SliceStable(idioms, func(i, j int) bool {
+		return idioms[i].PublishedAt.Time.After(idioms[j].PublishedAt.Time)
+	})
+	return idioms, nil
+}
+
+func (memory *Memory) ListSimilarIdioms(ctx context.Context, id string, count int) ([]models.Idiom, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	target, ok := memory.vectors[id]
+	if !ok {
+		return []models.Idiom{}, nil
+	}
+	idioms := []models.Idiom{}
+	distances := map[string]float64{}
+	for _, idiom := range memory.idioms {
+		other, ok := memory.vectors[idiom.ID]
+		if idiom.ID == id || idiom.Status != models.IdiomPublished || !ok || other.model != target.model {
+			continue
+		}
+		distances[idiom.ID] = cosineDistance(target.embedding, other.embedding)
+		idioms = append(idioms, toRow(idiom))
+	}
+	sort.Slice(idioms, func(i, j int) bool {
+		if distances[idioms[i].ID] != distances[idioms[j].ID] {
+			return distances[idioms[i].ID] < distances[idioms[j].ID]
+		}
+		return idioms[i].ID < idioms[j].ID
+	})
+	if len(idioms) > count {
+		idioms = idioms[:count]
+	}
+	return idioms, nil
+}
+
+func (memory *Memory) ListIdiomsToEmbed(ctx context.Context, model string, count int) ([]EmbeddingSource, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	sources := []EmbeddingSource{}
+	for _, idiom := range memory.idioms {
+		if idiom.Status != models.IdiomPublished {
+			continue
+		}
+		parts := []string{idiom.Idiom, idiom.MeaningBrief, idiom.MeaningFull}
+		examples := []string{}
+		for _, example := range memory.examples[idiom.ID] {
+			examples = append(examples, example.Expression)
+		}
+		if len(examples) > 0 {
+			parts = append(parts, strings.Join(examples, "\n"))
+		}
+		source := strings.Join(parts, "\n")
+		sum := md5.Sum([]byte(source))
+		hash := hex.EncodeToString(sum[:])
+		if stored, ok := memory.vectors[idiom.ID]; ok && stored.model == model && stored.hash == hash {
+			continue
+		}
+		sources = append(sources, EmbeddingSource{ID: idiom.ID, Source: source, Hash: hash})
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].ID < sources[j].ID
+	})
+	if len(sources) > count {
+		sources = sources[:count]
+	}
+	return sources, nil
+}
+
+func (memory *Memory) UpdateIdiomEmbedding(ctx context.Context, source *EmbeddingSource, model string, embedding []float32) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	if _, ok := memory.idioms[source.ID]; ok {
+		memory.vectors[source.ID] = vector{embedding: embedding, model: model, hash: source.Hash}
+	}
+	return nil
+}
+
+func (memory *Memory) ListMainPageIdioms(ctx context.Context) ([]models.Idiom, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	page := &Page{Published: true, OrderBy: "published_at", Descending: true, Count: mainPageCount}
+	idioms := []models.Idiom{}
+	for _, idiom := range memory.idioms {
+		if page.matches(&idiom) {
+			idioms = append(idioms, toRow(idiom))
+		}
+	}
+	return page.sorted(idioms), nil
+}
+
+func (memory *Memory) ListExistingIdioms(ctx context.Context, ids []string) ([]string, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	existing := []string{}
+	for _, id := range ids {
+		if _, ok := memory.idioms[id]; ok {
+			existing = append(existing, id)
+		}
+	}
+	return existing, nil
+}
+
+func (memory *Memory) CreateIdiom(ctx context.Context, idiom *NewIdiom) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	if _, ok := memory.idioms[idiom.ID]; ok {
+		return &pgconn.PgError{Code: "23505"}
+	}
+	memory.idioms[idiom.ID] = models.Idiom{
+		ID:            idiom.ID,
+		Idiom:         idiom.Idiom,
+		MeaningBrief:  idiom.MeaningBrief,
+		MeaningFull:   idiom.MeaningFull,
+		CreatedAt:     ToTimestamp(time.Now()),
+		Description:   idiom.Description,
+		NumID:         int64(len(memory.idioms) + 1),
+		PromptVersion: idiom.PromptVersion,
+		Status:        idiom.Status,
+	}
+	if idiom.ThumbnailPrompt.Valid {
+		memory.prompts[idiom.ID] = idiom.ThumbnailPrompt.String
+	}
+	memory.examples[idiom.ID] = memory.newExamples(idiom.ID, idiom.Examples)
+	return nil
+}
+
+func (memory *Memory) PatchIdiom(ctx context.Context, id string, input *models.PatchIdiomInput) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	idiom, ok := memory.idioms[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if input.Idiom != nil {
+		idiom.Idiom = *input.Idiom
+	}
+	if input.MeaningBrief != nil {
+		idiom.MeaningBrief = *input.MeaningBrief
+	}
+	if input.MeaningFull != nil {
+		idiom.MeaningFull = *input.MeaningFull
+	}
+	if input.Description != nil {
+		idiom.Description = pgtype.Text{String: *input.Description, Valid: true}
+	}
+	if input.ThumbnailPrompt != nil {
+		memory.prompts[id] = *input.ThumbnailPrompt
+	}
+	memory.idioms[id] = idiom
+	return nil
+}
+
+func (memory *Memory) UpdateIdiomMeanings(ctx context.Context, id string, meaningBrief string, meaningFull string, promptVersion pgtype.Text) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	idiom, ok := memory.idioms[id]
+	if !ok {
+		return ErrNotFound
+	}
+	idiom.MeaningBrief = meaningBrief
+	idiom.MeaningFull = meaningFull
+	if promptVersion.Valid {
+		idiom.PromptVersion = promptVersion
+	}
+	memory.idioms[id] = idiom
+	return nil
+}
+
+func (memory *Memory) UpdateIdiomDescription(ctx context.Context, id string, description string, promptVersion string) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	idiom, ok := memory.idioms[id]
+	if !ok {
+		return ErrNotFound
+	}
+	idiom.Description = pgtype.Text{String: description, Valid: true}
+	idiom.PromptVersion = toText(promptVersion)
+	memory.idioms[id] = idiom
+	return nil
+}
+
+func (memory *Memory) UpdateIdiomStatus(ctx context.Context, id string, current string, status string) (*models.Idiom, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	idiom, ok := memory.idioms[id]
+	if !ok || idiom.Status != current {
+		return nil, ErrNotFound
+	}
+	idiom.Status = status
+	idiom.PublishAt = pgtype.Timestamp{}
+	if status == models.IdiomPublished && !idiom.PublishedAt.Valid {
+		idiom.PublishedAt = ToTimestamp(time.Now())
+	}
+	memory.idioms[id] = idiom
+	row := toRow(idiom)
+	return &row, nil
+}
+
+func (memory *Memory) ScheduleIdiom(ctx context.Context, id string, current string, publishAt time.Time) (*models.Idiom, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	idiom, ok := memory.idioms[id]
+	if !ok || idiom.Status != current {
+		return nil, ErrNotFound
+	}
+	idiom.PublishAt = ToTimestamp(publishAt)
+	memory.idioms[id] = idiom
+	row := toRow(idiom)
+	return &row, nil
+}
+
+func (memory *Memory) PublishScheduledIdioms(ctx context.Context) ([]string, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	now := time.Now().UTC()
+	published := []string{}
+	for id, idiom := range memory.idioms {
+		if idiom.Status != models.IdiomInReview || !idiom.PublishAt.Valid || idiom.PublishAt.Time.After(now) {
+			continue
+		}
+		idiom.Status = models.IdiomPublished
+		if !idiom.PublishedAt.Valid {
+			idiom.PublishedAt = idiom.PublishAt
+		}
+		idiom.PublishAt = pgtype.Timestamp{}
+		memory.idioms[id] = idiom
+		published = append(published, id)
+	}
+	return published, nil
+}
+
+func (memory *Memory) SetIdiomThumbnail(ctx context.Context, id string, thumbnail string, thumbnails models.TextArray) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	idiom, ok := memory.idioms[id]
+	if !ok {
+		return ErrNotFound
+	}
+	idiom.Thumbnail = pgtype.Text{String: thumbnail, Valid: true}
+	idiom.Thumbnails = thumbnails
+	memory.idioms[id] = idiom
+	return nil
+}
+
+// LockIdiom only checks that the idiom exists, as the mutex already orders the writes.
+func (memory *Memory) LockIdiom(ctx context.Context, id string) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	if _, ok := memory.idioms[id]; !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (memory *Memory) DeleteIdiom(ctx context.Context, id string) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	if _, ok := memory.idioms[id]; !ok {
+		return ErrNotFound
+	}
+	delete(memory.idioms, id)
+	delete(memory.examples, id)
+	delete(memory.prompts, id)
+	delete(memory.vectors, id)
+	for draftId, draft := range memory.drafts {
+		if draft.IdiomID == id {
+			delete(memory.drafts, draftId)
+		}
+	}
+	return nil
+}
+
+func (memory *Memory) GetIdiomInput(ctx context.Context, id string) (*models.IdiomInput, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	input, ok := memory.inputs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &input, nil
+}
+
+func (memory *Memory) ListExistingIdiomInputs(ctx context.Context, ids []string) ([]string, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	existing := []string{}
+	for _, id := range ids {
+		if _, ok := memory.inputs[id]; ok {
+			existing = append(existing, id)
+		}
+	}
+	return existing, nil
+}
+
+func (memory *Memory) CreateIdiomInputs(ctx context.Context, inputs []models.IdiomInput) ([]string, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	ids := []string{}
+	for _, input := range inputs {
+		if _, ok := memory.inputs[input.ID]; ok {
+			continue
+		}
+		input.CreatedAt = ToTimestamp(time.Now())
+		memory.inputs[input.ID] = input
+		ids = append(ids, input.ID)
+	}
+	return ids, nil
+}
+
+func (memory *Memory) DeleteIdiomInput(ctx context.Context, id string) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	delete(memory.inputs, id)
+	return nil
+}
+
+func (memory *Memory) GetThumbnailPrompt(ctx context.Context, id string) (*string, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	if _, ok := memory.idioms[id]; !ok {
+		return nil, ErrNotFound
+	}
+	prompt, ok := memory.prompts[id]
+	if !ok {
+		return nil, nil
+	}
+	return &prompt, nil
+}
+
+func (memory *Memory) UpdateThumbnailPrompt(ctx context.Context, id string, prompt string) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	if _, ok := memory.idioms[id]; !ok {
+		return ErrNotFound
+	}
+	memory.prompts[id] = prompt
+	return nil
+}
+
+func (memory *Memory) CreateThumbnailDraft(ctx context.Context, draft *models.ThumbnailDraft) (*models.ThumbnailDraft, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	memory.draftId++
+	created := *draft
+	created.ID = memory.draftId
+	created.CreatedAt = ToTimestamp(time.Now())
+	memory.drafts[created.ID] = created
+	return &created, nil
+}
+
+func (memory *Memory) GetThumbnailDraft(ctx context.Context, idiomId string, id int64) (*models.ThumbnailDraft, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	draft, ok := memory.drafts[id]
+	if !ok || draft.IdiomID != idiomId {
+		return nil, ErrNotFound
+	}
+	return &draft, nil
+}
+
+func (memory *Memory) ListThumbnailDrafts(ctx context.Context, idiomId string) ([]models.ThumbnailDraft, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	drafts := []models.ThumbnailDraft{}
+	for _, draft := range memory.drafts {
+		if draft.IdiomID == idiomId {
+			drafts = append(drafts, draft)
+		}
+	}
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].ID > drafts[j].ID
+	})
+	return drafts, nil
+}
+
+func (memory *Memory) ListExpiredThumbnailDrafts(ctx context.Context, count int) ([]models.ThumbnailDraft, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	now := time.Now().UTC()
+	drafts := []models.ThumbnailDraft{}
+	for _, draft := range memory.drafts {
+		if !draft.PromotedAt.Valid && draft.ExpiresAt.Valid && !draft.ExpiresAt.Time.After(now) {
+			drafts = append(drafts, draft)
+		}
+	}
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].ExpiresAt.Time.Before(drafts[j].ExpiresAt.Time)
+	})
+	if len(drafts) > count {
+		drafts = drafts[:count]
+	}
+	return drafts, nil
+}
+
+func (memory *Memory) PromoteThumbnailDraft(ctx context.Context, id int64, promotedBy pgtype.Text) (*models.ThumbnailDraft, error) {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	draft, ok := memory.drafts[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	draft.PromotedAt = ToTimestamp(time.Now())
+	draft.PromotedBy = promotedBy
+	draft.ExpiresAt = pgtype.Timestamp{}
+	memory.drafts[id] = draft
+	return &draft, nil
+}
+
+func (memory *Memory) DeleteThumbnailDrafts(ctx context.Context, ids []int64) error {
+	memory.mutex.Lock()
+	defer memory.mutex.Unlock()
+
+	for _, id := range ids {
+		delete(memory.drafts, id)
+	}
+	return nil
+}
+
+// toRow drops what the idiom queries do not select, as the examples, tags and categories.
+func toRow(idiom models.Idiom) models.Idiom {
+	idiom.Examples = nil
+	idiom.Tags = nil
+	idiom.Categories = nil
+	idiom.Rank = nil
+	idiom.Highlights = nil
+	return idiom
+}
+
+// matches applies the filters of the page, apart from the cursor.
+func (page *Page) matches(idiom *models.Idiom) bool {
+	if page.Published && idiom.Status != models.IdiomPublished {
+		return false
+	}
+	if len(page.Status) > 0 && idiom.Status != page.Status {
+		return false
+	}
+	for _, tag := range page.Tags {
+		found := false
+		for _, idiomTag := range idiom.Tags {
+			found = found || idiomTag.ID == tag
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(page.Category) > 0 {
+		found := false
+		for _, category := range idiom.Categories {
+			found = found || category.ID == page.Category
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// after tells whether the idiom comes after the (sort value, id) key of the cursor of the page.
+func (page *Page) after(idiom *models.Idiom) bool {
+	if page.ID == nil {
+		return true
+	}
+	cursor := &models.Idiom{ID: *page.ID, Rank: page.Rank}
+	if page.Idiom != nil {
+		cursor.Idiom = *page.Idiom
+	}
+	if page.Time != nil {
+		cursor.CreatedAt = *page.Time
+		cursor.PublishedAt = *page.Time
+	}
+	return page.beyond(page.compare(idiom, cursor))
+}
+
+func (page *Page) beyond(comparison int) bool {
+	if page.Descending {
+		return comparison < 0
+	}
+	return comparison > 0
+}
+
+// compare orders the idioms by the column of the page and then by id.
+func (page *Page) compare(left *models.Idiom, right *models.Idiom) int {
+	comparison := 0
+	switch page.OrderBy {
+	case "idiom":
+		{
+			comparison = strings.Compare(left.Idiom, right.Idiom)
+			break
+		}
+	case "rank":
+		{
+			if left.Rank != nil && right.Rank != nil {
+				comparison = compareFloat(*left.Rank, *right.Rank)
+			}
+			break
+		}
+	case "created_at":
+		{
+			comparison = compareTimestamp(left.CreatedAt, right.CreatedAt)
+			break
+		}
+	default:
+		{
+			comparison = compareTimestamp(left.PublishedAt, right.PublishedAt)
+		}
+	}
+	if comparison == 0 {
+		comparison = strings.Compare(left.ID, right.ID)
+	}
+	return comparison
+}
+
+// sorted orders the idioms in the direction of the page and keeps the first Count of them.
+func (page *Page) sorted(idioms []models.Idiom) []models.Idiom {
+	sort.Slice(idioms, func(i, j int) bool {
+		return page.beyond(page.compare(&idioms[j], &idioms[i]))
+	})
+	if page.Count >= 0 && len(idioms) > page.Count {
+		idioms = idioms[:page.Count]
+	}
+	return idioms
+}
+
+// compareTimestamp compares the timestamps with null as infinity, like coalesce(published_at, 'infinity').
+func compareTimestamp(left pgtype.Timestamp, right pgtype.Timestamp) int {
+	leftInfinite := !left.Valid || left.InfinityModifier == pgtype.Infinity
+	rightInfinite := !right.Valid || right.InfinityModifier == pgtype.Infinity
+	switch {
+	case leftInfinite && rightInfinite:
+		{
+			return 0
+		}
+	case leftInfinite:
+		{
+			return 1
+		}
+	case rightInfinite:
+		{
+			return -1
+		}
+	}
+	return left.Time.Compare(right.Time)
+}
+
+// cosineDistance is the <=> operator of pgvector, apart from a zero vector being the farthest.
+func cosineDistance(left []float32, right []float32) float64 {
+	dot, leftNorm, rightNorm := 0.0, 0.0, 0.0
+	for index := range left {
+		dot += float64(left[index]) * float64(right[index])
+		leftNorm += float64(left[index]) * float64(left[index])
+		rightNorm += float64(right[index]) * float64(right[index])
+	}
+	if leftNorm == 0 || rightNorm == 0 {
+		return 2
+	}
+	return 1 - dot/math.Sqrt(leftNorm*rightNorm)
+}
+
+func compareFloat(left float64, right float64) int {
+	switch {
+	case left < right:
+		{
+			return -1
+		}
+	case left > right:
+		{
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,734 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/generated"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+// ErrNotFound is returned when the row looked up by id does not exist.
+var ErrNotFound = errors.New("not found")
+
+// Page selects a page of idioms, read in the Descending direction. With an ID, the page starts
+// after the idiom with that id and the sort value of OrderBy in Idiom, Time or Rank.
+// Time is infinity for an idiom which was never published.
+type Page struct {
+	Published  bool
+	Status     string
+	Tags       []string
+	Category   string
+	Keyword    string
+	OrderBy    string
+	Descending bool
+	Count      int
+
+	Idiom *string
+	Time  *pgtype.Timestamp
+	Rank  *float64
+	ID    *string
+}
+
+// EmbeddingSource is the text embedded for an idiom, with the hash stored next to its embedding.
+type EmbeddingSource struct {
+	ID     string
+	Source string
+	Hash   string
+}
+
+// NewIdiom is an idiom to insert, with its examples in order.
+type NewIdiom struct {
+	ID              string
+	Idiom           string
+	MeaningBrief    string
+	MeaningFull     string
+	Description     pgtype.Text
+	ThumbnailPrompt pgtype.Text
+	PromptVersion   pgtype.Text
+	Status          string
+	Examples        []string
+}
+
+// DB begins the transactions the services share between IdiomRepository.WithTx and
+// revisions, tags and jobs, which still run on sqlx.
+type DB interface {
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*sqlx.Tx, error)
+}
+
+type IdiomRepository interface {
+	// WithTx runs the queries of the repository it returns in the transaction.
+	WithTx(tx *sqlx.Tx) IdiomRepository
+	GetIdiom(ctx context.Context, id string, published bool) (*models.Idiom, error)
+	GetIdiomStatus(ctx context.Context, id string) (string, error)
+	IdiomExists(ctx context.Context, id string) (bool, error)
+	GetExamples(ctx context.Context, idiomId string) ([]models.IdiomExample, error)
+	GetExample(ctx context.Context, idiomId string, id int64) (*models.IdiomExample, error)
+	CountExamples(ctx context.Context, idiomId string) (int, error)
+	CreateExample(ctx context.Context, idiomId string, expression string, position int) (*models.IdiomExample, error)
+	ReplaceExamples(ctx context.Context, idiomId string, expressions []string) error
+	UpdateExample(ctx context.Context, id int64, expression *string, position *int) (*models.IdiomExample, error)
+	ShiftExamples(ctx context.Context, idiomId string, from int, to int, shift int) error
+	ReorderExamples(ctx context.Context, idiomId string, ids []int64) error
+	DeleteExample(ctx context.Context, id int64) error
+	GetTags(ctx context.Context, idiomId string) ([]models.Tag, error)
+	GetCategories(ctx context.Context, idiomId string) ([]models.Category, error)
+	ListIdioms(ctx context.Context, page *Page) ([]models.Idiom, error)
+	SearchIdioms(ctx context.Context, page *Page) ([]models.Idiom, error)
+	ListRelatedIdioms(ctx context.Context, id string) ([]models.Idiom, error)
+	ListSimilarIdioms(ctx context.Context, id string, count int) ([]models.Idiom, error)
+	ListIdiomsToEmbed(ctx context.Context, model string, count int) ([]EmbeddingSource, error)
+	UpdateIdiomEmbedding(ctx context.Context, source *EmbeddingSource, model string, embedding []float32) error
+	ListMainPageIdioms(ctx context.Context) ([]models.Idiom, error)
+	ListExistingIdioms(ctx context.Context, ids []string) ([]string, error)
+	CreateIdiom(ctx context.Context, idiom *NewIdiom) error
+	PatchIdiom(ctx context.Context, id string, input *models.PatchIdiomInput) error
+	UpdateIdiomMeanings(ctx context.Context, id string, meaningBrief string, meaningFull string, promptVersion pgtype.Text) error
+	UpdateIdiomDescription(ctx context.Context, id string, description string, promptVersion string) error
+	UpdateIdiomStatus(ctx context.Context, id string, current string, status string) (*models.Idiom, error)
+	ScheduleIdiom(ctx context.Context, id string, current string, publishAt time.Time) (*models.Idiom, error)
+	PublishScheduledIdioms(ctx context.Context) ([]string, error)
+	SetIdiomThumbnail(ctx context.Context, id string, thumbnail string, thumbnails models.TextArray) error
+	LockIdiom(ctx context.Context, id string) error
+	DeleteIdiom(ctx context.Context, id string) error
+	GetIdiomInput(ctx context.Context, id string) (*models.IdiomInput, error)
+	ListExistingIdiomInputs(ctx context.Context, ids []string) ([]string, error)
+	CreateIdiomInputs(ctx context.Context, inputs []models.IdiomInput) ([]string, error)
+	DeleteIdiomInput(ctx context.Context, id string) error
+	GetThumbnailPrompt(ctx context.Context, id string) (*string, error)
+	UpdateThumbnailPrompt(ctx context.Context, id string, prompt string) error
+	CreateThumbnailDraft(ctx context.Context, draft *models.ThumbnailDraft) (*models.ThumbnailDraft, error)
+	GetThumbnailDraft(ctx context.Context, idiomId string, id int64) (*models.ThumbnailDraft, error)
+	ListThumbnailDrafts(ctx context.Context, idiomId string) ([]models.ThumbnailDraft, error)
+	ListExpiredThumbnailDrafts(ctx context.Context, count int) ([]models.ThumbnailDraft, error)
+	PromoteThumbnailDraft(ctx context.Context, id int64, promotedBy pgtype.Text) (*models.ThumbnailDraft, error)
+	DeleteThumbnailDrafts(ctx context.Context, ids []int64) error
+}
+
+// Repository runs the queries generated by sqlc from engine/query.sql.
+type Repository struct {
+	queries *generated.Queries
+}
+
+func NewRepository(db generated.DBTX) *Repository {
+	repository := new(Repository)
+	repository.queries = generated.New(db)
+
+	return repository
+}
+
+func (repository *Repository) WithTx(tx *sqlx.Tx) IdiomRepository {
+	transactional := new(Repository)
+	transactional.queries = repository.queries.WithTx(tx.Tx)
+
+	return transactional
+}
+
+func (repository *Repository) GetIdiom(ctx context.Context, id string, published bool) (*models.Idiom, error) {
+	row, err := repository.queries.GetIdiom(ctx, generated.GetIdiomParams{ID: id, Published: published})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toIdiom(generated.ListIdiomsRow(row))
+}
+
+func (repository *Repository) GetIdiomStatus(ctx context.Context, id string) (string, error) {
+	status, err := repository.queries.GetIdiomStatus(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return status, err
+}
+
+func (repository *Repository) IdiomExists(ctx context.Context, id string) (bool, error) {
+	return repository.queries.IdiomExists(ctx, id)
+}
+
+func (repository *Repository) GetExamples(ctx context.Context, idiomId string) ([]models.IdiomExample, error) {
+	rows, err := repository.queries.ListIdiomExamples(ctx, idiomId)
+	if err != nil {
+		return nil, err
+	}
+	examples := []models.IdiomExample{}
+	for _, row := range rows {
+		examples = append(examples, *toExample(row))
+	}
+	return examples, nil
+}
+
+func (repository *Repository) GetExample(ctx context.Context, idiomId string, id int64) (*models.IdiomExample, error) {
+	row, err := repository.queries.GetIdiomExample(ctx, generated.GetIdiomExampleParams{IdiomID: idiomId, ID: id})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toExample(generated.ListIdiomExamplesRow(row)), nil
+}
+
+func (repository *Repository) CountExamples(ctx context.Context, idiomId string) (int, error) {
+	count, err := repository.queries.CountIdiomExamples(ctx, idiomId)
+	return int(count), err
+}
+
+func (repository *Repository) CreateExample(ctx context.Context, idiomId string, expression string, position int) (*models.IdiomExample, error) {
+	row, err := repository.queries.CreateIdiomExample(ctx, generated.CreateIdiomExampleParams{IdiomID: idiomId, Expression: expression, Position: int32(position)})
+	if err != nil {
+		return nil, err
+	}
+	return toExample(generated.ListIdiomExamplesRow(row)), nil
+}
+
+// ReplaceExamples deletes the examples of the idiom and inserts the expressions in their place.
+// It belongs in a transaction, as the idiom has no examples in between.
+func (repository *Repository) ReplaceExamples(ctx context.Context, idiomId string, expressions []string) error {
+	err := repository.queries.DeleteIdiomExamples(ctx, idiomId)
+	if err != nil {
+		return err
+	}
+	return repository.queries.CreateIdiomExamples(ctx, generated.CreateIdiomExamplesParams{IdiomID: idiomId, Expressions: toArray(expressions)})
+}
+
+func (repository *Repository) UpdateExample(ctx context.Context, id int64, expression *string, position *int) (*models.IdiomExample, error) {
+	params := generated.UpdateIdiomExampleParams{Expression: ToNullableText(expression), ID: id}
+	if position != nil {
+		params.Position = pgtype.Int4{Int32: int32(*position), Valid: true}
+	}
+	row, err := repository.queries.UpdateIdiomExample(ctx, params)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toExample(generated.ListIdiomExamplesRow(row)), nil
+}
+
+func (repository *Repository) ShiftExamples(ctx context.Context, idiomId string, from int, to int, shift int) error {
+	return repository.queries.ShiftIdiomExamples(ctx, generated.ShiftIdiomExamplesParams{
+		Shift:        int32(shift),
+		IdiomID:      idiomId,
+		FromPosition: int32(from),
+		ToPosition:   int32(to),
+	})
+}
+
+func (repository *Repository) ReorderExamples(ctx context.Context, idiomId string, ids []int64) error {
+	return repository.queries.ReorderIdiomExamples(ctx, generated.ReorderIdiomExamplesParams{IdiomID: idiomId, Ids: ids})
+}
+
+func (repository *Repository) DeleteExample(ctx context.Context, id int64) error {
+	return repository.queries.DeleteIdiomExample(ctx, id)
+}
+
+func (repository *Repository) GetTags(ctx context.Context, idiomId string) ([]models.Tag, error) {
+	rows, err := repository.queries.ListIdiomTags(ctx, idiomId)
+	if err != nil {
+		return nil, err
+	}
+	tags := []models.Tag{}
+	for _, row := range rows {
+		tags = append(tags, models.Tag{ID: row.ID, Name: row.Name, CreatedAt: row.CreatedAt})
+	}
+	return tags, nil
+}
+
+func (repository *Repository) GetCategories(ctx context.Context, idiomId string) ([]models.Category, error) {
+	rows, err := repository.queries.ListIdiomCategories(ctx, idiomId)
+	if err != nil {
+		return nil, err
+	}
+	categories := []models.Category{}
+	for _, row := range rows {
+		categories = append(categories, models.Category{ID: row.ID, Name: row.Name, Description: row.Description, CreatedAt: row.CreatedAt})
+	}
+	return categories, nil
+}
+
+func (repository *Repository) ListIdioms(ctx context.Context, page *Page) ([]models.Idiom, error) {
+	params := generated.ListIdiomsParams{
+		Published:   page.Published,
+		Status:      toText(page.Status),
+		Tags:        toArray(page.Tags),
+		Category:    toText(page.Category),
+		CursorID:    ToNullableText(page.ID),
+		OrderBy:     page.OrderBy,
+		Descending:  page.Descending,
+		CursorIdiom: ToNullableText(page.Idiom),
+		Count:       int32(page.Count),
+	}
+	if page.Time != nil {
+		params.CursorTime = *page.Time
+	}
+	rows, err := repository.queries.ListIdioms(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	idioms := []models.Idiom{}
+	for _, row := range rows {
+		idiom, err := toIdiom(row)
+		if err != nil {
+			return nil, err
+		}
+		idioms = append(idioms, *idiom)
+	}
+	return idioms, nil
+}
+
+func (repository *Repository) SearchIdioms(ctx context.Context, page *Page) ([]models.Idiom, error) {
+	params := generated.SearchIdiomsParams{
+		Query:       toSearchQuery(page.Keyword),
+		Keyword:     page.Keyword,
+		Pattern:     "%" + escapeLike(page.Keyword) + "%",
+		Published:   page.Published,
+		Tags:        toArray(page.Tags),
+		Category:    toText(page.Category),
+		CursorID:    ToNullableText(page.ID),
+		OrderBy:     page.OrderBy,
+		Descending:  page.Descending,
+		CursorIdiom: ToNullableText(page.Idiom),
+		Count:       int32(page.Count),
+	}
+	if page.Rank != nil {
+		params.CursorRank = pgtype.Float8{Float64: *page.Rank, Valid: true}
+	}
+	if page.Time != nil {
+		params.CursorTime = *page.Time
+	}
+	rows, err := repository.queries.SearchIdioms(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	idioms := []models.Idiom{}
+	for _, row := range rows {
+		idiom, err := toIdiom(generated.ListIdiomsRow{
+			ID:            row.ID,
+			Idiom:         row.Idiom,
+			MeaningBrief:  row.MeaningBrief,
+			MeaningFull:   row.MeaningFull,
+			CreatedAt:     row.CreatedAt,
+			PublishedAt:   row.PublishedAt,
+			Thumbnail:     row.Thumbnail,
+			Thumbnails:    row.Thumbnails,
+			Description:   row.Description,
+			NumID:         row.NumID,
+			PromptVersion: row.PromptVersion,
+			Status:        row.Status,
+			PublishAt:     row.PublishAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		rank := row.Rank
+		idiom.Rank = &rank
+		idiom.Highlights = &models.IdiomHighlight{
+			Idiom:        row.HighlightIdiom,
+			MeaningBrief: row.HighlightMeaningBrief,
+			MeaningFull:  row.HighlightMeaningFull,
+			Description:  row.HighlightDescription,
+			Examples:     row.HighlightExamples,
+		}
+		idioms = append(idioms, *idiom)
+	}
+	return idioms, nil
+}
+
+func (repository *Repository) ListRelatedIdioms(ctx context.Context, id string) ([]models.Idiom, error) {
+	rows, err := repository.queries.ListRelatedIdioms(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	idioms := []models.Idiom{}
+	for _, row := range rows {
+		idiom, err := toIdiom(generated.ListIdiomsRow(row))
+		if err != nil {
+			return nil, err
+		}
+		idioms = append(idioms, *idiom)
+	}
+	return idioms, nil
+}
+
+func (repository *Repository) ListSimilarIdioms(ctx context.Context, id string, count int) ([]models.Idiom, error) {
+	rows, err := repository.queries.ListSimilarIdioms(ctx, generated.ListSimilarIdiomsParams{ID: id, Count: int32(count)})
+	if err != nil {
+		return nil, err
+	}
+	idioms := []models.Idiom{}
+	for _, row := range rows {
+		idiom, err := toIdiom(generated.ListIdiomsRow(row))
+		if err != nil {
+			return nil, err
+		}
+		idioms = append(idioms, *idiom)
+	}
+	return idioms, nil
+}
+
+func (repository *Repository) ListIdiomsToEmbed(ctx context.Context, model string, count int) ([]EmbeddingSource, error) {
+	rows, err := repository.queries.ListIdiomsToEmbed(ctx, generated.ListIdiomsToEmbedParams{Model: model, Count: int32(count)})
+	if err != nil {
+		return nil, err
+	}
+	sources := []EmbeddingSource{}
+	for _, row := range rows {
+		sources = append(sources, EmbeddingSource{ID: row.ID, Source: row.Source, Hash: row.ContentHash.String})
+	}
+	return sources, nil
+}
+
+func (repository *Repository) UpdateIdiomEmbedding(ctx context.Context, source *EmbeddingSource, model string, embedding []float32) error {
+	return repository.queries.UpdateIdiomEmbedding(ctx, generated.UpdateIdiomEmbeddingParams{
+		Embedding: toVector(embedding),
+		Model:     toText(model),
+		Hash:      toText(source.Hash),
+		ID:        source.ID,
+	})
+}
+
+func (repository *Repository) ListMainPageIdioms(ctx context.Context) ([]models.Idiom, error) {
+	rows, err := repository.queries.ListMainPageIdioms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	idioms := []models.Idiom{}
+	for _, row := range rows {
+		idiom, err := toIdiom(generated.ListIdiomsRow(row))
+		if err != nil {
+			return nil, err
+		}
+		idioms = append(idioms, *idiom)
+	}
+	return idioms, nil
+}
+
+func (repository *Repository) ListExistingIdioms(ctx context.Context, ids []string) ([]string, error) {
+	return repository.queries.ListIdiomIDs(ctx, toArray(ids))
+}
+
+// CreateIdiom inserts the idiom and its examples, which belongs in a transaction.
+func (repository *Repository) CreateIdiom(ctx context.Context, idiom *NewIdiom) error {
+	err := repository.queries.CreateIdiom(ctx, generated.CreateIdiomParams{
+		ID:              idiom.ID,
+		Idiom:           idiom.Idiom,
+		MeaningBrief:    idiom.MeaningBrief,
+		MeaningFull:     idiom.MeaningFull,
+		Description:     idiom.Description,
+		ThumbnailPrompt: idiom.ThumbnailPrompt,
+		PromptVersion:   idiom.PromptVersion,
+		Status:          idiom.Status,
+	})
+	if err != nil || len(idiom.Examples) == 0 {
+		return err
+	}
+	return repository.queries.CreateIdiomExamples(ctx, generated.CreateIdiomExamplesParams{IdiomID: idiom.ID, Expressions: idiom.Examples})
+}
+
+func (repository *Repository) PatchIdiom(ctx context.Context, id string, input *models.PatchIdiomInput) error {
+	affected, err := repository.queries.PatchIdiom(ctx, generated.PatchIdiomParams{
+		Idiom:           ToNullableText(input.Idiom),
+		MeaningBrief:    ToNullableText(input.MeaningBrief),
+		MeaningFull:     ToNullableText(input.MeaningFull),
+		Description:     ToNullableText(input.Description),
+		ThumbnailPrompt: ToNullableText(input.ThumbnailPrompt),
+		ID:              id,
+	})
+	return toAffected(affected, err)
+}
+
+func (repository *Repository) UpdateIdiomMeanings(ctx context.Context, id string, meaningBrief string, meaningFull string, promptVersion pgtype.Text) error {
+	affected, err := repository.queries.UpdateIdiomMeanings(ctx, generated.UpdateIdiomMeaningsParams{
+		MeaningBrief:  meaningBrief,
+		MeaningFull:   meaningFull,
+		PromptVersion: promptVersion,
+		ID:            id,
+	})
+	return toAffected(affected, err)
+}
+
+func (repository *Repository) UpdateIdiomDescription(ctx context.Context, id string, description string, promptVersion string) error {
+	affected, err := repository.queries.UpdateIdiomDescription(ctx, generated.UpdateIdiomDescriptionParams{
+		Description:   pgtype.Text{String: description, Valid: true},
+		PromptVersion: toText(promptVersion),
+		ID:            id,
+	})
+	return toAffected(affected, err)
+}
+
+// UpdateIdiomStatus returns ErrNotFound when the idiom is not in the current status anymore.
+func (repository *Repository) UpdateIdiomStatus(ctx context.Context, id string, current string, status string) (*models.Idiom, error) {
+	row, err := repository.queries.UpdateIdiomStatus(ctx, generated.UpdateIdiomStatusParams{Status: status, ID: id, Current: current})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toIdiom(generated.ListIdiomsRow(row))
+}
+
+// ScheduleIdiom returns ErrNotFound when the idiom is not in the current status anymore.
+func (repository *Repository) ScheduleIdiom(ctx context.Context, id string, current string, publishAt time.Time) (*models.Idiom, error) {
+	row, err := repository.queries.ScheduleIdiom(ctx, generated.ScheduleIdiomParams{PublishAt: ToTimestamp(publishAt), ID: id, Current: current})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toIdiom(generated.ListIdiomsRow(row))
+}
+
+func (repository *Repository) PublishScheduledIdioms(ctx context.Context) ([]string, error) {
+	return repository.queries.PublishScheduledIdioms(ctx)
+}
+
+func (repository *Repository) SetIdiomThumbnail(ctx context.Context, id string, thumbnail string, thumbnails models.TextArray) error {
+	encoded, err := json.Marshal(thumbnails)
+	if err != nil {
+		return err
+	}
+	affected, err := repository.queries.SetIdiomThumbnail(ctx, generated.SetIdiomThumbnailParams{
+		Thumbnail:  pgtype.Text{String: thumbnail, Valid: true},
+		Thumbnails: encoded,
+		ID:         id,
+	})
+	return toAffected(affected, err)
+}
+
+func (repository *Repository) LockIdiom(ctx context.Context, id string) error {
+	_, err := repository.queries.LockIdiom(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (repository *Repository) DeleteIdiom(ctx context.Context, id string) error {
+	affected, err := repository.queries.DeleteIdiom(ctx, id)
+	return toAffected(affected, err)
+}
+
+func (repository *Repository) GetIdiomInput(ctx context.Context, id string) (*models.IdiomInput, error) {
+	row, err := repository.queries.GetIdiomInput(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.IdiomInput{ID: row.ID, Idiom: row.Idiom, Meaning: row.Meaning, CreatedAt: row.CreatedAt}, nil
+}
+
+func (repository *Repository) ListExistingIdiomInputs(ctx context.Context, ids []string) ([]string, error) {
+	return repository.queries.ListIdiomInputIDs(ctx, toArray(ids))
+}
+
+// CreateIdiomInputs inserts the inputs whose id is not in idiom_inputs yet and returns their ids.
+func (repository *Repository) CreateIdiomInputs(ctx context.Context, inputs []models.IdiomInput) ([]string, error) {
+	params := generated.CreateIdiomInputsParams{Ids: []string{}, Idioms: []string{}, Meanings: []string{}}
+	for _, input := range inputs {
+		params.Ids = append(params.Ids, input.ID)
+		params.Idioms = append(params.Idioms, input.Idiom)
+		params.Meanings = append(params.Meanings, input.Meaning)
+	}
+	return repository.queries.CreateIdiomInputs(ctx, params)
+}
+
+func (repository *Repository) DeleteIdiomInput(ctx context.Context, id string) error {
+	return repository.queries.DeleteIdiomInput(ctx, id)
+}
+
+func (repository *Repository) GetThumbnailPrompt(ctx context.Context, id string) (*string, error) {
+	prompt, err := repository.queries.GetThumbnailPrompt(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil || !prompt.Valid {
+		return nil, err
+	}
+	return &prompt.String, nil
+}
+
+func (repository *Repository) UpdateThumbnailPrompt(ctx context.Context, id string, prompt string) error {
+	affected, err := repository.queries.UpdateThumbnailPrompt(ctx, generated.UpdateThumbnailPromptParams{
+		ThumbnailPrompt: pgtype.Text{String: prompt, Valid: true},
+		ID:              id,
+	})
+	return toAffected(affected, err)
+}
+
+func (repository *Repository) CreateThumbnailDraft(ctx context.Context, draft *models.ThumbnailDraft) (*models.ThumbnailDraft, error) {
+	row, err := repository.queries.CreateThumbnailDraft(ctx, generated.CreateThumbnailDraftParams{
+		IdiomID:     draft.IdiomID,
+		Prompt:      draft.Prompt,
+		Model:       draft.Model,
+		StorageKey:  draft.StorageKey,
+		ContentType: draft.ContentType,
+		CreatedBy:   draft.CreatedBy,
+		ExpiresAt:   draft.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toThumbnailDraft(row), nil
+}
+
+func (repository *Repository) GetThumbnailDraft(ctx context.Context, idiomId string, id int64) (*models.ThumbnailDraft, error) {
+	row, err := repository.queries.GetThumbnailDraft(ctx, generated.GetThumbnailDraftParams{IdiomID: idiomId, ID: id})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toThumbnailDraft(row), nil
+}
+
+func (repository *Repository) ListThumbnailDrafts(ctx context.Context, idiomId string) ([]models.ThumbnailDraft, error) {
+	rows, err := repository.queries.ListThumbnailDrafts(ctx, idiomId)
+	if err != nil {
+		return nil, err
+	}
+	return toThumbnailDrafts(rows), nil
+}
+
+func (repository *Repository) ListExpiredThumbnailDrafts(ctx context.Context, count int) ([]models.ThumbnailDraft, error) {
+	rows, err := repository.queries.ListExpiredThumbnailDrafts(ctx, int32(count))
+	if err != nil {
+		return nil, err
+	}
+	return toThumbnailDrafts(rows), nil
+}
+
+func (repository *Repository) PromoteThumbnailDraft(ctx context.Context, id int64, promotedBy pgtype.Text) (*models.ThumbnailDraft, error) {
+	row, err := repository.queries.PromoteThumbnailDraft(ctx, generated.PromoteThumbnailDraftParams{ID: id, PromotedBy: promotedBy})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toThumbnailDraft(row), nil
+}
+
+func (repository *Repository) DeleteThumbnailDrafts(ctx context.Context, ids []int64) error {
+	return repository.queries.DeleteThumbnailDrafts(ctx, ids)
+}
+
+// toIdiom converts the rows of every query selecting the idiom columns, which sqlc
+// generates as separate types with the same fields.
+func toIdiom(row generated.ListIdiomsRow) (*models.Idiom, error) {
+	idiom := &models.Idiom{
+		ID:            row.ID,
+		Idiom:         row.Idiom,
+		MeaningBrief:  row.MeaningBrief,
+		MeaningFull:   row.MeaningFull,
+		CreatedAt:     row.CreatedAt,
+		PublishedAt:   row.PublishedAt,
+		Thumbnail:     row.Thumbnail,
+		Description:   row.Description,
+		NumID:         row.NumID,
+		PromptVersion: row.PromptVersion,
+		Status:        row.Status,
+		PublishAt:     row.PublishAt,
+		Examples:      []string{},
+	}
+	if len(row.Thumbnails) > 0 {
+		err := idiom.Thumbnails.Scan(row.Thumbnails)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return idiom, nil
+}
+
+func toExample(row generated.ListIdiomExamplesRow) *models.IdiomExample {
+	return &models.IdiomExample{
+		ID:         row.ID,
+		IdiomID:    row.IdiomID,
+		Expression: row.Expression,
+		Position:   int(row.Position),
+	}
+}
+
+func toThumbnailDraft(row generated.ThumbnailDraft) *models.ThumbnailDraft {
+	return &models.ThumbnailDraft{
+		ID:          row.ID,
+		IdiomID:     row.IdiomID,
+		Prompt:      row.Prompt,
+		Model:       row.Model,
+		StorageKey:  row.StorageKey,
+		ContentType: row.ContentType,
+		CreatedBy:   row.CreatedBy,
+		CreatedAt:   row.CreatedAt,
+		ExpiresAt:   row.ExpiresAt,
+		PromotedAt:  row.PromotedAt,
+		PromotedBy:  row.PromotedBy,
+	}
+}
+
+func toThumbnailDrafts(rows []generated.ThumbnailDraft) []models.ThumbnailDraft {
+	drafts := []models.ThumbnailDraft{}
+	for _, row := range rows {
+		drafts = append(drafts, *toThumbnailDraft(row))
+	}
+	return drafts
+}
+
+// toVector writes the embedding in the text format of pgvector, which saves registering the vector type with pgx.
+func toVector(embedding []float32) string {
+	values := make([]string, len(embedding))
+	for index, value := range embedding {
+		values[index] = strconv.FormatFloat(float64(value), 'f', -1, 32)
+	}
+	return "[" + strings.Join(values, ",") + "]"
+}
+
+// toAffected is ErrNotFound when a write by id changed no rows.
+func toAffected(affected int64, err error) error {
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// toText maps an empty string to null, which the queries read as no filter.
+func toText(value string) pgtype.Text {
+	return pgtype.Text{String: value, Valid: len(value) > 0}
+}
+
+// ToNullableText is null for a nil value.
+func ToNullableText(value *string) pgtype.Text {
+	if value == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *value, Valid: true}
+}
+
+// toArray keeps an array empty rather than null, since cardinality(null) is null.
+func toArray(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// ToTimestamp is a non-null timestamp of t in UTC, the time zone every timestamp column is stored in.
+func ToTimestamp(t time.Time) pgtype.Timestamp {
+	return pgtype.Timestamp{Time: t.UTC(), Valid: true}
+}
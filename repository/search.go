@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var searchWordMatcher = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// toSearchQuery turns free text into a prefix-matching tsquery such as "thick:* | thin:*".
+func toSearchQuery(keyword string) string {
+	words := searchWordMatcher.FindAllString(strings.ToLower(keyword), -1)
+	terms := []string{}
+	for _, word := range words {
+		terms = append(terms, fmt.Sprintf("%s:*", word))
+	}
+	return strings.Join(terms, " | ")
+}
+
+func escapeLike(keyword string) string {
+	return likeEscaper.Replace(keyword)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import "testing"
+
+func TestSearchQuery(t *testing.T) {
+	cases := map[string]string{
+		"through thick and thin": "through:* | thick:* | and:* | thin:*",
+		"  Bite the BULLET!  ":   "bite:* | the:* | bullet:*",
+		"a":                      "a:*",
+		"it's (raining) & cats":  "it:* | s:* | raining:* | cats:*",
+		"!!!":                    "",
+	}
+	for keyword, expected := range cases {
+		received := toSearchQuery(keyword)
+		if received != expected {
+			t.Errorf("Expected %q, received %q", expected, received)
+		}
+	}
+}
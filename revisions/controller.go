@@ -0,0 +1,96 @@
+package revisions
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+)
+
+type RevisionController interface {
+	GetRevisions(writer http.ResponseWriter, request *http.Request)
+	GetRevision(writer http.ResponseWriter, request *http.Request)
+	DiffRevisions(writer http.ResponseWriter, request *http.Request)
+	RestoreRevision(writer http.ResponseWriter, request *http.Request)
+}
+
+type Controller struct {
+	revisionService RevisionService
+
+	logger logger.LoggerService
+}
+
+func NewController(revisionService RevisionService, logger logger.LoggerService) *Controller {
+	controller := new(Controller)
+	controller.revisionService = revisionService
+	controller.logger = logger
+
+	return controller
+}
+
+func (controller *Controller) GetRevisions(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	revisions, err := controller.revisionService.GetRevisions(request.Context(), idiomId)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"revisions": revisions,
+	})
+}
+
+func (controller *Controller) GetRevision(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	id, err := strconv.ParseInt(chi.URLParam(request, "revisionId"), 10, 64)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid revision id.", nil))
+		return
+	}
+	revision, err := controller.revisionService.GetRevision(request.Context(), idiomId, id)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"revision": revision,
+	})
+}
+
+func (controller *Controller) DiffRevisions(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	params := request.URL.Query()
+	from, fromError := strconv.ParseInt(params.Get("from"), 10, 64)
+	to, toError := strconv.ParseInt(params.Get("to"), 10, 64)
+	if fromError != nil || toError != nil {
+		lib.WriteError(writer, lib.NewValidationError("The from and to revision ids are required.", nil))
+		return
+	}
+	diff, err := controller.revisionService.DiffRevisions(request.Context(), idiomId, from, to)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"diff": diff,
+	})
+}
+
+func (controller *Controller) RestoreRevision(writer http.ResponseWriter, request *http.Request) {
+	idiomId := chi.URLParam(request, "id")
+	id, err := strconv.ParseInt(chi.URLParam(request, "revisionId"), 10, 64)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid revision id.", nil))
+		return
+	}
+	revision, err := controller.revisionService.RestoreRevision(request.Context(), idiomId, id)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"revision": revision,
+	})
+}
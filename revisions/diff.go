@@ -0,0 +1,54 @@
+package revisions
+
+import (
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+// Diff lists the fields changed between two revisions. Examples are compared as
+// sets, so reordering them is not reported as a change.
+func Diff(from *models.IdiomRevision, to *models.IdiomRevision) *models.RevisionDiff {
+	diff := &models.RevisionDiff{
+		IdiomID:         to.IdiomID,
+		From:            from.ID,
+		To:              to.ID,
+		Changes:         []models.RevisionChange{},
+		ExamplesAdded:   []string{},
+		ExamplesRemoved: []string{},
+	}
+	fields := []struct {
+		name string
+		from string
+		to   string
+	}{
+		{"idiom", from.Idiom, to.Idiom},
+		{"meaningBrief", from.MeaningBrief.String, to.MeaningBrief.String},
+		{"meaningFull", from.MeaningFull.String, to.MeaningFull.String},
+		{"description", from.Description.String, to.Description.String},
+		{"thumbnail", from.Thumbnail.String, to.Thumbnail.String},
+		{"promptVersion", from.PromptVersion.String, to.PromptVersion.String},
+	}
+	for _, field := range fields {
+		if field.from != field.to {
+			diff.Changes = append(diff.Changes, models.RevisionChange{Field: field.name, From: field.from, To: field.to})
+		}
+	}
+	diff.ExamplesAdded = subtract(to.Examples, from.Examples)
+	diff.ExamplesRemoved = subtract(from.Examples, to.Examples)
+	return diff
+}
+
+func subtract(values []string, removed []string) []string {
+	counts := map[string]int{}
+	for _, value := range removed {
+		counts[value]++
+	}
+	result := []string{}
+	for _, value := range values {
+		if counts[value] > 0 {
+			counts[value]--
+			continue
+		}
+		result = append(result, value)
+	}
+	return result
+}
@@ -0,0 +1,42 @@
+package revisions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+func TestDiff(t *testing.T) {
+	from := &models.IdiomRevision{
+		ID:           1,
+		IdiomID:      "break-the-ice",
+		Idiom:        "Break the ice",
+		MeaningBrief: pgtype.Text{String: "To start a conversation.", Valid: true},
+		Examples:     models.TextArray{"One.", "Two.", "Two."},
+	}
+	to := &models.IdiomRevision{
+		ID:           2,
+		IdiomID:      "break-the-ice",
+		Idiom:        "Break the ice",
+		MeaningBrief: pgtype.Text{String: "To start a friendly conversation.", Valid: true},
+		Description:  pgtype.Text{String: "A party.", Valid: true},
+		Examples:     models.TextArray{"Two.", "Three.", "One."},
+	}
+
+	diff := Diff(from, to)
+	expected := []models.RevisionChange{
+		{Field: "meaningBrief", From: "To start a conversation.", To: "To start a friendly conversation."},
+		{Field: "description", From: "", To: "A party."},
+	}
+	if !reflect.DeepEqual(diff.Changes, expected) {
+		t.Errorf("unexpected changes %+v", diff.Changes)
+	}
+	if !reflect.DeepEqual(diff.ExamplesAdded, []string{"Three."}) || !reflect.DeepEqual(diff.ExamplesRemoved, []string{"Two."}) {
+		t.Errorf("unexpected examples %v %v", diff.ExamplesAdded, diff.ExamplesRemoved)
+	}
+	if diff.From != 1 || diff.To != 2 {
+		t.Errorf("unexpected revisions %d %d", diff.From, diff.To)
+	}
+}
@@ -0,0 +1,207 @@
+package revisions
+
+import (
+	"context"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/auth"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type RevisionService interface {
+	Record(ctx context.Context, executor sqlx.ExecerContext, idiomId string, source string) error
+	GetRevisions(ctx context.Context, idiomId string) ([]models.IdiomRevision, error)
+	GetRevision(ctx context.Context, idiomId string, id int64) (*models.IdiomRevision, error)
+	DiffRevisions(ctx context.Context, idiomId string, from int64, to int64) (*models.RevisionDiff, error)
+	RestoreRevision(ctx context.Context, idiomId string, id int64) (*models.IdiomRevision, error)
+}
+
+type Service struct {
+	db     *sqlx.DB
+	logger logger.LoggerService
+}
+
+func NewService(db *sqlx.DB, logger logger.LoggerService) *Service {
+	service := new(Service)
+	service.db = db
+	service.logger = logger
+
+	return service
+}
+
+// Record snapshots the current content of an idiom. It runs on the executor of the
+// write, usually its transaction, so a revision exists for every committed change.
+func (service *Service) Record(ctx context.Context, executor sqlx.ExecerContext, idiomId string, source string) error {
+	return service.record(ctx, executor, idiomId, source, nil)
+}
+
+func (service *Service) GetRevisions(ctx context.Context, idiomId string) ([]models.IdiomRevision, error) {
+	revisions := []models.IdiomRevision{}
+	query, args, err := sq.Select(models.IdiomRevisionColumns...).
+		From("idiom_revisions").
+		Where("idiom_id = ?", idiomId).
+		OrderBy("id desc").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	err = service.db.SelectContext(ctx, &revisions, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to query revisions.", idiomId)
+		return nil, err
+	}
+	return revisions, nil
+}
+
+func (service *Service) GetRevision(ctx context.Context, idiomId string, id int64) (*models.IdiomRevision, error) {
+	revisions := []models.IdiomRevision{}
+	query, args, err := sq.Select(models.IdiomRevisionColumns...).
+		From("idiom_revisions").
+		Where("idiom_id = ? and id = ?", idiomId, id).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	err = service.db.SelectContext(ctx, &revisions, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to query the revision.", idiomId, id)
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, lib.NewNotFoundError("Revision not found.", map[string]interface{}{"idiomId": idiomId, "id": id})
+	}
+	return &revisions[0], nil
+}
+
+func (service *Service) DiffRevisions(ctx context.Context, idiomId string, from int64, to int64) (*models.RevisionDiff, error) {
+	fromRevision, err := service.GetRevision(ctx, idiomId, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := service.GetRevision(ctx, idiomId, to)
+	if err != nil {
+		return nil, err
+	}
+	return Diff(fromRevision, toRevision), nil
+}
+
+// RestoreRevision writes the content of a revision back to the idiom and records
+// the result as a new revision, so a restore can be undone like any other change.
+func (service *Service) RestoreRevision(ctx context.Context, idiomId string, id int64) (*models.IdiomRevision, error) {
+	revision, err := service.GetRevision(ctx, idiomId, id)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := service.db.BeginTxx(ctx, nil)
+	if err != nil {
+		service.logger.Error(err, "Failed to instantiate new transaction.")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	update := sq.Update("idioms").
+		Set("idiom", revision.Idiom).
+		Set("meaning_brief", revision.MeaningBrief).
+		Set("meaning_full", revision.MeaningFull).
+		Set("description", revision.Description).
+		Set("thumbnail", revision.Thumbnail).
+		Set("prompt_version", revision.PromptVersion).
+		Where("id = ?", idiomId)
+	if revision.Thumbnails != nil {
+		update = update.Set("thumbnails", revision.Thumbnails)
+	} else {
+		update = update.Set("thumbnails", nil)
+	}
+	updateQuery, updateArgs, err := update.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, updateQuery, updateArgs...)
+	if err != nil {
+		service.logger.Error(err, "Failed to restore the idiom.", idiomId, id)
+		return nil, err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return nil, lib.NewNotFoundError("Idiom not found.", map[string]string{"id": idiomId})
+	}
+
+	deleteQuery, deleteArgs, _ := sq.Delete("idiom_examples").Where("idiom_id = ?", idiomId).PlaceholderFormat(sq.Dollar).ToSql()
+	_, err = tx.ExecContext(ctx, deleteQuery, deleteArgs...)
+	if err != nil {
+		service.logger.Error(err, "Failed to delete idiom examples from database.", idiomId)
+		return nil, err
+	}
+	if len(revision.Examples) > 0 {
+		exampleQuery := sq.Insert("idiom_examples").Columns("idiom_id", "expression", "position")
+		for position, example := range revision.Examples {
+			exampleQuery = exampleQuery.Values(idiomId, example, position)
+		}
+		exampleSql, exampleArgs, _ := exampleQuery.PlaceholderFormat(sq.Dollar).ToSql()
+		_, err = tx.ExecContext(ctx, exampleSql, exampleArgs...)
+		if err != nil {
+			service.logger.Error(err, "Failed to insert idiom examples.", idiomId)
+			return nil, err
+		}
+	}
+
+	query, args, err := service.insertRevision(ctx, idiomId, models.RevisionSourceAdmin, &revision.ID).
+		Suffix("returning " + strings.Join(models.IdiomRevisionColumns, ", ")).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+	restored := new(models.IdiomRevision)
+	err = tx.GetContext(ctx, restored, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to record a revision.", idiomId, models.RevisionSourceAdmin)
+		return nil, err
+	}
+	return restored, tx.Commit()
+}
+
+func (service *Service) record(ctx context.Context, executor sqlx.ExecerContext, idiomId string, source string, restoredFrom *int64) error {
+	query, args, err := service.insertRevision(ctx, idiomId, source, restoredFrom).ToSql()
+	if err != nil {
+		return err
+	}
+	_, err = executor.ExecContext(ctx, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to record a revision.", idiomId, source)
+		return err
+	}
+	return nil
+}
+
+// insertRevision snapshots the idiom as it is in the statement, with the author of the request in ctx.
+func (service *Service) insertRevision(ctx context.Context, idiomId string, source string, restoredFrom *int64) sq.InsertBuilder {
+	var author *string
+	if principal := auth.PrincipalFromContext(ctx); principal != nil {
+		author = &principal.Subject
+	}
+	snapshot := sq.Select(
+		"idioms.id",
+		"idioms.idiom",
+		"idioms.meaning_brief",
+		"idioms.meaning_full",
+		"idioms.description",
+		"coalesce((select jsonb_agg(expression order by position, id) from idiom_examples where idiom_id = idioms.id), '[]'::jsonb)",
+		"idioms.thumbnail",
+		"idioms.thumbnails",
+		"idioms.prompt_version",
+	).
+		Column("?", source).
+		Column("?", author).
+		Column("?::bigint", restoredFrom).
+		From("idioms").
+		Where("idioms.id = ?", idiomId)
+	return sq.Insert("idiom_revisions").
+		Columns("idiom_id", "idiom", "meaning_brief", "meaning_full", "description", "examples", "thumbnail", "thumbnails", "prompt_version", "source", "author", "restored_from").
+		Select(snapshot).
+		PlaceholderFormat(sq.Dollar)
+}
@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type LocalService struct {
+	root      string
+	publicURL string
+	secret    []byte
+}
+
+// NewLocalService stores objects under root. Upload URLs are signed with secret, or
+// with a random key valid until the process exits when it is empty.
+func NewLocalService(root string, publicURL string, secret string) (*LocalService, error) {
+	if len(root) == 0 {
+		return nil, errors.New("local storage directory is required")
+	}
+	absolute, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(absolute, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	if len(publicURL) == 0 {
+		publicURL = "/storage"
+	}
+
+	service := new(LocalService)
+	service.root = absolute
+	service.publicURL = strings.TrimRight(publicURL, "/")
+	service.secret = []byte(secret)
+	if len(service.secret) == 0 {
+		service.secret = make([]byte, 32)
+		_, err = rand.Read(service.secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return service, nil
+}
+
+func (service *LocalService) PutObject(ctx context.Context, key string, body io.Reader, option *PutOption) error {
+	filePath, err := service.resolve(key)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(filePath), 0o755)
+	if err != nil {
+		return err
+	}
+	temp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	_, err = io.Copy(temp, body)
+	if err != nil {
+		temp.Close()
+		return err
+	}
+	err = temp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), filePath)
+}
+
+func (service *LocalService) GetObject(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	filePath, err := service.resolve(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, service.toObject(key, info), nil
+}
+
+func (service *LocalService) HeadObject(ctx context.Context, key string) (*Object, error) {
+	filePath, err := service.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filePath)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return service.toObject(key, info), nil
+}
+
+func (service *LocalService) DeleteObject(ctx context.Context, key string) error {
+	filePath, err := service.resolve(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (service *LocalService) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	objects := []Object{}
+	err := filepath.WalkDir(service.root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+		relative, err := filepath.Rel(service.root, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(relative)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, *service.toObject(key, info))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+func (service *LocalService) PublicURL(key string) string {
+	return fmt.Sprintf("%s/%s", service.publicURL, key)
+}
+
+func (service *LocalService) PresignPut(ctx context.Context, key string, option *PresignOption) (*PresignedRequest, error) {
+	_, err := service.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	expires := option.Expires
+	if expires <= 0 {
+		expires = DefaultPresignExpires
+	}
+	expiresAt := time.Now().Add(expires).UTC()
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expiresAt.Unix(), 10))
+	query.Set("contentType", option.ContentType)
+	query.Set("contentLength", strconv.FormatInt(option.ContentLength, 10))
+	query.Set("signature", service.sign(key, query))
+	return &PresignedRequest{
+		URL:       fmt.Sprintf("%s/%s?%s", service.publicURL, key, query.Encode()),
+		Method:    http.MethodPut,
+		Headers:   map[string]string{"Content-Type": option.ContentType},
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (service *LocalService) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	key := strings.TrimPrefix(request.URL.Path, "/")
+	if request.Method == http.MethodPut {
+		service.serveUpload(writer, request, key)
+		return
+	}
+	filePath, err := service.resolve(key)
+	if err != nil {
+		http.NotFound(writer, request)
+		return
+	}
+	// Like a public bucket, every object is served to whoever knows its key, drafts included,
+	// and directories are never listed.
+	info, err := os.Stat(filePath)
+	if err != nil || info.IsDir() {
+		http.NotFound(writer, request)
+		return
+	}
+	writer.Header().Del("content-type")
+	http.ServeFile(writer, request, filePath)
+}
+
+// serveUpload accepts a PUT to a URL from PresignPut with the signed content type and length.
+func (service *LocalService) serveUpload(writer http.ResponseWriter, request *http.Request, key string) {
+	query := request.URL.Query()
+	signature, _ := hex.DecodeString(query.Get("signature"))
+	expected, _ := hex.DecodeString(service.sign(key, query))
+	if !hmac.Equal(signature, expected) {
+		http.Error(writer, "invalid signature", http.StatusForbidden)
+		return
+	}
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		http.Error(writer, "expired upload url", http.StatusForbidden)
+		return
+	}
+	contentType := query.Get("contentType")
+	contentLength, _ := strconv.ParseInt(query.Get("contentLength"), 10, 64)
+	if request.Header.Get("content-type") != contentType {
+		http.Error(writer, "content type does not match the upload url", http.StatusBadRequest)
+		return
+	}
+	if request.ContentLength != contentLength {
+		http.Error(writer, "content length does not match the upload url", http.StatusBadRequest)
+		return
+	}
+	body := http.MaxBytesReader(writer, request.Body, contentLength)
+	err = service.PutObject(request.Context(), key, body, &PutOption{ContentType: contentType, ContentLength: contentLength})
+	if err != nil {
+		http.Error(writer, "failed to store the object", http.StatusBadRequest)
+		return
+	}
+	writer.WriteHeader(http.StatusOK)
+}
+
+func (service *LocalService) sign(key string, query url.Values) string {
+	mac := hmac.New(sha256.New, service.secret)
+	fmt.Fprintf(mac, "PUT\n%s\n%s\n%s\n%s", key, query.Get("contentType"), query.Get("contentLength"), query.Get("expires"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// resolve returns the file of key, which must stay under the root once cleaned.
+func (service *LocalService) resolve(key string) (string, error) {
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") || path.IsAbs(cleaned) {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	filePath := filepath.Join(service.root, filepath.FromSlash(cleaned))
+	relative, err := filepath.Rel(service.root, filePath)
+	if err != nil || relative == "." || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filePath, nil
+}
+
+func (service *LocalService) toObject(key string, info fs.FileInfo) *Object {
+	return &Object{
+		Key:          key,
+		Size:         info.Size(),
+		ContentType:  mime.TypeByExtension(path.Ext(key)),
+		LastModified: info.ModTime().UTC(),
+	}
+}
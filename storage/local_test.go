@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalService(t *testing.T) {
+	ctx := context.Background()
+	service, err := NewLocalService(t.TempDir(), "http://localhost:8081/storage/", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = service.PutObject(ctx, "2024/3/1/an-idiom.png", bytes.NewReader([]byte("image")), &PutOption{ContentType: "image/png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	object, err := service.HeadObject(ctx, "2024/3/1/an-idiom.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.Size != 5 || object.ContentType != "image/png" {
+		t.Errorf("Expected 5 bytes of image/png, received %d bytes of %s", object.Size, object.ContentType)
+	}
+
+	body, _, err := service.GetObject(ctx, "2024/3/1/an-idiom.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(body)
+	body.Close()
+	if string(content) != "image" {
+		t.Errorf("Expected image, received %s", content)
+	}
+
+	objects, err := service.ListObjects(ctx, "2024/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 1 || objects[0].Key != "2024/3/1/an-idiom.png" {
+		t.Errorf("Expected a single object, received %v", objects)
+	}
+
+	if url := service.PublicURL("2024/3/1/an-idiom.png"); url != "http://localhost:8081/storage/2024/3/1/an-idiom.png" {
+		t.Errorf("Unexpected public url %s", url)
+	}
+
+	err = service.DeleteObject(ctx, "2024/3/1/an-idiom.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = service.HeadObject(ctx, "2024/3/1/an-idiom.png")
+	if !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Expected ErrObjectNotFound, received %v", err)
+	}
+
+	for _, key := range []string{"../escape.png", "2024/../../escape.png", "/escape.png", "..", ""} {
+		err = service.PutObject(ctx, key, bytes.NewReader([]byte("image")), nil)
+		if err == nil {
+			t.Errorf("Expected an error for the key %q outside of the storage root", key)
+		}
+	}
+	for _, key := range []string{"a..b.jpg", "2024/../a.jpg"} {
+		err = service.PutObject(ctx, key, bytes.NewReader([]byte("image")), nil)
+		if err != nil {
+			t.Errorf("Expected the key %q under the storage root to be stored, received %v", key, err)
+		}
+	}
+}
+
+func TestLocalPresignPut(t *testing.T) {
+	ctx := context.Background()
+	service, err := NewLocalService(t.TempDir(), "", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.StripPrefix("/storage", service))
+	defer server.Close()
+
+	presigned, err := service.PresignPut(ctx, "uploads/an-idiom/image.png", &PresignOption{ContentType: "image/png", ContentLength: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload := func(url string, contentType string, body string) int {
+		request, _ := http.NewRequest(presigned.Method, server.URL+url, strings.NewReader(body))
+		request.Header.Set("content-type", contentType)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		return response.StatusCode
+	}
+
+	if status := upload(presigned.URL, "image/jpeg", "image"); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for another content type, received %d", status)
+	}
+	if status := upload(presigned.URL, "image/png", "images"); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for another content length, received %d", status)
+	}
+	if status := upload(strings.Replace(presigned.URL, "an-idiom", "another-idiom", 1), "image/png", "image"); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for another key, received %d", status)
+	}
+	if status := upload(presigned.URL, "image/png", "image"); status != http.StatusOK {
+		t.Fatalf("Expected 200, received %d", status)
+	}
+	object, err := service.HeadObject(ctx, "uploads/an-idiom/image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if object.Size != 5 {
+		t.Errorf("Expected 5 bytes, received %d", object.Size)
+	}
+
+	expired, _ := service.PresignPut(ctx, "uploads/an-idiom/expired.png", &PresignOption{ContentType: "image/png", ContentLength: 5, Expires: time.Minute})
+	expired.URL = strings.Replace(expired.URL, "expires=", "expires=1", 1)
+	if status := upload(expired.URL, "image/png", "image"); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a tampered expiry, received %d", status)
+	}
+}
+
+func TestLocalServeHTTP(t *testing.T) {
+	service, err := NewLocalService(t.TempDir(), "http://localhost:8081/storage/", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = service.PutObject(context.Background(), "drafts/an-idiom/1.png", bytes.NewReader([]byte("image")), &PutOption{ContentType: "image/png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, status := range map[string]int{
+		"/drafts/an-idiom/1.png": http.StatusOK,
+		"/":                      http.StatusNotFound,
+		"/drafts/":               http.StatusNotFound,
+		"/drafts/an-idiom":       http.StatusNotFound,
+		"/missing.png":           http.StatusNotFound,
+	} {
+		recorder := httptest.NewRecorder()
+		service.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		if recorder.Code != status {
+			t.Errorf("Expected %d for %s, received %d", status, path, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+type S3ServiceOption struct {
+	roleArn         string
+	roleSessionName string
+
+	createdAt time.Time
+	duration  time.Duration
+}
+
+type S3Service struct {
+	config    *aws.Config
+	s3Client  *s3.Client
+	stsClient *sts.Client
+	option    *S3ServiceOption
+
+	bucket    string
+	publicURL string
+}
+
+func NewS3Service(config *aws.Config, bucket string, publicURL string, awsId string, awsKey string, awsRoleArn string) *S3Service {
+	config.Credentials = credentials.NewStaticCredentialsProvider(awsId, awsKey, "")
+	stsClient := sts.NewFromConfig(*config)
+	roleArn := awsRoleArn
+	roleSessionName := "austin-idioms-sessions"
+
+	service := new(S3Service)
+	service.config = config
+	service.stsClient = stsClient
+	service.bucket = bucket
+	service.publicURL = strings.TrimRight(publicURL, "/")
+	service.option = &S3ServiceOption{
+		roleArn:         roleArn,
+		roleSessionName: roleSessionName,
+		createdAt:       time.Now(),
+		duration:        time.Duration(time.Second * 900),
+	}
+
+	return service
+}
+
+func (service *S3Service) GetStorage() *s3.Client {
+	if time.Since(service.option.createdAt) < time.Duration(time.Second*600) && service.s3Client != nil {
+		return service.s3Client
+	}
+	credentials := stscreds.NewAssumeRoleProvider(service.stsClient, service.option.roleArn, func(roleOptions *stscreds.AssumeRoleOptions) {
+		roleOptions.RoleSessionName = service.option.roleSessionName
+		roleOptions.Duration = *aws.Duration((service.option.duration))
+	})
+	service.config.Credentials = aws.NewCredentialsCache(credentials)
+	s3Client := s3.NewFromConfig(*service.config)
+
+	service.s3Client = s3Client
+	service.option.createdAt = time.Now()
+	return service.s3Client
+}
+
+func (service *S3Service) PutObject(ctx context.Context, key string, body io.Reader, option *PutOption) error {
+	input := &s3.PutObjectInput{
+		Bucket: &service.bucket,
+		Key:    &key,
+		Body:   body,
+	}
+	if option != nil && len(option.ContentType) > 0 {
+		input.ContentType = &option.ContentType
+	}
+	if option != nil && option.ContentLength > 0 {
+		input.ContentLength = &option.ContentLength
+	}
+	_, err := service.GetStorage().PutObject(ctx, input)
+	return err
+}
+
+func (service *S3Service) GetObject(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	output, err := service.GetStorage().GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &service.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, nil, toStorageError(err)
+	}
+	object := &Object{
+		Key:          key,
+		Size:         aws.ToInt64(output.ContentLength),
+		ContentType:  aws.ToString(output.ContentType),
+		LastModified: aws.ToTime(output.LastModified),
+	}
+	return output.Body, object, nil
+}
+
+func (service *S3Service) HeadObject(ctx context.Context, key string) (*Object, error) {
+	output, err := service.GetStorage().HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &service.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, toStorageError(err)
+	}
+	return &Object{
+		Key:          key,
+		Size:         aws.ToInt64(output.ContentLength),
+		ContentType:  aws.ToString(output.ContentType),
+		LastModified: aws.ToTime(output.LastModified),
+	}, nil
+}
+
+func (service *S3Service) DeleteObject(ctx context.Context, key string) error {
+	_, err := service.GetStorage().DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &service.bucket,
+		Key:    &key,
+	})
+	return toStorageError(err)
+}
+
+func (service *S3Service) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	objects := []Object{}
+	input := &s3.ListObjectsV2Input{
+		Bucket: &service.bucket,
+	}
+	if len(prefix) > 0 {
+		input.Prefix = &prefix
+	}
+	paginator := s3.NewListObjectsV2Paginator(service.GetStorage(), input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, content := range page.Contents {
+			objects = append(objects, Object{
+				Key:          aws.ToString(content.Key),
+				Size:         aws.ToInt64(content.Size),
+				LastModified: aws.ToTime(content.LastModified),
+			})
+		}
+	}
+	return objects, nil
+}
+
+func (service *S3Service) PresignPut(ctx context.Context, key string, option *PresignOption) (*PresignedRequest, error) {
+	expires := option.Expires
+	if expires <= 0 {
+		expires = DefaultPresignExpires
+	}
+	client := s3.NewPresignClient(service.GetStorage(), s3.WithPresignExpires(expires))
+	request, err := client.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:        &service.bucket,
+		Key:           &key,
+		ContentType:   &option.ContentType,
+		ContentLength: &option.ContentLength,
+	})
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{}
+	for name, values := range request.SignedHeader {
+		if strings.EqualFold(name, "host") || len(values) == 0 {
+			continue
+		}
+		headers[name] = values[0]
+	}
+	return &PresignedRequest{
+		URL:       request.URL,
+		Method:    request.Method,
+		Headers:   headers,
+		ExpiresAt: time.Now().Add(expires).UTC(),
+	}, nil
+}
+
+func (service *S3Service) PublicURL(key string) string {
+	if len(service.publicURL) > 0 {
+		return fmt.Sprintf("%s/%s", service.publicURL, key)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", service.bucket, service.config.Region, key)
+}
+
+func toStorageError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var apiError smithy.APIError
+	if errors.As(err, &apiError) {
+		switch apiError.ErrorCode() {
+		case "NotFound", "NoSuchKey":
+			{
+				return ErrObjectNotFound
+			}
+		}
+	}
+	return err
+}
@@ -1,64 +1,90 @@
 package storage
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
 type StorageService interface {
-	GetStorage() *s3.Client
+	PutObject(ctx context.Context, key string, body io.Reader, option *PutOption) error
+	GetObject(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	HeadObject(ctx context.Context, key string) (*Object, error)
+	DeleteObject(ctx context.Context, key string) error
+	ListObjects(ctx context.Context, prefix string) ([]Object, error)
+	PublicURL(key string) string
+	PresignPut(ctx context.Context, key string, option *PresignOption) (*PresignedRequest, error)
 }
 
-type ServiceOption struct {
-	roleArn         string
-	roleSessionName string
+type Object struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ContentType  string    `json:"contentType"`
+	LastModified time.Time `json:"lastModified"`
+}
 
-	createdAt time.Time
-	duration  time.Duration
+type PutOption struct {
+	ContentType   string
+	ContentLength int64
 }
 
-type Service struct {
-	config    *aws.Config
-	s3Client  *s3.Client
-	stsClient *sts.Client
-	option    *ServiceOption
+// PresignOption constrains a presigned upload to exactly this content type and length.
+type PresignOption struct {
+	ContentType   string
+	ContentLength int64
+	Expires       time.Duration
 }
 
-func NewService(config *aws.Config, awsId string, awsKey string, awsRoleArn string) *Service {
-	config.Credentials = credentials.NewStaticCredentialsProvider(awsId, awsKey, "")
-	stsClient := sts.NewFromConfig(*config)
-	roleArn := awsRoleArn
-	roleSessionName := "austin-idioms-sessions"
+type PresignedRequest struct {
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+}
 
-	service := new(Service)
-	service.config = config
-	service.stsClient = stsClient
-	service.option = &ServiceOption{
-		roleArn:         roleArn,
-		roleSessionName: roleSessionName,
-		createdAt:       time.Now(),
-		duration:        time.Duration(time.Second * 900),
-	}
+type Option struct {
+	Driver    string
+	Bucket    string
+	PublicURL string
+	LocalDir  string
+	// SigningSecret signs the upload URLs of the local driver.
+	SigningSecret string
 
-	return service
+	AwsConfig  *aws.Config
+	AwsId      string
+	AwsKey     string
+	AwsRoleArn string
 }
 
-func (service *Service) GetStorage() *s3.Client {
-	if time.Since(service.option.createdAt) < time.Duration(time.Second*600) && service.s3Client != nil {
-		return service.s3Client
-	}
-	credentials := stscreds.NewAssumeRoleProvider(service.stsClient, service.option.roleArn, func(roleOptions *stscreds.AssumeRoleOptions) {
-		roleOptions.RoleSessionName = service.option.roleSessionName
-		roleOptions.Duration = *aws.Duration((service.option.duration))
-	})
-	service.config.Credentials = aws.NewCredentialsCache(credentials)
-	s3Client := s3.NewFromConfig(*service.config)
+const (
+	DriverS3    = "s3"
+	DriverLocal = "local"
+)
+
+const DefaultPresignExpires = time.Minute * 10
 
-	service.s3Client = s3Client
-	return service.s3Client
+var ErrObjectNotFound = errors.New("object not found")
+
+func NewStorage(option *Option) (StorageService, error) {
+	switch option.Driver {
+	case DriverLocal:
+		{
+			return NewLocalService(option.LocalDir, option.PublicURL, option.SigningSecret)
+		}
+	case DriverS3, "":
+		{
+			if option.AwsConfig == nil {
+				return nil, errors.New("aws config is required for s3 storage")
+			}
+			return NewS3Service(option.AwsConfig, option.Bucket, option.PublicURL, option.AwsId, option.AwsKey, option.AwsRoleArn), nil
+		}
+	default:
+		{
+			return nil, fmt.Errorf("unknown storage driver %q", option.Driver)
+		}
+	}
 }
@@ -0,0 +1,206 @@
+package tags
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type TagController interface {
+	GetTags(writer http.ResponseWriter, request *http.Request)
+	GetAllTags(writer http.ResponseWriter, request *http.Request)
+	CreateTag(writer http.ResponseWriter, request *http.Request)
+	RenameTag(writer http.ResponseWriter, request *http.Request)
+	DeleteTag(writer http.ResponseWriter, request *http.Request)
+	GetCategories(writer http.ResponseWriter, request *http.Request)
+	GetAllCategories(writer http.ResponseWriter, request *http.Request)
+	CreateCategory(writer http.ResponseWriter, request *http.Request)
+	UpdateCategory(writer http.ResponseWriter, request *http.Request)
+	DeleteCategory(writer http.ResponseWriter, request *http.Request)
+	SetIdiomTags(writer http.ResponseWriter, request *http.Request)
+	SetIdiomCategories(writer http.ResponseWriter, request *http.Request)
+}
+
+type Controller struct {
+	tagService TagService
+
+	logger logger.LoggerService
+}
+
+func NewController(tagService TagService, logger logger.LoggerService) *Controller {
+	controller := new(Controller)
+	controller.tagService = tagService
+	controller.logger = logger
+
+	return controller
+}
+
+// GetTags counts only the published idioms of each tag.
+func (controller *Controller) GetTags(writer http.ResponseWriter, request *http.Request) {
+	controller.writeTags(writer, request, true)
+}
+
+func (controller *Controller) GetAllTags(writer http.ResponseWriter, request *http.Request) {
+	controller.writeTags(writer, request, false)
+}
+
+func (controller *Controller) writeTags(writer http.ResponseWriter, request *http.Request, published bool) {
+	tags, err := controller.tagService.GetTags(request.Context(), published)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"tags": tags,
+	})
+}
+
+func (controller *Controller) CreateTag(writer http.ResponseWriter, request *http.Request) {
+	input := new(models.TagInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid JSON body.", err.Error()))
+		return
+	}
+	tag, err := controller.tagService.CreateTag(request.Context(), input)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusCreated, map[string]interface{}{
+		"tag": tag,
+	})
+}
+
+func (controller *Controller) RenameTag(writer http.ResponseWriter, request *http.Request) {
+	input := new(models.TagInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid JSON body.", err.Error()))
+		return
+	}
+	tag, err := controller.tagService.RenameTag(request.Context(), chi.URLParam(request, "id"), input)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"tag": tag,
+	})
+}
+
+func (controller *Controller) DeleteTag(writer http.ResponseWriter, request *http.Request) {
+	id := chi.URLParam(request, "id")
+	err := controller.tagService.DeleteTag(request.Context(), id)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"id": id,
+	})
+}
+
+// GetCategories counts only the published idioms of each category.
+func (controller *Controller) GetCategories(writer http.ResponseWriter, request *http.Request) {
+	controller.writeCategories(writer, request, true)
+}
+
+func (controller *Controller) GetAllCategories(writer http.ResponseWriter, request *http.Request) {
+	controller.writeCategories(writer, request, false)
+}
+
+func (controller *Controller) writeCategories(writer http.ResponseWriter, request *http.Request, published bool) {
+	categories, err := controller.tagService.GetCategories(request.Context(), published)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"categories": categories,
+	})
+}
+
+func (controller *Controller) CreateCategory(writer http.ResponseWriter, request *http.Request) {
+	input := new(models.CategoryInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid JSON body.", err.Error()))
+		return
+	}
+	category, err := controller.tagService.CreateCategory(request.Context(), input)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusCreated, map[string]interface{}{
+		"category": category,
+	})
+}
+
+func (controller *Controller) UpdateCategory(writer http.ResponseWriter, request *http.Request) {
+	input := new(models.CategoryInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid JSON body.", err.Error()))
+		return
+	}
+	category, err := controller.tagService.UpdateCategory(request.Context(), chi.URLParam(request, "id"), input)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"category": category,
+	})
+}
+
+func (controller *Controller) DeleteCategory(writer http.ResponseWriter, request *http.Request) {
+	id := chi.URLParam(request, "id")
+	err := controller.tagService.DeleteCategory(request.Context(), id)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"id": id,
+	})
+}
+
+func (controller *Controller) SetIdiomTags(writer http.ResponseWriter, request *http.Request) {
+	input := new(models.IdiomTagsInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid JSON body.", err.Error()))
+		return
+	}
+	tags, err := controller.tagService.SetIdiomTags(request.Context(), chi.URLParam(request, "id"), input)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"tags": tags,
+	})
+}
+
+func (controller *Controller) SetIdiomCategories(writer http.ResponseWriter, request *http.Request) {
+	input := new(models.IdiomCategoriesInput)
+	err := json.NewDecoder(request.Body).Decode(input)
+	if err != nil {
+		lib.WriteError(writer, lib.NewValidationError("Invalid JSON body.", err.Error()))
+		return
+	}
+	categories, err := controller.tagService.SetIdiomCategories(request.Context(), chi.URLParam(request, "id"), input)
+	if err != nil {
+		lib.WriteError(writer, err)
+		return
+	}
+	lib.WriteJSON(writer, http.StatusOK, map[string]interface{}{
+		"categories": categories,
+	})
+}
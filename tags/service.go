@@ -0,0 +1,372 @@
+package tags
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+type TagService interface {
+	GetTags(ctx context.Context, published bool) ([]models.Tag, error)
+	CreateTag(ctx context.Context, input *models.TagInput) (*models.Tag, error)
+	RenameTag(ctx context.Context, id string, input *models.TagInput) (*models.Tag, error)
+	DeleteTag(ctx context.Context, id string) error
+	GetCategories(ctx context.Context, published bool) ([]models.Category, error)
+	CreateCategory(ctx context.Context, input *models.CategoryInput) (*models.Category, error)
+	UpdateCategory(ctx context.Context, id string, input *models.CategoryInput) (*models.Category, error)
+	DeleteCategory(ctx context.Context, id string) error
+	SetIdiomTags(ctx context.Context, idiomId string, input *models.IdiomTagsInput) ([]models.Tag, error)
+	SetIdiomCategories(ctx context.Context, idiomId string, input *models.IdiomCategoriesInput) ([]models.Category, error)
+	Attach(ctx context.Context, executor sqlx.ExecerContext, idiomId string, names []string, source string) error
+}
+
+type Service struct {
+	db     *sqlx.DB
+	logger logger.LoggerService
+}
+
+func NewService(db *sqlx.DB, logger logger.LoggerService) *Service {
+	service := new(Service)
+	service.db = db
+	service.logger = logger
+
+	return service
+}
+
+// GetTags lists the tags by name with the number of idioms, only published ones when published is set.
+func (service *Service) GetTags(ctx context.Context, published bool) ([]models.Tag, error) {
+	builder := sq.Select("tags.id", "tags.name", "tags.created_at", "count(idioms.id) as count").
+		From("tags").
+		LeftJoin("idiom_tags on idiom_tags.tag_id = tags.id")
+	if published {
+		builder = builder.LeftJoin("idioms on idioms.id = idiom_tags.idiom_id and idioms.status = ?", models.IdiomPublished)
+	} else {
+		builder = builder.LeftJoin("idioms on idioms.id = idiom_tags.idiom_id")
+	}
+	query, args, err := builder.GroupBy("tags.id").OrderBy("tags.name").PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	tags := []models.Tag{}
+	err = service.db.SelectContext(ctx, &tags, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to query tags.")
+		return nil, err
+	}
+	return tags, nil
+}
+
+func (service *Service) CreateTag(ctx context.Context, input *models.TagInput) (*models.Tag, error) {
+	id, name, err := normalize(input.Name, input.Validate())
+	if err != nil {
+		return nil, err
+	}
+	query, args, _ := sq.Insert("tags").Columns("id", "name").Values(id, name).
+		Suffix("returning " + strings.Join(models.TagColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	tag := new(models.Tag)
+	err = service.db.GetContext(ctx, tag, query, args...)
+	if lib.IsUniqueViolation(err) {
+		return nil, lib.NewConflictError("A tag with the same id already exists.", map[string]string{"id": id})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to create a tag.", id)
+		return nil, err
+	}
+	return tag, nil
+}
+
+// RenameTag changes the name of a tag and keeps its id, so the links and filters stay valid.
+func (service *Service) RenameTag(ctx context.Context, id string, input *models.TagInput) (*models.Tag, error) {
+	_, name, err := normalize(input.Name, input.Validate())
+	if err != nil {
+		return nil, err
+	}
+	query, args, _ := sq.Update("tags").Set("name", name).Where("id = ?", id).
+		Suffix("returning " + strings.Join(models.TagColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	tags := []models.Tag{}
+	err = service.db.SelectContext(ctx, &tags, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to rename the tag.", id)
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, lib.NewNotFoundError("Tag not found.", map[string]string{"id": id})
+	}
+	return &tags[0], nil
+}
+
+func (service *Service) DeleteTag(ctx context.Context, id string) error {
+	return service.delete(ctx, "tags", "Tag not found.", id)
+}
+
+func (service *Service) GetCategories(ctx context.Context, published bool) ([]models.Category, error) {
+	builder := sq.Select("categories.id", "categories.name", "categories.description", "categories.created_at", "count(idioms.id) as count").
+		From("categories").
+		LeftJoin("idiom_categories on idiom_categories.category_id = categories.id")
+	if published {
+		builder = builder.LeftJoin("idioms on idioms.id = idiom_categories.idiom_id and idioms.status = ?", models.IdiomPublished)
+	} else {
+		builder = builder.LeftJoin("idioms on idioms.id = idiom_categories.idiom_id")
+	}
+	query, args, err := builder.GroupBy("categories.id").OrderBy("categories.name").PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return nil, err
+	}
+	categories := []models.Category{}
+	err = service.db.SelectContext(ctx, &categories, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to query categories.")
+		return nil, err
+	}
+	return categories, nil
+}
+
+func (service *Service) CreateCategory(ctx context.Context, input *models.CategoryInput) (*models.Category, error) {
+	id, _, err := normalize(input.Name, input.Validate())
+	if err != nil {
+		return nil, err
+	}
+	query, args, _ := sq.Insert("categories").Columns("id", "name", "description").Values(id, strings.TrimSpace(input.Name), input.Description).
+		Suffix("returning " + strings.Join(models.CategoryColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	category := new(models.Category)
+	err = service.db.GetContext(ctx, category, query, args...)
+	if lib.IsUniqueViolation(err) {
+		return nil, lib.NewConflictError("A category with the same id already exists.", map[string]string{"id": id})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to create a category.", id)
+		return nil, err
+	}
+	return category, nil
+}
+
+func (service *Service) UpdateCategory(ctx context.Context, id string, input *models.CategoryInput) (*models.Category, error) {
+	_, _, err := normalize(input.Name, input.Validate())
+	if err != nil {
+		return nil, err
+	}
+	query, args, _ := sq.Update("categories").
+		Set("name", strings.TrimSpace(input.Name)).
+		Set("description", input.Description).
+		Where("id = ?", id).
+		Suffix("returning " + strings.Join(models.CategoryColumns, ", ")).
+		PlaceholderFormat(sq.Dollar).ToSql()
+	categories := []models.Category{}
+	err = service.db.SelectContext(ctx, &categories, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to update the category.", id)
+		return nil, err
+	}
+	if len(categories) == 0 {
+		return nil, lib.NewNotFoundError("Category not found.", map[string]string{"id": id})
+	}
+	return &categories[0], nil
+}
+
+func (service *Service) DeleteCategory(ctx context.Context, id string) error {
+	return service.delete(ctx, "categories", "Category not found.", id)
+}
+
+// SetIdiomTags replaces the tags of an idiom, creating the tags which do not exist yet.
+func (service *Service) SetIdiomTags(ctx context.Context, idiomId string, input *models.IdiomTagsInput) ([]models.Tag, error) {
+	if problems := input.Validate(); len(problems) > 0 {
+		return nil, lib.NewValidationError("Invalid tags.", map[string]interface{}{"problems": problems})
+	}
+	tx, err := service.db.BeginTxx(ctx, nil)
+	if err != nil {
+		service.logger.Error(err, "Failed to instantiate new transaction.")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = service.findIdiom(ctx, tx, idiomId)
+	if err != nil {
+		return nil, err
+	}
+	query, args, _ := sq.Delete("idiom_tags").Where("idiom_id = ?", idiomId).PlaceholderFormat(sq.Dollar).ToSql()
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to delete the tags of the idiom.", idiomId)
+		return nil, err
+	}
+	err = service.Attach(ctx, tx, idiomId, input.Tags, models.TagSourceAdmin)
+	if err != nil {
+		return nil, err
+	}
+	query, args, _ = sq.Select("tags.id", "tags.name", "tags.created_at").From("tags").
+		Join("idiom_tags on idiom_tags.tag_id = tags.id").
+		Where("idiom_tags.idiom_id = ?", idiomId).
+		OrderBy("tags.name").
+		PlaceholderFormat(sq.Dollar).ToSql()
+	tags := []models.Tag{}
+	err = tx.SelectContext(ctx, &tags, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to query the tags of the idiom.", idiomId)
+		return nil, err
+	}
+	return tags, tx.Commit()
+}
+
+// SetIdiomCategories replaces the categories of an idiom. Every category must exist.
+func (service *Service) SetIdiomCategories(ctx context.Context, idiomId string, input *models.IdiomCategoriesInput) ([]models.Category, error) {
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, id := range input.Categories {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	tx, err := service.db.BeginTxx(ctx, nil)
+	if err != nil {
+		service.logger.Error(err, "Failed to instantiate new transaction.")
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = service.findIdiom(ctx, tx, idiomId)
+	if err != nil {
+		return nil, err
+	}
+	categories := []models.Category{}
+	if len(ids) > 0 {
+		query, args, _ := sq.Select(models.CategoryColumns...).From("categories").Where(sq.Eq{"id": ids}).OrderBy("name").PlaceholderFormat(sq.Dollar).ToSql()
+		err = tx.SelectContext(ctx, &categories, query, args...)
+		if err != nil {
+			service.logger.Error(err, "Failed to query categories.", ids)
+			return nil, err
+		}
+	}
+	if len(categories) != len(ids) {
+		found := map[string]bool{}
+		for _, category := range categories {
+			found[category.ID] = true
+		}
+		missing := []string{}
+		for _, id := range ids {
+			if !found[id] {
+				missing = append(missing, id)
+			}
+		}
+		return nil, lib.NewValidationError("Unknown categories.", map[string]interface{}{"categories": missing})
+	}
+
+	query, args, _ := sq.Delete("idiom_categories").Where("idiom_id = ?", idiomId).PlaceholderFormat(sq.Dollar).ToSql()
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to delete the categories of the idiom.", idiomId)
+		return nil, err
+	}
+	if len(ids) > 0 {
+		insert := sq.Insert("idiom_categories").Columns("idiom_id", "category_id")
+		for _, id := range ids {
+			insert = insert.Values(idiomId, id)
+		}
+		query, args, _ = insert.PlaceholderFormat(sq.Dollar).ToSql()
+		_, err = tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			service.logger.Error(err, "Failed to link the categories of the idiom.", idiomId)
+			return nil, err
+		}
+	}
+	return categories, tx.Commit()
+}
+
+// Attach links tags to an idiom by name, creating the tags which do not exist yet.
+// It runs on the executor of the caller, so it commits or rolls back with the change.
+// Names without a letter or a digit are a validation error from an admin, and skipped from the AI.
+func (service *Service) Attach(ctx context.Context, executor sqlx.ExecerContext, idiomId string, names []string, source string) error {
+	ids := []string{}
+	insertTags := sq.Insert("tags").Columns("id", "name")
+	seen := map[string]bool{}
+	problems := []string{}
+	for index, name := range names {
+		id, normalized, err := normalize(name, nil)
+		if err != nil && source == models.TagSourceAi {
+			service.logger.Warn("Skipped an invalid suggested tag.", idiomId, name)
+			continue
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("tags[%d] must contain a letter or a digit", index))
+			continue
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+		insertTags = insertTags.Values(id, normalized)
+	}
+	if len(problems) > 0 {
+		return lib.NewValidationError("Invalid tags.", map[string]interface{}{"problems": problems})
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	query, args, _ := insertTags.Suffix("on conflict (id) do nothing").PlaceholderFormat(sq.Dollar).ToSql()
+	_, err := executor.ExecContext(ctx, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to create tags.", ids)
+		return err
+	}
+	insertLinks := sq.Insert("idiom_tags").Columns("idiom_id", "tag_id", "source")
+	for _, id := range ids {
+		insertLinks = insertLinks.Values(idiomId, id, source)
+	}
+	query, args, _ = insertLinks.Suffix("on conflict (idiom_id, tag_id) do nothing").PlaceholderFormat(sq.Dollar).ToSql()
+	_, err = executor.ExecContext(ctx, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to link tags to the idiom.", idiomId, ids)
+		return err
+	}
+	return nil
+}
+
+func (service *Service) findIdiom(ctx context.Context, queryer sqlx.QueryerContext, idiomId string) error {
+	query, args, _ := sq.Select("count(*)").From("idioms").Where("id = ?", idiomId).PlaceholderFormat(sq.Dollar).ToSql()
+	var count int
+	err := sqlx.GetContext(ctx, queryer, &count, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to query the idiom with id.", idiomId)
+		return err
+	}
+	if count == 0 {
+		return lib.NewNotFoundError("Idiom not found.", map[string]string{"id": idiomId})
+	}
+	return nil
+}
+
+func (service *Service) delete(ctx context.Context, table string, message string, id string) error {
+	query, args, _ := sq.Delete(table).Where("id = ?", id).PlaceholderFormat(sq.Dollar).ToSql()
+	result, err := service.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		service.logger.Error(err, "Failed to delete the row.", table, id)
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return lib.NewNotFoundError(message, map[string]string{"id": id})
+	}
+	return nil
+}
+
+// normalize returns the id and the name of a tag, lowercase words separated by single spaces.
+func normalize(name string, problems []string) (string, string, error) {
+	normalized := strings.ToLower(strings.Join(strings.Fields(name), " "))
+	id := lib.ToTagID(normalized)
+	if len(problems) == 0 && len(id) == 0 {
+		problems = []string{"name must contain a letter or a digit"}
+	}
+	if len(problems) > 0 {
+		return "", "", lib.NewValidationError("Invalid name.", map[string]interface{}{"problems": problems})
+	}
+	return id, normalized, nil
+}
@@ -0,0 +1,53 @@
+package tags
+
+import (
+	"context"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
+)
+
+func TestNormalize(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		id   string
+		tag  string
+	}{
+		{"Business", "business", "business"},
+		{"  Small   Talk ", "small-talk", "small talk"},
+		{"rock & roll!", "rock-roll", "rock & roll!"},
+	} {
+		id, tag, err := normalize(test.name, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != test.id || tag != test.tag {
+			t.Errorf("Expected %s and %s for %q, received %s and %s", test.id, test.tag, test.name, id, tag)
+		}
+	}
+	if _, _, err := normalize("!!!", nil); err == nil {
+		t.Errorf("Expected an error for a name without letters")
+	}
+	if _, _, err := normalize("business", []string{"name must not be empty"}); err == nil {
+		t.Errorf("Expected the problems as an error")
+	}
+}
+
+func TestAttachInvalidNames(t *testing.T) {
+	service := NewService(nil, logger.NewService(log.Default()))
+	ctx := context.Background()
+
+	// Invalid names are rejected before the executor is used, and AI suggestions without a valid name attach nothing.
+	err := service.Attach(ctx, nil, "an-idiom", []string{"business", "!!!"}, models.TagSourceAdmin)
+	var libError *lib.Error
+	if !errors.As(err, &libError) || libError.Code != lib.ErrorValidation {
+		t.Errorf("Expected a validation error for an admin, received %v", err)
+	}
+	if err := service.Attach(ctx, nil, "an-idiom", []string{"!!!", "  "}, models.TagSourceAi); err != nil {
+		t.Errorf("Expected invalid suggestions to be skipped, received %v", err)
+	}
+}
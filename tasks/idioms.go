@@ -1,169 +1,180 @@
 package tasks
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"strings"
-	"time"
 
-	sq "github.com/Masterminds/squirrel"
-	"github.com/jmoiron/sqlx"
-	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/nw.lee/idioms-backend/logger"
 	"github.com/nw.lee/idioms-backend/models"
 	"github.com/nw.lee/idioms-backend/openai"
+	"github.com/nw.lee/idioms-backend/prompts"
+	"github.com/nw.lee/idioms-backend/repository"
+	"github.com/nw.lee/idioms-backend/revisions"
+	"github.com/nw.lee/idioms-backend/tags"
 )
 
+// maxTagVocabulary bounds the existing tags given to the model to reuse.
+const maxTagVocabulary = 200
+
 type IdiomTask interface {
+	GenerateIdiom(ctx context.Context, job *models.Job) error
 }
 
 type Task struct {
-	db     *sqlx.DB
-	logger logger.LoggerService
-	ai     openai.OpenAiInterface
+	db         repository.DB
+	repository repository.IdiomRepository
+	logger     logger.LoggerService
+	ai         openai.OpenAiInterface
+	prompts    prompts.PromptService
+	revisions  revisions.RevisionService
+	tags       tags.TagService
 }
 
-func NewIdiomTask(db *sqlx.DB, logger logger.LoggerService, ai openai.OpenAiInterface) *Task {
+func NewIdiomTask(db repository.DB, repository repository.IdiomRepository, logger logger.LoggerService, ai openai.OpenAiInterface, prompts prompts.PromptService, revisions revisions.RevisionService, tags tags.TagService) *Task {
 	task := new(Task)
 	task.db = db
+	task.repository = repository
 	task.logger = logger
 	task.ai = ai
+	task.prompts = prompts
+	task.revisions = revisions
+	task.tags = tags
 
 	return task
 }
 
-func (task *Task) DeleteInput(input models.IdiomInput) {
-	deleteQuery, deleteArgs, _ := sq.Delete("idiom_inputs").Where("id = ?", input.ID).PlaceholderFormat(sq.Dollar).ToSql()
-	_, err := task.db.Exec(deleteQuery, deleteArgs...)
-
+func (task *Task) GenerateIdiom(ctx context.Context, job *models.Job) error {
+	payload := new(models.GenerateIdiomPayload)
+	err := json.Unmarshal(job.Payload, payload)
 	if err != nil {
-		task.logger.Error(err, "Failed to delete idiom input with id.", input.ID)
-		return
+		return fmt.Errorf("invalid payload: %w", err)
 	}
-}
-
-func (task *Task) CreateIdiomMeanings(interval time.Duration) {
-	inputs := []models.IdiomInput{}
-	idioms := []models.Idiom{}
-	query, args, err := sq.Select("*").From("idiom_inputs").OrderBy("created_at asc").Limit(1).PlaceholderFormat(sq.Dollar).ToSql()
-	if err != nil {
-		task.logger.Error(err, "Failed to create a query from db.", args...)
-		return
+	input, err := task.repository.GetIdiomInput(ctx, payload.InputID)
+	if errors.Is(err, repository.ErrNotFound) {
+		task.logger.Warn("The input no longer exists.", payload.InputID)
+		return nil
 	}
-
-	err = task.db.Select(&inputs, query, args...)
 	if err != nil {
 		task.logger.Error(err, "Failed to query a idiom input from db.")
-		return
+		return err
 	}
-	if len(inputs) == 0 {
-		task.logger.Warn("There are no inputs.")
-		return
-	}
-	input := inputs[0]
-
-	idiomQuery, args, _ := sq.Select("*").From("idioms").Where("id = ?", input.ID).Limit(1).PlaceholderFormat(sq.Dollar).ToSql()
-	err = task.db.Select(&idioms, idiomQuery, args...)
+	exists, err := task.repository.IdiomExists(ctx, input.ID)
 	if err != nil {
 		task.logger.Error(err, "Failed to query idioms with inputs")
-		return
+		return err
 	}
-	if len(idioms) > 0 {
+	if exists {
 		task.logger.Warn("The idiom already exists", input)
-		task.DeleteInput(input)
-		return
+		return task.deleteInput(ctx, task.repository, input)
 	}
 
-	textArgs := new(openai.TextCompletionArgs)
-	textArgs.AddMessage("system", "You are the well telanted English instructor.")
-	textArgs.AddMessage("system", "You are good at teaching English to everyone.")
-	textArgs.AddMessage("system", "You should know how to teach English to students.")
-	textArgs.AddMessage("system", "You have every knowledges to teach English to people.")
-	textArgs.AddMessage("system", "You should have the mindset to make English learning textbooks for high school students.")
-	textArgs.AddMessage("system", "You should act like that you are writing educational books for high school students.")
-	textArgs.AddMessage("system", "Your answer should be enough to use in real life.")
-	textArgs.AddMessage("system", "You should use active tones instead of passive tones.")
-	textArgs.AddMessage("system", "You should use active voices instead of passive voices.")
-	textArgs.AddMessage("system", "Your content should be much more unique than plagiarism content.")
-	textArgs.AddMessage("system", "Your content should be academic.")
-	textArgs.AddMessage("system", "Your content should be extremely detailed.")
-	textArgs.AddMessage("system", "Your content should be highly readable.")
-	textArgs.AddMessage("system", "I will be very disappointed if your answer is like plagiarism.")
-
-	textArgs.AddMessage("system", "Your missions are tasks below.")
-	textArgs.AddMessage("system", "- Create a brief meaning.")
-	textArgs.AddMessage("system", "- Create a full meaning.")
-	textArgs.AddMessage("system", "- Create example sentences.")
-	textArgs.AddMessage("system", "You should limit the brief meaning to 200 words.")
-	textArgs.AddMessage("system", "You should limit the full meaning to 1000 words.")
-	textArgs.AddMessage("system", "You should create 10 example sentences.")
-	textArgs.AddMessage("system", "- You should limit each example to 500 words.")
-	textArgs.AddMessage("system", "- Each example should be like examples in Harvard dictionary.")
-	textArgs.AddMessage("system", "- Each example can be academic, casual, or businesslike.")
-	textArgs.AddMessage("system", "- Each example should be shorter than 600 letters.")
-	textArgs.AddMessage("system", "- Each example should be much detailed than plagiarism content.")
-	textArgs.AddMessage("system", "- Each example should be more practical and specific to use in real life.")
-	textArgs.AddMessage("system", "Response should be json format to {\"idiom\": \"A Idiom\", \"meaningBrief\": \"This is a brief meaning\", \"meaningFull\": \"This is a full meaning.\", \"examples\": [\"This is example 1.\", \"This is example 2.\"]}")
-	information := map[string]string{}
-	information["idiom"] = input.Idiom
-	information["meaning"] = input.Meaning
-	formatted, _ := json.Marshal(information)
-
-	textArgs.AddMessage("assistant", fmt.Sprintf("The Idiom is here.\n%s\n", string(formatted)))
-
-	textArgs.AddMessage("user", fmt.Sprintf("Create me a brief meaning, a full meaning, a description and 10 example sentences with this idiom %s.", string(formatted)))
-
-	textArgs.Model = "gpt-4o"
+	prompt, err := task.prompts.Render(ctx, prompts.PromptGenerateIdiom, &prompts.Variables{
+		Idiom:   input.Idiom,
+		Meaning: input.Meaning,
+	})
+	if err != nil {
+		task.logger.Error(err, "Failed to render the prompt.", prompts.PromptGenerateIdiom)
+		return err
+	}
+	textArgs := prompt.Apply(new(openai.TextCompletionArgs))
 	textArgs.Temperature = 1
 
-	content, err := task.ai.TextCompletion(textArgs)
+	content := new(models.GeneratedIdiom)
+	err = openai.CompleteJSON(ctx, task.ai, "idiom", textArgs, content, openai.ValidationAttempts)
+	var invalid *openai.InvalidContentError
+	if errors.As(err, &invalid) {
+		task.logger.Warn("Failed to create valid content.", input.Idiom, invalid.Problems)
+		return fmt.Errorf("%w\n%s", err, invalid.Content)
+	}
 	if err != nil {
 		task.logger.Error(err, "Failed to create examples.", input.Idiom)
-		return
+		return err
 	}
-	idiom := new(models.Idiom)
-
-	trimmed := strings.TrimLeft(*content, "```json")
-	trimmed = strings.TrimRight(trimmed, "```")
+	idiom := &models.Idiom{
+		ID:           input.ID,
+		Idiom:        content.Idiom,
+		MeaningBrief: content.MeaningBrief,
+		MeaningFull:  content.MeaningFull,
+		Description:  pgtype.Text{String: content.Description, Valid: true},
+		Examples:     content.Examples,
+	}
+	suggestedTags := task.suggestTags(ctx, idiom)
 
-	err = json.Unmarshal([]byte(trimmed), idiom)
+	tx, err := task.db.BeginTxx(ctx, nil)
 	if err != nil {
-		task.logger.Error(err, "Failed to decode JSON.", content)
-
-		now := time.Now().Unix()
-		fileLog, _ := os.Create(fmt.Sprintf("./logs/%d.json", now))
-		logContent, _ := json.Marshal(*content)
-		fileLog.Write(logContent)
-		return
-	}
-	idiomID := lib.ToIdiomID(idiom.Idiom)
-	idiom.ID = idiomID
-
-	if !idiom.Description.Valid || idiom.Examples == nil || len(idiom.Examples) == 0 {
-		task.logger.Warn("Failed to create examples.", idiom)
-		return
+		task.logger.Error(err, "Failed to instantiate new transaction.")
+		return err
 	}
-
-	insertQuery, insertArgs, _ := sq.Insert("idioms").Columns("id", "idiom", "meaning_brief", "meaning_full", "description").Values(idiom.ID, idiom.Idiom, idiom.MeaningBrief, idiom.MeaningFull, idiom.Description).PlaceholderFormat(sq.Dollar).ToSql()
-	_, err = task.db.Exec(insertQuery, insertArgs...)
+	defer tx.Rollback()
+
+	idioms := task.repository.WithTx(tx)
+	err = idioms.CreateIdiom(ctx, &repository.NewIdiom{
+		ID:            idiom.ID,
+		Idiom:         idiom.Idiom,
+		MeaningBrief:  idiom.MeaningBrief,
+		MeaningFull:   idiom.MeaningFull,
+		Description:   idiom.Description,
+		PromptVersion: pgtype.Text{String: prompt.ID(), Valid: true},
+		Status:        models.IdiomDraft,
+		Examples:      idiom.Examples,
+	})
 	if err != nil {
 		task.logger.Error(err, "Failed to insert idiom.", idiom)
+		return err
+	}
+	err = task.tags.Attach(ctx, tx, idiom.ID, suggestedTags, models.TagSourceAi)
+	if err != nil {
+		return err
+	}
+	err = task.revisions.Record(ctx, tx, idiom.ID, models.RevisionSourceAi)
+	if err != nil {
+		return err
+	}
+	err = task.deleteInput(ctx, idioms, input)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-		task.DeleteInput(input)
-		return
+// suggestTags asks the model for the topics of an idiom, reusing the existing tags.
+// The idiom is generated without tags when the suggestion fails.
+func (task *Task) suggestTags(ctx context.Context, idiom *models.Idiom) []string {
+	existing, err := task.tags.GetTags(ctx, false)
+	if err != nil {
+		task.logger.Warn("Failed to query existing tags.", idiom.ID, err.Error())
+		return nil
 	}
-	exampleQuery := sq.Insert("idiom_examples").Columns("idiom_id", "expression")
-	for _, example := range idiom.Examples {
-		exampleQuery = exampleQuery.Values(idiom.ID, example)
+	vocabulary := []string{}
+	for index := 0; index < len(existing) && index < maxTagVocabulary; index++ {
+		vocabulary = append(vocabulary, existing[index].Name)
+	}
+	prompt, err := task.prompts.Render(ctx, prompts.PromptTags, &prompts.Variables{
+		Idiom:   idiom.Idiom,
+		Meaning: idiom.MeaningBrief,
+		Tags:    vocabulary,
+	})
+	if err != nil {
+		task.logger.Warn("Failed to render the prompt.", prompts.PromptTags, err.Error())
+		return nil
 	}
-	exampleSql, exampleArgs, _ := exampleQuery.PlaceholderFormat(sq.Dollar).ToSql()
-	_, err = task.db.Exec(exampleSql, exampleArgs...)
+	content := new(models.GeneratedTags)
+	err = openai.CompleteJSON(ctx, task.ai, "tags", prompt.Apply(new(openai.TextCompletionArgs)), content, openai.ValidationAttempts)
 	if err != nil {
-		task.logger.Error(err, "Failed to insert examples.", idiom)
+		task.logger.Warn("Failed to suggest tags.", idiom.ID, err.Error())
+		return nil
+	}
+	return content.Tags
+}
 
-		task.DeleteInput(input)
-		return
+func (task *Task) deleteInput(ctx context.Context, idioms repository.IdiomRepository, input *models.IdiomInput) error {
+	err := idioms.DeleteIdiomInput(ctx, input.ID)
+	if err != nil {
+		task.logger.Error(err, "Failed to delete idiom input with id.", input.ID)
+		return err
 	}
-	task.DeleteInput(input)
+	return nil
 }
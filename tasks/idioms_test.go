@@ -3,8 +3,9 @@ package tasks
 import (
 	"encoding/json"
 	"fmt"
-	"regexp"
 	"testing"
+
+	"github.com/nw.lee/idioms-backend/openai"
 )
 
 func TestParseResponse(t *testing.T) {
@@ -25,13 +26,12 @@ func TestParseResponse(t *testing.T) {
 	`
 	response = fmt.Sprintf("\n```json\n%s\n```\n", string(text))
 
-	promptRe := regexp.MustCompile("```json\n?|```/g")
-	content := promptRe.FindStringSubmatch(response)
+	content := openai.ExtractJSON(response)
 
 	t.Log(content)
 
-	if content[0] != string(text) {
-		t.Errorf("Expected %s, received %s", string(text), content[0])
+	if content != string(text) {
+		t.Errorf("Expected %s, received %s", string(text), content)
 		return
 	}
 	t.Log("No errors found")
@@ -0,0 +1,221 @@
+package thumbnail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/nw.lee/idioms-backend/auth"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/repository"
+	"github.com/nw.lee/idioms-backend/storage"
+)
+
+const DefaultDraftTTL = time.Hour * 24 * 7
+
+const expireBatchSize = 100
+
+// CreateThumbnail generates an image and keeps it as a draft of the idiom until it
+// is promoted or discarded. The prompt defaults to the thumbnail prompt of the idiom.
+func (service *Service) CreateThumbnail(idiomId string, prompt string, ctx *context.Context) (*models.ThumbnailDraft, error) {
+	if len(idiomId) == 0 {
+		return nil, lib.NewValidationError("Idiom id is required.", nil)
+	}
+	thumbnailPrompt, err := service.repository.GetThumbnailPrompt(*ctx, idiomId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, lib.NewNotFoundError("Idiom not found.", map[string]string{"id": idiomId})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to query the idiom with id.", idiomId)
+		return nil, err
+	}
+	if len(strings.TrimSpace(prompt)) == 0 && thumbnailPrompt != nil {
+		prompt = *thumbnailPrompt
+	}
+	if len(strings.TrimSpace(prompt)) == 0 {
+		return nil, lib.NewValidationError("Prompt is required.", map[string]string{"id": idiomId})
+	}
+
+	image, err := service.ai.Image(*ctx, prompt)
+	if err != nil {
+		service.logger.Error(err, "Failed to create thumbnail with prompt.", prompt)
+		return nil, lib.NewUpstreamError("Failed to create an image.", err)
+	}
+	response, err := service.fetcher.Fetch(*ctx, *image)
+	if err != nil {
+		service.logger.Error(err, "Failed to fetch a image with url", *image)
+		return nil, lib.NewUpstreamError("Failed to fetch the generated image.", err)
+	}
+	extension, err := toImageExtension(response.ContentType)
+	if err != nil {
+		return nil, lib.NewUpstreamError("The generated image has an invalid content type.", err)
+	}
+	fileKey := fmt.Sprintf("drafts/%s/%s.%s", idiomId, uuid.NewString(), extension)
+	err = service.storage.PutObject(*ctx, fileKey, bytes.NewReader(response.Body), &storage.PutOption{
+		ContentType:   response.ContentType,
+		ContentLength: int64(len(response.Body)),
+	})
+	if err != nil {
+		service.logger.Error(err, "Failed to save a draft image.", idiomId)
+		return nil, lib.NewUpstreamError("Failed to store the draft image.", err)
+	}
+
+	draft := &models.ThumbnailDraft{
+		IdiomID:     idiomId,
+		Prompt:      prompt,
+		Model:       service.ai.ImageModel(),
+		StorageKey:  fileKey,
+		ContentType: response.ContentType,
+		ExpiresAt:   repository.ToTimestamp(time.Now().Add(service.draftTTL)),
+	}
+	if principal := auth.PrincipalFromContext(*ctx); principal != nil {
+		draft.CreatedBy = pgtype.Text{String: principal.Subject, Valid: true}
+	}
+	draft, err = service.repository.CreateThumbnailDraft(*ctx, draft)
+	if err != nil {
+		service.logger.Error(err, "Failed to insert the thumbnail draft.", idiomId, fileKey)
+		service.storage.DeleteObject(*ctx, fileKey)
+		return nil, err
+	}
+	draft.URL = service.storage.PublicURL(draft.StorageKey)
+	return draft, nil
+}
+
+func (service *Service) GetDrafts(idiomId string, ctx *context.Context) ([]models.ThumbnailDraft, error) {
+	drafts, err := service.repository.ListThumbnailDrafts(*ctx, idiomId)
+	if err != nil {
+		service.logger.Error(err, "Failed to query thumbnail drafts.", idiomId)
+		return nil, err
+	}
+	for index := range drafts {
+		drafts[index].URL = service.storage.PublicURL(drafts[index].StorageKey)
+	}
+	return drafts, nil
+}
+
+// PromoteDraft publishes a draft as the thumbnail of its idiom. A promoted draft
+// is kept as the history of the thumbnail and never expires.
+func (service *Service) PromoteDraft(idiomId string, draftId int64, ctx *context.Context) (*models.ThumbnailDraft, error) {
+	draft, err := service.findDraft(*ctx, idiomId, draftId)
+	if err != nil {
+		return nil, err
+	}
+	body, _, err := service.storage.GetObject(*ctx, draft.StorageKey)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, lib.NewNotFoundError("The draft image no longer exists.", map[string]interface{}{"idiomId": idiomId, "id": draftId})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to read the thumbnail draft.", idiomId, draft.StorageKey)
+		return nil, lib.NewUpstreamError("Failed to read the draft image.", err)
+	}
+	defer body.Close()
+
+	_, err = service.storeThumbnail(*ctx, idiomId, body)
+	if err != nil {
+		return nil, err
+	}
+
+	promotedBy := pgtype.Text{}
+	if principal := auth.PrincipalFromContext(*ctx); principal != nil {
+		promotedBy = pgtype.Text{String: principal.Subject, Valid: true}
+	}
+	draft, err = service.repository.PromoteThumbnailDraft(*ctx, draft.ID, promotedBy)
+	if err != nil {
+		service.logger.Error(err, "Failed to promote the thumbnail draft.", idiomId, draftId)
+		return nil, err
+	}
+	draft.URL = service.storage.PublicURL(draft.StorageKey)
+	return draft, nil
+}
+
+func (service *Service) DiscardDraft(idiomId string, draftId int64, ctx *context.Context) error {
+	draft, err := service.findDraft(*ctx, idiomId, draftId)
+	if err != nil {
+		return err
+	}
+	if draft.PromotedAt.Valid {
+		return lib.NewConflictError("A promoted draft can not be discarded.", map[string]interface{}{"idiomId": idiomId, "id": draftId})
+	}
+	return service.deleteDrafts(*ctx, []models.ThumbnailDraft{*draft})
+}
+
+// DiscardDrafts deletes every draft of an idiom which was not promoted.
+func (service *Service) DiscardDrafts(idiomId string, ctx *context.Context) (int, error) {
+	drafts, err := service.GetDrafts(idiomId, ctx)
+	if err != nil {
+		return 0, err
+	}
+	discarded := []models.ThumbnailDraft{}
+	for _, draft := range drafts {
+		if !draft.PromotedAt.Valid {
+			discarded = append(discarded, draft)
+		}
+	}
+	err = service.deleteDrafts(*ctx, discarded)
+	if err != nil {
+		return 0, err
+	}
+	return len(discarded), nil
+}
+
+// ExpireDrafts is the job handler deleting the drafts left unpromoted past their expiry.
+func (service *Service) ExpireDrafts(ctx context.Context, job *models.Job) error {
+	for {
+		drafts, err := service.repository.ListExpiredThumbnailDrafts(ctx, expireBatchSize)
+		if err != nil {
+			service.logger.Error(err, "Failed to query expired thumbnail drafts.")
+			return err
+		}
+		if len(drafts) == 0 {
+			return nil
+		}
+		err = service.deleteDrafts(ctx, drafts)
+		if err != nil {
+			return err
+		}
+		if len(drafts) < expireBatchSize {
+			return nil
+		}
+	}
+}
+
+func (service *Service) findDraft(ctx context.Context, idiomId string, draftId int64) (*models.ThumbnailDraft, error) {
+	draft, err := service.repository.GetThumbnailDraft(ctx, idiomId, draftId)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, lib.NewNotFoundError("Thumbnail draft not found.", map[string]interface{}{"idiomId": idiomId, "id": draftId})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to query the thumbnail draft.", idiomId, draftId)
+		return nil, err
+	}
+	return draft, nil
+}
+
+// deleteDrafts removes the objects before the rows, so a failed delete leaves a
+// row which is retried rather than an object nothing points at.
+func (service *Service) deleteDrafts(ctx context.Context, drafts []models.ThumbnailDraft) error {
+	if len(drafts) == 0 {
+		return nil
+	}
+	ids := []int64{}
+	for _, draft := range drafts {
+		err := service.storage.DeleteObject(ctx, draft.StorageKey)
+		if err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			service.logger.Error(err, "Failed to delete the thumbnail draft.", draft.IdiomID, draft.StorageKey)
+			return lib.NewUpstreamError("Failed to delete the draft image.", err)
+		}
+		ids = append(ids, draft.ID)
+	}
+	err := service.repository.DeleteThumbnailDrafts(ctx, ids)
+	if err != nil {
+		service.logger.Error(err, "Failed to delete thumbnail drafts.", ids)
+		return err
+	}
+	return nil
+}
@@ -3,151 +3,196 @@ package thumbnail
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"net/http"
+	"mime"
 	"strings"
 	"time"
 
-	sq "github.com/Masterminds/squirrel"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/jmoiron/sqlx"
+	"github.com/nw.lee/idioms-backend/fetcher"
+	"github.com/nw.lee/idioms-backend/imaging"
 	"github.com/nw.lee/idioms-backend/lib"
 	"github.com/nw.lee/idioms-backend/logger"
+	"github.com/nw.lee/idioms-backend/models"
 	"github.com/nw.lee/idioms-backend/openai"
+	"github.com/nw.lee/idioms-backend/repository"
+	"github.com/nw.lee/idioms-backend/revisions"
 	"github.com/nw.lee/idioms-backend/storage"
 )
 
 type ThumbnailService interface {
-	UploadThumbnail(idiomId string, file *lib.File) (*string, error)
-	CreateThumbnailByURL(idiomId string, url string) (*string, error)
-	CreateThumbnail(prompt string) (*string, error)
+	UploadThumbnail(idiomId string, file *lib.File, ctx *context.Context) (*string, error)
+	CreateThumbnailByURL(idiomId string, url string, ctx *context.Context) (*string, error)
+	CreateThumbnail(idiomId string, prompt string, ctx *context.Context) (*models.ThumbnailDraft, error)
+	GetDrafts(idiomId string, ctx *context.Context) ([]models.ThumbnailDraft, error)
+	PromoteDraft(idiomId string, draftId int64, ctx *context.Context) (*models.ThumbnailDraft, error)
+	DiscardDraft(idiomId string, draftId int64, ctx *context.Context) error
+	DiscardDrafts(idiomId string, ctx *context.Context) (int, error)
+	ExpireDrafts(ctx context.Context, job *models.Job) error
+	CreateUploadURL(idiomId string, input *models.CreateThumbnailUploadInput, ctx *context.Context) (*models.ThumbnailUpload, error)
+	ConfirmUpload(idiomId string, key string, ctx *context.Context) (*string, error)
 }
 
 type Service struct {
-	db      *sqlx.DB
-	logger  logger.LoggerService
-	storage storage.StorageService
-	ai      openai.OpenAiInterface
-	context *context.Context
+	db         repository.DB
+	repository repository.IdiomRepository
+	logger     logger.LoggerService
+	storage    storage.StorageService
+	ai         openai.OpenAiInterface
+	context    *context.Context
+
+	revisions revisions.RevisionService
+	processor *imaging.Processor
+	fetcher   *fetcher.Fetcher
+	draftTTL  time.Duration
 }
 
-var bucketName string = "austin-idioms"
-
-func NewService(db *sqlx.DB, logger logger.LoggerService, storage storage.StorageService, ai openai.OpenAiInterface, context *context.Context) *Service {
+func NewService(db repository.DB, repository repository.IdiomRepository, logger logger.LoggerService, storage storage.StorageService, ai openai.OpenAiInterface, revisions revisions.RevisionService, processor *imaging.Processor, fetcher *fetcher.Fetcher, draftTTL time.Duration, context *context.Context) *Service {
 	service := new(Service)
 	service.db = db
+	service.repository = repository
 	service.logger = logger
 	service.storage = storage
 	service.context = context
 	service.ai = ai
+	service.revisions = revisions
+	service.processor = processor
+	service.fetcher = fetcher
+	service.draftTTL = draftTTL
+	if service.draftTTL <= 0 {
+		service.draftTTL = DefaultDraftTTL
+	}
 
 	return service
 }
 
-func (service *Service) CreateThumbnailByURL(idiomId string, url string) (*string, error) {
-	resp, err := http.Get(url)
+func (service *Service) CreateThumbnailByURL(idiomId string, url string, ctx *context.Context) (*string, error) {
+	err := service.findIdiom(idiomId)
 	if err != nil {
-		service.logger.Error(err, "Failed to fetch image with url.", url)
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	now := time.Now().UTC()
-	contentType := resp.Header.Get("content-type")
-	extension := strings.Split(contentType, "/")[1]
-	fileKey := fmt.Sprintf("%d/%d/%d/%s.%s", now.Year(), now.Month(), now.Day(), idiomId, extension)
-	imageBytes := new(bytes.Buffer)
-	io.Copy(imageBytes, resp.Body)
-
-	output, err := service.storage.GetStorage().PutObject(*service.context, &s3.PutObjectInput{
-		Bucket:      &bucketName,
-		Key:         &fileKey,
-		Body:        bytes.NewReader(imageBytes.Bytes()),
-		ContentType: &contentType,
-	})
-
-	if err != nil || output == nil {
-		service.logger.Error(err, "Failed to create a thumbnail with id", idiomId)
-		return nil, err
-	}
-	publishedAt := time.Now().UTC().Format(time.RFC3339Nano)
-	query, args, err := sq.Update("idioms").Set("thumbnail", fileKey).Set("published_at", publishedAt).Where("id = ?", idiomId).PlaceholderFormat(sq.Dollar).ToSql()
+	response, err := service.fetcher.Fetch(*ctx, url)
 	if err != nil {
-		service.logger.Error(err, "Failed to query the idiom with id", idiomId)
-		return nil, err
-	}
-	_, err = service.db.Exec(query, args...)
-	if err != nil {
-		service.logger.Error(err, "Failed to update the idiom with id", idiomId)
-		return nil, err
+		service.logger.Error(err, "Failed to fetch image with url.", url)
+		return nil, toFetchError(err)
 	}
 
-	return &fileKey, nil
+	return service.storeThumbnail(*ctx, idiomId, bytes.NewReader(response.Body))
 }
 
-func (service *Service) UploadThumbnail(idiomId string, file *lib.File) (*string, error) {
-	now := time.Now().UTC()
-	fileKey := fmt.Sprintf("%d/%d/%d/%s%s", now.Year(), now.Month(), now.Day(), idiomId, file.Extension)
-	contentType := fmt.Sprintf("image/%s", strings.ReplaceAll(file.Extension, ".", ""))
-
-	output, err := service.storage.GetStorage().PutObject(*service.context, &s3.PutObjectInput{
-		Bucket:      &bucketName,
-		Key:         &fileKey,
-		Body:        file.Content,
-		ContentType: &contentType,
-	})
-
-	if err != nil || output == nil {
-		service.logger.Error(err, "Failed to create a thumbnail with id.", idiomId)
-		return nil, err
+func (service *Service) UploadThumbnail(idiomId string, file *lib.File, ctx *context.Context) (*string, error) {
+	if len(idiomId) == 0 {
+		return nil, lib.NewValidationError("Idiom id is required.", nil)
 	}
-	publishedAt := time.Now().UTC().Format(time.RFC3339Nano)
-	query, args, err := sq.Update("idioms").Set("thumbnail", fileKey).Set("published_at", publishedAt).Where("id = ?", idiomId).PlaceholderFormat(sq.Dollar).ToSql()
+	err := service.findIdiom(idiomId)
 	if err != nil {
-		service.logger.Error(err, "Failed to query the idiom with id.", idiomId)
 		return nil, err
 	}
-	_, err = service.db.Exec(query, args...)
+	return service.storeThumbnail(*ctx, idiomId, file.Content)
+}
+
+// storeThumbnail stores the resized variants of an image and points the idiom at them.
+// The largest variant becomes the thumbnail and all of them, narrowest first, the thumbnails.
+func (service *Service) storeThumbnail(ctx context.Context, idiomId string, body io.Reader) (*string, error) {
+	variants, err := service.processor.Process(body)
+	if err != nil {
+		service.logger.Warn("Failed to process the thumbnail.", idiomId, err.Error())
+		return nil, toImageError(err)
+	}
+	now := time.Now().UTC()
+	hash := sha256.Sum256(variants[len(variants)-1].Body)
+	fileKeys := models.TextArray{}
+	for _, variant := range variants {
+		fileKey := fmt.Sprintf("%d/%d/%d/%s-%s-%dw.%s", now.Year(), now.Month(), now.Day(), idiomId, hex.EncodeToString(hash[:4]), variant.Width, variant.Extension)
+		err = service.storage.PutObject(ctx, fileKey, bytes.NewReader(variant.Body), &storage.PutOption{
+			ContentType:   variant.ContentType,
+			ContentLength: int64(len(variant.Body)),
+		})
+		if err != nil {
+			service.logger.Error(err, "Failed to create a thumbnail with id.", idiomId)
+			return nil, lib.NewUpstreamError("Failed to store the thumbnail.", err)
+		}
+		fileKeys = append(fileKeys, fileKey)
+	}
+	fileKey := fileKeys[len(fileKeys)-1]
+	err = service.setThumbnail(ctx, idiomId, fileKey, fileKeys)
 	if err != nil {
-		service.logger.Error(err, "Failed to update the idiom with id.", idiomId)
 		return nil, err
 	}
-
 	return &fileKey, nil
 }
 
-func (service *Service) CreateThumbnail(prompt string) (*string, error) {
-	image, err := service.ai.Image(prompt)
+func (service *Service) setThumbnail(ctx context.Context, idiomId string, fileKey string, fileKeys models.TextArray) error {
+	tx, err := service.db.BeginTxx(ctx, nil)
 	if err != nil {
-		service.logger.Error(err, "Failed to create thumbnail with prompt.", prompt)
-		return nil, err
+		service.logger.Error(err, "Failed to instantiate new transaction.")
+		return err
 	}
+	defer tx.Rollback()
 
-	resp, err := http.Get(*image)
+	err = service.repository.WithTx(tx).SetIdiomThumbnail(ctx, idiomId, fileKey, fileKeys)
+	if errors.Is(err, repository.ErrNotFound) {
+		return lib.NewNotFoundError("Idiom not found.", map[string]string{"id": idiomId})
+	}
 	if err != nil {
-		service.logger.Error(err, "Failed to fetch a image with url", *image)
-		return nil, err
+		service.logger.Error(err, "Failed to update the idiom with id.", idiomId)
+		return err
+	}
+	err = service.revisions.Record(ctx, tx, idiomId, models.RevisionSourceAdmin)
+	if err != nil {
+		return err
 	}
-	defer resp.Body.Close()
+	return tx.Commit()
+}
 
-	contentType := resp.Header.Get("content-type")
-	extension := strings.Split(contentType, "/")[1]
-	fileKey := fmt.Sprintf("drafts/output.%s", extension)
-	imageBytes := new(bytes.Buffer)
-	io.Copy(imageBytes, resp.Body)
+func (service *Service) findIdiom(idiomId string) error {
+	exists, err := service.repository.IdiomExists(context.Background(), idiomId)
+	if err != nil {
+		service.logger.Error(err, "Failed to query the idiom with id.", idiomId)
+		return err
+	}
+	if !exists {
+		return lib.NewNotFoundError("Idiom not found.", map[string]string{"id": idiomId})
+	}
+	return nil
+}
 
-	output, err := service.storage.GetStorage().PutObject(*service.context, &s3.PutObjectInput{
-		Bucket:      &bucketName,
-		Key:         &fileKey,
-		Body:        bytes.NewReader(imageBytes.Bytes()),
-		ContentType: &contentType,
-	})
+func toFetchError(err error) error {
+	var statusError *fetcher.StatusError
+	switch {
+	case errors.Is(err, fetcher.ErrInvalidURL), errors.Is(err, fetcher.ErrBlockedAddress):
+		return lib.NewValidationError("The url is not allowed.", nil)
+	case errors.Is(err, fetcher.ErrUnsupportedType):
+		return lib.NewValidationError("The url is not a JPEG, PNG, GIF or WebP image.", nil)
+	case errors.Is(err, fetcher.ErrTooLarge):
+		return lib.NewValidationError("The image is too large.", nil)
+	case errors.As(err, &statusError):
+		return lib.NewUpstreamError(fmt.Sprintf("Failed to fetch the image with status %d.", statusError.StatusCode), nil)
+	default:
+		return lib.NewUpstreamError("Failed to fetch the image.", err)
+	}
+}
 
-	if err != nil || output == nil {
-		service.logger.Error(err, "Failed to save a draft image.")
-		return nil, err
+func toImageError(err error) error {
+	switch {
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		return lib.NewValidationError("The file is not a JPEG, PNG, GIF or WebP image.", nil)
+	case errors.Is(err, imaging.ErrTooLarge):
+		return lib.NewValidationError("The image is too large.", nil)
+	case errors.Is(err, imaging.ErrTooSmall):
+		return lib.NewValidationError("The image is too small.", nil)
+	default:
+		return lib.NewValidationError("The image can not be decoded.", err.Error())
 	}
+}
 
-	return &fileKey, nil
+func toImageExtension(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "image/") {
+		return "", lib.NewValidationError("The file is not an image.", map[string]string{"contentType": contentType})
+	}
+	return strings.TrimPrefix(mediaType, "image/"), nil
 }
@@ -0,0 +1,97 @@
+package thumbnail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nw.lee/idioms-backend/lib"
+	"github.com/nw.lee/idioms-backend/models"
+	"github.com/nw.lee/idioms-backend/storage"
+)
+
+var uploadTypes = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// CreateUploadURL presigns a PUT of one image to uploads/{idiomId}/, so a client can
+// upload it straight to storage and then confirm it with ConfirmUpload.
+func (service *Service) CreateUploadURL(idiomId string, input *models.CreateThumbnailUploadInput, ctx *context.Context) (*models.ThumbnailUpload, error) {
+	extension, ok := uploadTypes[input.ContentType]
+	if !ok {
+		return nil, lib.NewValidationError("contentType must be image/jpeg, image/png, image/gif or image/webp.", map[string]string{"contentType": input.ContentType})
+	}
+	maxBytes := service.processor.MaxBytes()
+	if input.ContentLength <= 0 || input.ContentLength > maxBytes {
+		return nil, lib.NewValidationError(fmt.Sprintf("contentLength must be between 1 and %d bytes.", maxBytes), map[string]int64{"contentLength": input.ContentLength})
+	}
+	err := service.findIdiom(idiomId)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%s%s.%s", uploadPrefix(idiomId), uuid.NewString(), extension)
+	request, err := service.storage.PresignPut(*ctx, key, &storage.PresignOption{
+		ContentType:   input.ContentType,
+		ContentLength: input.ContentLength,
+	})
+	if err != nil {
+		service.logger.Error(err, "Failed to presign the upload.", idiomId, key)
+		return nil, lib.NewUpstreamError("Failed to create the upload url.", err)
+	}
+	return &models.ThumbnailUpload{
+		Key:       key,
+		URL:       request.URL,
+		Method:    request.Method,
+		Headers:   request.Headers,
+		ExpiresAt: request.ExpiresAt,
+	}, nil
+}
+
+// ConfirmUpload processes an uploaded image into the thumbnail of the idiom and
+// deletes the upload. Unconfirmed uploads are left to storage reconciliation.
+func (service *Service) ConfirmUpload(idiomId string, key string, ctx *context.Context) (*string, error) {
+	if !strings.HasPrefix(key, uploadPrefix(idiomId)) || strings.Contains(key, "..") {
+		return nil, lib.NewValidationError("The key is not an upload of the idiom.", map[string]string{"idiomId": idiomId, "key": key})
+	}
+	err := service.findIdiom(idiomId)
+	if err != nil {
+		return nil, err
+	}
+	object, err := service.storage.HeadObject(*ctx, key)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, lib.NewNotFoundError("The upload was not found.", map[string]string{"key": key})
+	}
+	if err != nil {
+		service.logger.Error(err, "Failed to read the upload.", key)
+		return nil, lib.NewUpstreamError("Failed to read the upload.", err)
+	}
+	if object.Size > service.processor.MaxBytes() {
+		service.storage.DeleteObject(*ctx, key)
+		return nil, lib.NewValidationError("The image is too large.", map[string]int64{"size": object.Size})
+	}
+	body, _, err := service.storage.GetObject(*ctx, key)
+	if err != nil {
+		service.logger.Error(err, "Failed to read the upload.", key)
+		return nil, lib.NewUpstreamError("Failed to read the upload.", err)
+	}
+	defer body.Close()
+
+	thumbnail, err := service.storeThumbnail(*ctx, idiomId, body)
+	if err != nil {
+		return nil, err
+	}
+	err = service.storage.DeleteObject(*ctx, key)
+	if err != nil {
+		service.logger.Warn("Failed to delete the confirmed upload.", key, err.Error())
+	}
+	return thumbnail, nil
+}
+
+func uploadPrefix(idiomId string) string {
+	return fmt.Sprintf("uploads/%s/", idiomId)
+}